@@ -0,0 +1,201 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+
+	"github.com/d5/tengo/objects"
+)
+
+// payloadMagic marks the end of the executables built by "tengo build". The
+// compiled bytecode is appended to a copy of the tengo executable, followed
+// by the bytecode size (8 bytes, big endian) and the magic.
+const payloadMagic = "\x00TENGOBC"
+
+// BuildExecutable compiles the source code, including the modules imported
+// from the files, and writes an executable that runs the program into
+// outputFile. The executable is a copy of exe, usually the running tengo
+// executable, with the compiled bytecode appended.
+func BuildExecutable(modules *objects.ModuleMap, data []byte, inputFile, exe, outputFile string) (err error) {
+	bytecode, err := (&Sandbox{}).compileSrc(modules, data, filepath.Base(inputFile))
+	if err != nil {
+		return
+	}
+
+	var payload bytes.Buffer
+	if err = bytecode.Encode(&payload); err != nil {
+		return
+	}
+
+	in, err := os.Open(exe)
+	if err != nil {
+		return
+	}
+	defer func() { _ = in.Close() }()
+
+	// the executable itself may have a payload
+	size, err := executableSize(in)
+	if err != nil {
+		return
+	}
+
+	out, err := os.OpenFile(outputFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+		} else {
+			err = out.Close()
+		}
+	}()
+
+	if _, err = io.Copy(out, io.NewSectionReader(in, 0, size)); err != nil {
+		return
+	}
+
+	trailer := make([]byte, 8, 8+len(payloadMagic))
+	binary.BigEndian.PutUint64(trailer, uint64(payload.Len()))
+	trailer = append(trailer, payloadMagic...)
+
+	if _, err = out.Write(payload.Bytes()); err != nil {
+		return
+	}
+
+	_, err = out.Write(trailer)
+
+	return
+}
+
+// ReadEmbedded returns the compiled bytecode embedded in the executable
+// file, or nil if the file has no embedded bytecode.
+func ReadEmbedded(exe string) ([]byte, error) {
+	f, err := os.Open(exe)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	size, err := executableSize(f)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if size == info.Size() {
+		return nil, nil
+	}
+
+	payload := make([]byte, info.Size()-size-int64(8+len(payloadMagic)))
+	if _, err := f.ReadAt(payload, size); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// RunEmbedded runs the program embedded in the running executable by
+// "tengo build" and exits with its exit code. It returns false if the
+// executable has no embedded program.
+func RunEmbedded(modules *objects.ModuleMap) bool {
+	exe, err := os.Executable()
+	if err != nil {
+		return false
+	}
+
+	data, err := ReadEmbedded(exe)
+	if err != nil || data == nil {
+		return false
+	}
+
+	if err := RunCompiled(modules, data); err != nil {
+		printError(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+
+	return true
+}
+
+// executableSize returns the size of the executable file without the
+// embedded payload.
+func executableSize(f *os.File) (int64, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	trailerSize := int64(8 + len(payloadMagic))
+	if info.Size() < trailerSize {
+		return info.Size(), nil
+	}
+
+	trailer := make([]byte, trailerSize)
+	if _, err := f.ReadAt(trailer, info.Size()-trailerSize); err != nil {
+		return 0, err
+	}
+
+	if string(trailer[8:]) != payloadMagic {
+		return info.Size(), nil
+	}
+
+	payloadSize := int64(binary.BigEndian.Uint64(trailer))
+	if payloadSize < 0 || payloadSize > info.Size()-trailerSize {
+		return 0, fmt.Errorf("invalid embedded bytecode size: %d", payloadSize)
+	}
+
+	return info.Size() - trailerSize - payloadSize, nil
+}
+
+func runBuildCommand(options *Options) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	outputFile := flags.String("o", "", "output executable file")
+	if err := flags.Parse(options.Args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: tengo build [-o output] file")
+		return 2
+	}
+
+	inputFile := flags.Arg(0)
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	if *outputFile == "" {
+		*outputFile = basename(inputFile)
+		if goruntime.GOOS == "windows" {
+			*outputFile += ".exe"
+		}
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	if err := BuildExecutable(options.Modules, data, inputFile, exe, *outputFile); err != nil {
+		printError(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,61 @@
+package cli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/cli"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestBuildExecutable(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "tengo_build")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	exe := filepath.Join(tempDir, "tengo")
+	exeData := []byte("#!/bin/sh\necho tengo\n")
+	assert.NoError(t, ioutil.WriteFile(exe, exeData, 0755))
+
+	// no embedded bytecode
+	payload, err := cli.ReadEmbedded(exe)
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+
+	modules := stdlib.GetModuleMap("fmt")
+	outFile := filepath.Join(tempDir, "out")
+	src := []byte(`fmt := import("fmt"); a := 1 + 2`)
+	assert.NoError(t, cli.BuildExecutable(modules, src, "main.tengo", exe, outFile))
+
+	data, err := ioutil.ReadFile(outFile)
+	assert.NoError(t, err)
+	assert.Equal(t, exeData, data[:len(exeData)])
+
+	info, err := os.Stat(outFile)
+	assert.NoError(t, err)
+	assert.True(t, info.Mode()&0100 != 0)
+
+	payload, err = cli.ReadEmbedded(outFile)
+	assert.NoError(t, err)
+	assert.NoError(t, cli.RunCompiled(modules, payload))
+
+	// the payload of the executable is replaced
+	outFile2 := filepath.Join(tempDir, "out2")
+	assert.NoError(t, cli.BuildExecutable(modules, []byte(`a := 1`), "main.tengo", outFile, outFile2))
+	data, err = ioutil.ReadFile(outFile2)
+	assert.NoError(t, err)
+	assert.Equal(t, exeData, data[:len(exeData)])
+
+	payload2, err := cli.ReadEmbedded(outFile2)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data)-len(exeData)-16, len(payload2))
+
+	// compile errors
+	err = cli.BuildExecutable(modules, []byte(`a :=`), "main.tengo", exe, outFile)
+	assert.Error(t, err)
+}
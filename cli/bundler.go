@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/objects"
+)
+
+const bundleFileExt = ".bundle"
+
+// BundleProgram compiles the source code and all the modules it imports from
+// the files into a program bundle, and writes it into outputFile.
+func BundleProgram(modules *objects.ModuleMap, data []byte, inputFile, outputFile string) error {
+	bundle, err := compiler.NewProgramBundle(modules, inputFile, data)
+	if err != nil {
+		return err
+	}
+
+	return writeBundle(bundle, outputFile)
+}
+
+// BundleModules compiles the module files found in the given paths into a
+// module bundle, and writes it into outputFile. The modules in a directory
+// are named by their paths relative to the directory without the ".tengo"
+// extension (e.g. "strings/pad" for "lib/strings/pad.tengo" in "lib"), and
+// the other files by their base names. Test files (*_test.tengo) are
+// skipped.
+func BundleModules(modules *objects.ModuleMap, paths []string, outputFile string) error {
+	var srcs []*compiler.ModuleSource
+	for _, path := range paths {
+		files, err := FindSourceFiles([]string{path})
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if strings.HasSuffix(file, testFileSuffix) {
+				continue
+			}
+
+			name := basename(file)
+			if rel, err := filepath.Rel(path, file); err == nil && rel != "." {
+				name = strings.TrimSuffix(filepath.ToSlash(rel), sourceFileExt)
+			}
+
+			src, err := ioutil.ReadFile(file)
+			if err != nil {
+				return err
+			}
+
+			srcs = append(srcs, &compiler.ModuleSource{Name: name, Filename: file, Src: src})
+		}
+	}
+
+	if len(srcs) == 0 {
+		return errors.New("no module files")
+	}
+
+	bundle, err := compiler.NewModuleBundle(modules, srcs)
+	if err != nil {
+		return err
+	}
+
+	return writeBundle(bundle, outputFile)
+}
+
+// LoadModuleBundle reads the module bundle file, and returns a copy of the
+// module map with the modules of the bundle added.
+func LoadModuleBundle(modules *objects.ModuleMap, file string) (*objects.ModuleMap, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &compiler.Bundle{}
+	if err := bundle.Decode(bytes.NewReader(data), modules); err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err.Error())
+	}
+
+	if modules == nil {
+		modules = objects.NewModuleMap()
+	}
+
+	modules = modules.Copy()
+	modules.AddMap(bundle.ModuleMap())
+
+	return modules, nil
+}
+
+// PrintManifest writes the manifest of the encoded bundle into out: the
+// entry file or the module names, followed by the SHA-256 hashes and the
+// paths of the source files, or the manifest in JSON if jsonOutput is set.
+func PrintManifest(data []byte, out io.Writer, jsonOutput bool) error {
+	m, err := compiler.ReadManifest(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+
+		return enc.Encode(m)
+	}
+
+	if m.Entry != "" {
+		_, _ = fmt.Fprintf(out, "entry: %s\n", m.Entry)
+	}
+	if len(m.Modules) > 0 {
+		_, _ = fmt.Fprintf(out, "modules: %s\n", strings.Join(m.Modules, ", "))
+	}
+	for _, f := range m.Files {
+		_, _ = fmt.Fprintf(out, "%s  %s\n", f.Hash, f.Path)
+	}
+
+	return nil
+}
+
+// decodeProgram decodes the compiled bytecode or the program bundle.
+func decodeProgram(modules *objects.ModuleMap, data []byte) (*compiler.Bytecode, error) {
+	if !compiler.IsBundle(data) {
+		bytecode := &compiler.Bytecode{}
+		if err := bytecode.Decode(bytes.NewReader(data), modules); err != nil {
+			return nil, err
+		}
+
+		return bytecode, nil
+	}
+
+	bundle := &compiler.Bundle{}
+	if err := bundle.Decode(bytes.NewReader(data), modules); err != nil {
+		return nil, err
+	}
+
+	if bundle.Program == nil {
+		return nil, errors.New("not a program bundle")
+	}
+
+	return bundle.Program, nil
+}
+
+func writeBundle(bundle *compiler.Bundle, outputFile string) (err error) {
+	out, err := os.OpenFile(outputFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+		} else {
+			err = out.Close()
+		}
+	}()
+
+	err = bundle.Encode(out)
+
+	return
+}
+
+func runBundleCommand(options *Options) int {
+	flags := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	outputFile := flags.String("o", "", "output bundle file")
+	module := flags.Bool("module", false, "bundle the module files")
+	manifest := flags.Bool("manifest", false, "print the manifest of the bundle file")
+	jsonOutput := flags.Bool("json", false, "print the manifest in JSON")
+	if err := flags.Parse(options.Args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 || (!*module && flags.NArg() != 1) {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: tengo bundle [-o output] file")
+		_, _ = fmt.Fprintln(os.Stderr, "       tengo bundle -module [-o output] paths...")
+		_, _ = fmt.Fprintln(os.Stderr, "       tengo bundle -manifest [-json] file")
+		return 2
+	}
+
+	inputFile := flags.Arg(0)
+	if *outputFile == "" {
+		*outputFile = basename(inputFile) + bundleFileExt
+	}
+
+	switch {
+	case *manifest:
+		data, err := ioutil.ReadFile(inputFile)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+
+		if err := PrintManifest(data, os.Stdout, *jsonOutput); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+	case *module:
+		if err := BundleModules(options.Modules, flags.Args(), *outputFile); err != nil {
+			printError(os.Stderr, err)
+			return 1
+		}
+	default:
+		data, err := ioutil.ReadFile(inputFile)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+
+		if err := BundleProgram(options.Modules, data, inputFile, *outputFile); err != nil {
+			printError(os.Stderr, err)
+			return 1
+		}
+	}
+
+	return 0
+}
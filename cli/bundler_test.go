@@ -0,0 +1,95 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/cli"
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestBundle(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "tengo_bundle")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(tempDir))
+	defer func() { _ = os.Chdir(wd) }()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join("lib", "text"), 0755))
+	writeFile(t, filepath.Join("lib", "greet.tengo"), `
+pad := import("./lib/text/pad")
+export func(name) { return "hello " + pad(name) }`)
+	writeFile(t, filepath.Join("lib", "text", "pad.tengo"), `export func(s) { return "[" + s + "]" }`)
+	writeFile(t, filepath.Join("lib", "greet_test.tengo"), `export {}`)
+
+	modules := stdlib.GetModuleMap("fmt")
+
+	// program bundle
+	src := []byte(`fmt := import("fmt"); fmt.print(import("./lib/greet")("go"))`)
+	assert.NoError(t, cli.BundleProgram(modules, src, "main.tengo", "main.bundle"))
+
+	data, err := ioutil.ReadFile("main.bundle")
+	assert.NoError(t, err)
+	assert.NoError(t, cli.RunCompiled(modules, data))
+
+	var out bytes.Buffer
+	assert.NoError(t, cli.PrintManifest(data, &out, false))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, "entry: main.tengo", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], "  main.tengo"), lines[1])
+	assert.True(t, strings.HasSuffix(lines[2], "  lib/greet.tengo"), lines[2])
+	assert.True(t, strings.HasSuffix(lines[3], "  lib/text/pad.tengo"), lines[3])
+
+	out.Reset()
+	assert.NoError(t, cli.PrintManifest(data, &out, true))
+	manifest := &compiler.Manifest{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), manifest))
+	assert.Equal(t, "main.tengo", manifest.Entry)
+	assert.Equal(t, 3, len(manifest.Files))
+
+	d, err := cli.Disassemble(modules, data, "main.bundle")
+	assert.NoError(t, err)
+	assert.Equal(t, "main", d.Functions[0].Name)
+
+	// module bundle
+	assert.NoError(t, cli.BundleModules(modules, []string{"lib"}, "lib.bundle"))
+
+	data, err = ioutil.ReadFile("lib.bundle")
+	assert.NoError(t, err)
+	out.Reset()
+	assert.NoError(t, cli.PrintManifest(data, &out, false))
+	assert.True(t, strings.HasPrefix(out.String(), "modules: greet, text/pad\n"), out.String())
+
+	// a module bundle is not a program
+	err = cli.RunCompiled(modules, data)
+	assert.Equal(t, "not a program bundle", err.Error())
+
+	// the modules are imported from the bundle without the source files
+	assert.NoError(t, os.RemoveAll("lib"))
+
+	bundleModules, err := cli.LoadModuleBundle(modules, "lib.bundle")
+	assert.NoError(t, err)
+	assert.Nil(t, modules.Get("greet"))
+
+	err = cli.EvalAndPrint(bundleModules, `import("greet")(import("text/pad")("x"))`, &out)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(out.String(), "hello [[x]]\n"), out.String())
+
+	_, err = cli.LoadModuleBundle(modules, "main.tengo")
+	assert.Error(t, err)
+
+	assert.Error(t, cli.BundleModules(modules, []string{"lib"}, "lib.bundle"))
+}
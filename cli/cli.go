@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"io"
@@ -13,14 +12,15 @@ import (
 	"github.com/d5/tengo/compiler"
 	"github.com/d5/tengo/compiler/ast"
 	"github.com/d5/tengo/compiler/parser"
-	"github.com/d5/tengo/compiler/source"
 	"github.com/d5/tengo/objects"
-	"github.com/d5/tengo/runtime"
+	"github.com/d5/tengo/stdlib"
 )
 
 const (
-	sourceFileExt = ".tengo"
-	replPrompt    = ">> "
+	sourceFileExt   = ".tengo"
+	stdinFile       = "-"
+	replPrompt      = ">> "
+	replHistoryFile = ".tengo_history"
 )
 
 // Options represent CLI options
@@ -42,6 +42,29 @@ type Options struct {
 
 	// Import modules
 	Modules *objects.ModuleMap
+
+	// Arguments following the input file
+	Args []string
+
+	// Source code to run instead of the input file
+	Eval string
+
+	// Restrictions on the scripts
+	Sandbox Sandbox
+
+	// Module bundle files whose modules are added to the import modules
+	ModuleBundles []string
+}
+
+// commands are the subcommands that take the place of the input file.
+var commands = map[string]func(options *Options) int{
+	"build":  runBuildCommand,
+	"bundle": runBundleCommand,
+	"disasm": runDisasmCommand,
+	"fmt":    runFmtCommand,
+	"lsp":    runLSPCommand,
+	"test":   runTestCommand,
+	"vet":    runVetCommand,
 }
 
 // Run CLI
@@ -54,45 +77,138 @@ func Run(options *Options) {
 		return
 	}
 
+	if command, ok := commands[options.InputFile]; ok {
+		os.Exit(command(options))
+	}
+
+	modules := options.Modules
+	for _, file := range options.ModuleBundles {
+		var err error
+		if modules, err = LoadModuleBundle(modules, file); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+	}
+
+	sandbox := &options.Sandbox
+	if modules != nil || len(sandbox.AllowedModules) > 0 || len(sandbox.DeniedModules) > 0 {
+		var err error
+		if modules, err = sandbox.Modules(modules); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(2)
+		}
+	}
+
+	if options.Eval != "" {
+		// the arguments following the source code
+		args := options.Args
+		if options.InputFile != "" {
+			args = append([]string{options.InputFile}, args...)
+		}
+
+		if err := sandbox.EvalAndPrint(scriptModules(modules, "-e", args), options.Eval, os.Stdout); err != nil {
+			printError(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if options.InputFile == "" {
 		// REPL
-		RunREPL(options.Modules, os.Stdin, os.Stdout)
+		repl := NewREPL(modules, os.Stdin, os.Stdout)
+		if home, err := os.UserHomeDir(); err == nil {
+			repl.SetHistoryFile(filepath.Join(home, replHistoryFile))
+		}
+		repl.Run()
 		return
 	}
 
-	inputData, err := ioutil.ReadFile(options.InputFile)
+	var inputData []byte
+	var err error
+	if options.InputFile == stdinFile {
+		inputData, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		inputData, err = ioutil.ReadFile(options.InputFile)
+	}
 	if err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "Error reading input file: %s", err.Error())
 		os.Exit(1)
 	}
 
+	modules = scriptModules(modules, options.InputFile, options.Args)
+
 	if options.CompileOutput != "" {
-		if err := CompileOnly(options.Modules, inputData, options.InputFile, options.CompileOutput); err != nil {
-			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		if err := sandbox.CompileOnly(modules, inputData, options.InputFile, options.CompileOutput); err != nil {
+			printError(os.Stderr, err)
 			os.Exit(1)
 		}
-	} else if filepath.Ext(options.InputFile) == sourceFileExt {
-		if err := CompileAndRun(options.Modules, inputData, options.InputFile); err != nil {
-			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+	} else if isSource(options.InputFile, inputData) {
+		if err := sandbox.CompileAndRun(modules, inputData, options.InputFile); err != nil {
+			printError(os.Stderr, err)
 			os.Exit(1)
 		}
 	} else {
-		if err := RunCompiled(options.Modules, inputData); err != nil {
-			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		if err := sandbox.RunCompiled(modules, inputData); err != nil {
+			printError(os.Stderr, err)
 			os.Exit(1)
 		}
 	}
 }
 
+// isSource returns true if the input is the source code: a file with
+// ".tengo" extension, the standard input or an executable script starting
+// with the interpreter line ("#!").
+func isSource(inputFile string, data []byte) bool {
+	return filepath.Ext(inputFile) == sourceFileExt ||
+		inputFile == stdinFile ||
+		bytes.HasPrefix(data, []byte("#!"))
+}
+
+// scriptModules returns a copy of the module map whose os.args() returns
+// the script name followed by the script arguments. A leading "--" that
+// separates the script arguments is removed.
+func scriptModules(modules *objects.ModuleMap, name string, args []string) *objects.ModuleMap {
+	if modules == nil {
+		return nil
+	}
+
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+
+	modules = modules.Copy()
+	stdlib.SetOSArgs(modules, append([]string{name}, args...))
+
+	return modules
+}
+
 func doHelp() {
 	fmt.Println("Usage:")
 	fmt.Println()
-	fmt.Println("	tengo [flags] {input-file}")
+	fmt.Println("	tengo [flags] {input-file} [--] [args...]")
+	fmt.Println("	tengo [flags] -e {source} [--] [args...]")
+	fmt.Println("	tengo fmt [-w] [-d] [paths...]")
+	fmt.Println("	tengo test [-v] [-run regexp] [-json file] [-junit file] [paths...]")
+	fmt.Println("	tengo vet [-module] [paths...]")
+	fmt.Println("	tengo lsp")
+	fmt.Println("	tengo disasm [-json] {input-file}")
+	fmt.Println("	tengo build [-o output] {input-file}")
+	fmt.Println("	tengo bundle [-o output] {input-file}")
+	fmt.Println("	tengo bundle -module [-o output] {paths...}")
+	fmt.Println("	tengo bundle -manifest [-json] {bundle-file}")
 	fmt.Println()
 	fmt.Println("Flags:")
 	fmt.Println()
-	fmt.Println("	-o        compile output file")
-	fmt.Println("	-version  show version")
+	fmt.Println("	-e                  run the source code")
+	fmt.Println("	-o                  compile output file")
+	fmt.Println("	-version            show version")
+	fmt.Println("	-timeout            maximum execution time (e.g. 10s)")
+	fmt.Println("	-max-allocs         maximum number of allocated objects")
+	fmt.Println("	-max-const-objects  maximum number of constant objects")
+	fmt.Println("	-allow-modules      comma-separated modules that can be imported")
+	fmt.Println("	-deny-modules       comma-separated modules that cannot be imported")
+	fmt.Println("	-file-import        allow importing modules from files (default true)")
+	fmt.Println("	-module-bundle      comma-separated module bundle files to import from")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println()
@@ -103,7 +219,22 @@ func doHelp() {
 	fmt.Println("	tengo myapp.tengo")
 	fmt.Println()
 	fmt.Println("	          Compile and run source file (myapp.tengo)")
-	fmt.Println("	          Source file must have .tengo extension")
+	fmt.Println("	          Source file must have .tengo extension, or start with")
+	fmt.Println("	          the interpreter line (#!/usr/bin/env tengo)")
+	fmt.Println()
+	fmt.Println("	tengo myapp.tengo -- arg1 arg2")
+	fmt.Println()
+	fmt.Println("	          Run source file with arguments; os.args() returns")
+	fmt.Println("	          [\"myapp.tengo\", \"arg1\", \"arg2\"]")
+	fmt.Println()
+	fmt.Println("	tengo - < myapp.tengo")
+	fmt.Println()
+	fmt.Println("	          Run the source code read from the standard input")
+	fmt.Println()
+	fmt.Println("	tengo -e '1 + 2'")
+	fmt.Println()
+	fmt.Println("	          Run the source code and print the value of the last")
+	fmt.Println("	          expression statement unless it is undefined")
 	fmt.Println()
 	fmt.Println("	tengo -o myapp myapp.tengo")
 	fmt.Println()
@@ -113,172 +244,69 @@ func doHelp() {
 	fmt.Println()
 	fmt.Println("	          Run bytecode file (myapp)")
 	fmt.Println()
+	fmt.Println("	tengo fmt -w .")
+	fmt.Println()
+	fmt.Println("	          Format all source files in the current directory and its")
+	fmt.Println("	          subdirectories in the canonical style")
+	fmt.Println()
+	fmt.Println("	tengo test ./...")
+	fmt.Println()
+	fmt.Println("	          Run the test functions in all test files (*_test.tengo)")
+	fmt.Println("	          found in the current directory and its subdirectories")
+	fmt.Println()
+	fmt.Println("	tengo vet ./...")
+	fmt.Println()
+	fmt.Println("	          Report suspicious constructs in all source files found in")
+	fmt.Println("	          the current directory and its subdirectories")
+	fmt.Println()
+	fmt.Println("	tengo lsp")
+	fmt.Println()
+	fmt.Println("	          Start the language server that communicates with the editor")
+	fmt.Println("	          over the standard input and output")
+	fmt.Println()
+	fmt.Println("	tengo disasm myapp.tengo")
+	fmt.Println()
+	fmt.Println("	          Show the compiled functions and instructions of the source")
+	fmt.Println("	          file (myapp.tengo) or the bytecode file")
+	fmt.Println()
+	fmt.Println("	tengo build -o myapp myapp.tengo")
+	fmt.Println()
+	fmt.Println("	          Build an executable (myapp) that runs the source file")
+	fmt.Println("	          (myapp.tengo) without tengo")
+	fmt.Println()
+	fmt.Println("	tengo bundle -module -o mylib.bundle mylib")
+	fmt.Println()
+	fmt.Println("	          Compile the module files in the directory (mylib) into")
+	fmt.Println("	          a module bundle (mylib.bundle)")
+	fmt.Println()
+	fmt.Println("	tengo -module-bundle mylib.bundle myapp.tengo")
+	fmt.Println()
+	fmt.Println("	          Run source file (myapp.tengo) that imports the modules of")
+	fmt.Println("	          the module bundle (mylib.bundle)")
+	fmt.Println()
 	fmt.Println()
 }
 
 // CompileOnly compiles the source code and writes the compiled binary into outputFile.
-func CompileOnly(modules *objects.ModuleMap, data []byte, inputFile, outputFile string) (err error) {
-	bytecode, err := compileSrc(modules, data, filepath.Base(inputFile))
-	if err != nil {
-		return
-	}
-
-	if outputFile == "" {
-		outputFile = basename(inputFile) + ".out"
-	}
-
-	out, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY, os.ModePerm)
-	if err != nil {
-		return
-	}
-	defer func() {
-		if err != nil {
-			_ = out.Close()
-		} else {
-			err = out.Close()
-		}
-	}()
-
-	err = bytecode.Encode(out)
-	if err != nil {
-		return
-	}
-
-	fmt.Println(outputFile)
-
-	return
+func CompileOnly(modules *objects.ModuleMap, data []byte, inputFile, outputFile string) error {
+	return (&Sandbox{}).CompileOnly(modules, data, inputFile, outputFile)
 }
 
 // CompileAndRun compiles the source code and executes it.
-func CompileAndRun(modules *objects.ModuleMap, data []byte, inputFile string) (err error) {
-	bytecode, err := compileSrc(modules, data, filepath.Base(inputFile))
-	if err != nil {
-		return
-	}
-
-	machine := runtime.NewVM(bytecode, nil, -1)
-
-	err = machine.Run()
-	if err != nil {
-		return
-	}
-
-	return
+func CompileAndRun(modules *objects.ModuleMap, data []byte, inputFile string) error {
+	return (&Sandbox{}).CompileAndRun(modules, data, inputFile)
 }
 
 // RunCompiled reads the compiled binary from file and executes it.
-func RunCompiled(modules *objects.ModuleMap, data []byte) (err error) {
-	bytecode := &compiler.Bytecode{}
-	err = bytecode.Decode(bytes.NewReader(data), modules)
-	if err != nil {
-		return
-	}
-
-	machine := runtime.NewVM(bytecode, nil, -1)
-
-	err = machine.Run()
-	if err != nil {
-		return
-	}
-
-	return
-}
-
-// RunREPL starts REPL.
-func RunREPL(modules *objects.ModuleMap, in io.Reader, out io.Writer) {
-	stdin := bufio.NewScanner(in)
-
-	fileSet := source.NewFileSet()
-	globals := make([]objects.Object, runtime.GlobalsSize)
-
-	symbolTable := compiler.NewSymbolTable()
-	for idx, fn := range objects.Builtins {
-		symbolTable.DefineBuiltin(idx, fn.Name)
-	}
-
-	// embed println function
-	symbol := symbolTable.Define("__repl_println__")
-	globals[symbol.Index] = &objects.UserFunction{
-		Name: "println",
-		Value: func(args ...objects.Object) (ret objects.Object, err error) {
-			var printArgs []interface{}
-			for _, arg := range args {
-				if _, isUndefined := arg.(*objects.Undefined); isUndefined {
-					printArgs = append(printArgs, "<undefined>")
-				} else {
-					s, _ := objects.ToString(arg)
-					printArgs = append(printArgs, s)
-				}
-			}
-
-			printArgs = append(printArgs, "\n")
-			_, _ = fmt.Print(printArgs...)
-
-			return
-		},
-	}
-
-	var constants []objects.Object
-
-	for {
-		_, _ = fmt.Fprint(out, replPrompt)
-
-		scanned := stdin.Scan()
-		if !scanned {
-			return
-		}
-
-		line := stdin.Text()
-
-		srcFile := fileSet.AddFile("repl", -1, len(line))
-		p := parser.NewParser(srcFile, []byte(line), nil)
-		file, err := p.ParseFile()
-		if err != nil {
-			_, _ = fmt.Fprintln(out, err.Error())
-			continue
-		}
-
-		file = addPrints(file)
-
-		c := compiler.NewCompiler(srcFile, symbolTable, constants, modules, nil)
-		if err := c.Compile(file); err != nil {
-			_, _ = fmt.Fprintln(out, err.Error())
-			continue
-		}
-
-		bytecode := c.Bytecode()
-
-		machine := runtime.NewVM(bytecode, globals, -1)
-		if err := machine.Run(); err != nil {
-			_, _ = fmt.Fprintln(out, err.Error())
-			continue
-		}
-
-		constants = bytecode.Constants
-	}
+func RunCompiled(modules *objects.ModuleMap, data []byte) error {
+	return (&Sandbox{}).RunCompiled(modules, data)
 }
 
-func compileSrc(modules *objects.ModuleMap, src []byte, filename string) (*compiler.Bytecode, error) {
-	fileSet := source.NewFileSet()
-	srcFile := fileSet.AddFile(filename, -1, len(src))
-
-	p := parser.NewParser(srcFile, src, nil)
-	file, err := p.ParseFile()
-	if err != nil {
-		return nil, err
-	}
-
-	c := compiler.NewCompiler(srcFile, nil, nil, modules, nil)
-	c.EnableFileImport(true)
-
-	if err := c.Compile(file); err != nil {
-		return nil, err
-	}
-
-	bytecode := c.Bytecode()
-	bytecode.RemoveDuplicates()
-
-	return bytecode, nil
+// EvalAndPrint compiles and runs the source code, and writes the value of
+// the last statement into out if it is an expression statement and its
+// value is not undefined.
+func EvalAndPrint(modules *objects.ModuleMap, src string, out io.Writer) error {
+	return (&Sandbox{}).EvalAndPrint(modules, src, out)
 }
 
 func addPrints(file *ast.File) *ast.File {
@@ -317,6 +345,63 @@ func addPrints(file *ast.File) *ast.File {
 	}
 }
 
+// findFiles returns the files in the given paths. Directories are searched
+// recursively for the files accepted by match, and other paths are returned
+// as they are. A trailing "/..." is accepted for familiarity and has no
+// additional effect.
+// printError prints the error into w. The errors in an error list are
+// printed in separate lines.
+func printError(w io.Writer, err error) {
+	switch err := err.(type) {
+	case compiler.ErrorList:
+		for _, e := range err {
+			_, _ = fmt.Fprintln(w, e.Error())
+		}
+	case parser.ErrorList:
+		for _, e := range err {
+			_, _ = fmt.Fprintln(w, e.Error())
+		}
+	default:
+		_, _ = fmt.Fprintln(w, err.Error())
+	}
+}
+
+func findFiles(paths []string, match func(path string) bool) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		if path == "..." || strings.HasSuffix(path, "/...") {
+			path = filepath.Clean(strings.TrimSuffix(path, "..."))
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() && match(p) {
+				files = append(files, p)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
 func basename(s string) string {
 	s = filepath.Base(s)
 
@@ -1,6 +1,7 @@
 package cli_test
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -62,3 +63,24 @@ file.close()
 	assert.NoError(t, err)
 	assert.True(t, ok, string(read))
 }
+
+func TestEvalAndPrint(t *testing.T) {
+	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+	stdlib.SetOSArgs(modules, []string{"-e", "a"})
+
+	expectEval := func(src, expected string) {
+		out := &bytes.Buffer{}
+		assert.NoError(t, cli.EvalAndPrint(modules, src, out))
+		assert.Equal(t, expected, out.String())
+	}
+
+	expectEval(`1 + 2`, "3\n")
+	expectEval(`a := "x"; a + "y"`, "xy\n")
+	expectEval(`os := import("os"); os.args()`, "[\"-e\", \"a\"]\n")
+	expectEval(`undefined`, "")
+	expectEval(`a := 1`, "")
+	expectEval(``, "")
+
+	assert.Error(t, cli.EvalAndPrint(modules, `a :=`, &bytes.Buffer{}))
+	assert.Error(t, cli.EvalAndPrint(modules, `1 / 0`, &bytes.Buffer{}))
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/objects"
+)
+
+// Disassemble disassembles the source file (with ".tengo" extension), the
+// compiled bytecode file or the program bundle. The sources of the main file and the modules are
+// used to find the names of the functions if they are available.
+func Disassemble(modules *objects.ModuleMap, data []byte, inputFile string) (*compiler.Disassembly, error) {
+	var bytecode *compiler.Bytecode
+	if filepath.Ext(inputFile) == sourceFileExt {
+		var err error
+		bytecode, err = (&Sandbox{}).compileSrc(modules, data, filepath.Base(inputFile))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		bytecode, err = decodeProgram(modules, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	mainFile := filepath.Base(inputFile)
+	src := func(filename string) []byte {
+		if filename == mainFile && filepath.Ext(inputFile) == sourceFileExt {
+			return data
+		}
+
+		if modules != nil {
+			if mod := modules.GetSourceModule(filename); mod != nil {
+				return mod.Src
+			}
+		}
+
+		// the main file is named relative to the directory of the input file
+		// and the module files are named relative to the working directory.
+		for _, path := range []string{filepath.Join(filepath.Dir(inputFile), filename), filename} {
+			if src, err := ioutil.ReadFile(path); err == nil {
+				return src
+			}
+		}
+
+		return nil
+	}
+
+	return bytecode.Disassemble(src), nil
+}
+
+func runDisasmCommand(options *Options) int {
+	flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+	jsonOutput := flags.Bool("json", false, "write the disassembly in JSON")
+	if err := flags.Parse(options.Args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: tengo disasm [-json] file")
+		return 2
+	}
+
+	inputFile := flags.Arg(0)
+	data, err := ioutil.ReadFile(inputFile)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	d, err := Disassemble(options.Modules, data, inputFile)
+	if err != nil {
+		printError(os.Stderr, err)
+		return 1
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(d); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+
+		return 0
+	}
+
+	fmt.Print(d.String())
+
+	return 0
+}
@@ -0,0 +1,62 @@
+package cli_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/cli"
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestDisassemble(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "tengo_disasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	src := []byte(`fmt := import("fmt")
+double := func(x) { return x * 2 }
+fmt.println(double(2))
+`)
+	srcFile := filepath.Join(tempDir, "app.tengo")
+	outFile := filepath.Join(tempDir, "app.out")
+	assert.NoError(t, ioutil.WriteFile(srcFile, src, 0644))
+
+	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+
+	d, err := cli.Disassemble(modules, src, srcFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main", "double"}, disassembledNames(d))
+	assert.Equal(t, "module fmt", d.Functions[0].Instructions[0].Comment)
+
+	// the source of the bytecode file is found in the same directory
+	assert.NoError(t, cli.CompileOnly(modules, src, srcFile, outFile))
+	data, err := ioutil.ReadFile(outFile)
+	assert.NoError(t, err)
+
+	d, err = cli.Disassemble(modules, data, outFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main", "double"}, disassembledNames(d))
+
+	assert.NoError(t, os.Remove(srcFile))
+	d, err = cli.Disassemble(modules, data, outFile)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main", "func"}, disassembledNames(d))
+
+	_, err = cli.Disassemble(modules, []byte("a :="), srcFile)
+	assert.Error(t, err)
+}
+
+func disassembledNames(d *compiler.Disassembly) []string {
+	var names []string
+	for _, fn := range d.Functions {
+		names = append(names, fn.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/d5/tengo/compiler/format"
+)
+
+// FormatOptions represent the options of the source formatter.
+type FormatOptions struct {
+	// Write the result to the source file instead of the output.
+	Write bool
+
+	// Write the diffs between the source and the result to the output.
+	Diff bool
+}
+
+// FindSourceFiles returns the source files found in the given paths.
+// Directories are searched recursively for the files ending with ".tengo",
+// and other paths are returned as they are.
+func FindSourceFiles(paths []string) ([]string, error) {
+	return findFiles(paths, func(path string) bool {
+		return filepath.Ext(path) == sourceFileExt
+	})
+}
+
+// FormatFile formats the source file. By default the formatted source is
+// written into out, but options can change it to update the file or to
+// write the diffs instead.
+func FormatFile(file string, options *FormatOptions, out io.Writer) error {
+	if options == nil {
+		options = &FormatOptions{}
+	}
+
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	res, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s: %s", file, err.Error())
+	}
+
+	if options.Diff && !bytes.Equal(src, res) {
+		_, _ = fmt.Fprintf(out, "diff %s %s\n", file, file)
+		_, _ = fmt.Fprintf(out, "--- %s.orig\n+++ %s\n", file, file)
+		_, _ = io.WriteString(out, diffLines(string(src), string(res)))
+	}
+
+	if options.Write {
+		if bytes.Equal(src, res) {
+			return nil
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+
+		return ioutil.WriteFile(file, res, info.Mode().Perm())
+	}
+
+	if !options.Diff {
+		_, _ = out.Write(res)
+	}
+
+	return nil
+}
+
+func runFmtCommand(options *Options) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	write := flags.Bool("w", false, "write the result to the source file")
+	diff := flags.Bool("d", false, "display the diffs instead of the formatted source")
+	if err := flags.Parse(options.Args); err != nil {
+		return 2
+	}
+
+	formatOptions := &FormatOptions{Write: *write, Diff: *diff}
+
+	if flags.NArg() == 0 {
+		if *write {
+			_, _ = fmt.Fprintln(os.Stderr, "Cannot use -w with standard input")
+			return 2
+		}
+
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+
+		res, err := format.Source(src)
+		if err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			return 1
+		}
+
+		if *diff {
+			_, _ = io.WriteString(os.Stdout, diffLines(string(src), string(res)))
+		} else {
+			_, _ = os.Stdout.Write(res)
+		}
+
+		return 0
+	}
+
+	files, err := FindSourceFiles(flags.Args())
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+
+	exitCode := 0
+	for _, file := range files {
+		if err := FormatFile(file, formatOptions, os.Stdout); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err.Error())
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
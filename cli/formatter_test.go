@@ -0,0 +1,51 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/cli"
+)
+
+func TestCLIFormat(t *testing.T) {
+	tempDir := filepath.Join(os.TempDir(), "tengo_format")
+	_ = os.MkdirAll(tempDir, os.ModePerm)
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	file := filepath.Join(tempDir, "a.tengo")
+	writeFile(t, file, "a:=1\nb := 2\nc:=3\n")
+	writeFile(t, filepath.Join(tempDir, "b.txt"), "a:=1")
+
+	files, err := cli.FindSourceFiles([]string{tempDir})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{file}, files)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, cli.FormatFile(file, nil, out))
+	assert.Equal(t, "a := 1\nb := 2\nc := 3\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, cli.FormatFile(file, &cli.FormatOptions{Diff: true}, out))
+	assert.Equal(t, "diff "+file+" "+file+"\n--- "+file+".orig\n+++ "+file+"\n"+
+		"@@ -1,3 +1,3 @@\n-a:=1\n+a := 1\n b := 2\n-c:=3\n+c := 3\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, cli.FormatFile(file, &cli.FormatOptions{Write: true}, out))
+	assert.Equal(t, "", out.String())
+	formatted, err := ioutil.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "a := 1\nb := 2\nc := 3\n", string(formatted))
+
+	// no diffs after formatting
+	assert.NoError(t, cli.FormatFile(file, &cli.FormatOptions{Diff: true}, out))
+	assert.Equal(t, "", out.String())
+
+	writeFile(t, file, "a:=")
+	assert.Error(t, cli.FormatFile(file, nil, out))
+}
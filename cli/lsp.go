@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/d5/tengo/lsp"
+)
+
+func runLSPCommand(options *Options) int {
+	flags := flag.NewFlagSet("lsp", flag.ContinueOnError)
+	if err := flags.Parse(options.Args); err != nil {
+		return 2
+	}
+
+	if err := lsp.NewServer(options.Modules).Serve(os.Stdin, os.Stdout); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,530 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/compiler/token"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+)
+
+const (
+	replContinuePrompt = ".. "
+	replHistorySize    = 1000
+	replPrintln        = "__repl_println__"
+	replValue          = "__repl_value__"
+)
+
+// replCommands are the meta-commands of the REPL.
+var replCommands = []struct {
+	usage string
+	help  string
+}{
+	{":load file", "run the source file"},
+	{":dis expr", "show the compiled instructions of the expression"},
+	{":type expr", "evaluate the expression and show the type of the value"},
+	{":globals", "show the global variables"},
+	{":reset", "remove all the variables"},
+	{":history", "show the input history"},
+	{":help", "show this help"},
+}
+
+// REPL is the interactive interpreter. The input is buffered until it
+// forms complete statements, so a statement can span multiple lines. An
+// input line ending with a tab character shows the completions of the
+// name before it instead of being evaluated. The lines starting with ':'
+// are the meta-commands (see ":help").
+type REPL struct {
+	modules     *objects.ModuleMap
+	in          io.Reader
+	out         io.Writer
+	historyFile string
+	history     []string
+	fileSet     *source.FileSet
+	symbolTable *compiler.SymbolTable
+	globals     []objects.Object
+	constants   []objects.Object
+}
+
+// NewREPL creates a REPL that reads the input from in and writes the
+// results into out.
+func NewREPL(modules *objects.ModuleMap, in io.Reader, out io.Writer) *REPL {
+	r := &REPL{
+		modules: modules,
+		in:      in,
+		out:     out,
+	}
+	r.reset()
+
+	return r
+}
+
+// SetHistoryFile sets the file where the input history is loaded from and
+// saved into. The history is not persisted if path is empty.
+func (r *REPL) SetHistoryFile(path string) {
+	r.historyFile = path
+	r.history = nil
+
+	if path == "" {
+		return
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if entry, err := strconv.Unquote(line); err == nil {
+			r.history = append(r.history, entry)
+		}
+	}
+
+	if len(r.history) > replHistorySize {
+		r.history = r.history[len(r.history)-replHistorySize:]
+		r.saveHistory()
+	}
+}
+
+// Run reads and evaluates the input until the end of the input.
+func (r *REPL) Run() {
+	stdin := bufio.NewScanner(r.in)
+
+	var buf string
+	for {
+		if buf == "" {
+			_, _ = fmt.Fprint(r.out, replPrompt)
+		} else {
+			_, _ = fmt.Fprint(r.out, replContinuePrompt)
+		}
+
+		if !stdin.Scan() {
+			return
+		}
+
+		line := stdin.Text()
+
+		// a tab at the end of the line requests the completions, while the
+		// tabs inside the line are part of the input
+		if prefix := strings.TrimSuffix(line, "\t"); prefix != line && strings.TrimSpace(prefix) != "" {
+			r.complete(prefix)
+			continue
+		}
+
+		if buf == "" {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			if strings.HasPrefix(strings.TrimSpace(line), ":") {
+				r.addHistory(line)
+				r.command(strings.TrimSpace(line))
+				continue
+			}
+		}
+
+		buf += line + "\n"
+
+		// an empty line ends the incomplete input
+		file, err := r.parse("repl", buf)
+		if err != nil && incomplete(err, buf) && strings.TrimSpace(line) != "" {
+			continue
+		}
+
+		r.addHistory(strings.TrimSuffix(buf, "\n"))
+		buf = ""
+
+		if err != nil {
+			printError(r.out, err)
+			continue
+		}
+
+		r.eval(addPrints(file))
+	}
+}
+
+// RunREPL starts REPL.
+func RunREPL(modules *objects.ModuleMap, in io.Reader, out io.Writer) {
+	NewREPL(modules, in, out).Run()
+}
+
+func (r *REPL) reset() {
+	r.fileSet = source.NewFileSet()
+	r.globals = make([]objects.Object, runtime.GlobalsSize)
+	r.constants = nil
+
+	r.symbolTable = compiler.NewSymbolTable()
+	for idx, fn := range objects.Builtins {
+		r.symbolTable.DefineBuiltin(idx, fn.Name)
+	}
+
+	// embed println function
+	symbol := r.symbolTable.Define(replPrintln)
+	r.globals[symbol.Index] = &objects.UserFunction{
+		Name: "println",
+		Value: func(args ...objects.Object) (ret objects.Object, err error) {
+			var printArgs []interface{}
+			for _, arg := range args {
+				printArgs = append(printArgs, replString(arg))
+			}
+
+			printArgs = append(printArgs, "\n")
+			_, _ = fmt.Fprint(r.out, printArgs...)
+
+			return
+		},
+	}
+
+	// the value of the expression evaluated by ':type'
+	r.symbolTable.Define(replValue)
+}
+
+func (r *REPL) parse(filename, src string) (*ast.File, error) {
+	srcFile := r.fileSet.AddFile(filename, -1, len(src))
+	p := parser.NewParser(srcFile, []byte(src), nil)
+
+	return p.ParseFile()
+}
+
+// compile compiles the file with the current symbols. The compiled
+// constants are discarded unless keep is set.
+func (r *REPL) compile(file *ast.File, keep bool) (*compiler.Bytecode, error) {
+	constants := r.constants
+	if !keep {
+		constants = append([]objects.Object(nil), r.constants...)
+	}
+
+	c := compiler.NewCompiler(file.InputFile, r.symbolTable, constants, r.modules, nil)
+	if err := c.Compile(file); err != nil {
+		return nil, err
+	}
+
+	return c.Bytecode(), nil
+}
+
+func (r *REPL) eval(file *ast.File) bool {
+	bytecode, err := r.compile(file, true)
+	if err != nil {
+		printError(r.out, err)
+		return false
+	}
+
+	machine := runtime.NewVM(bytecode, r.globals, -1)
+	if err := machine.Run(); err != nil {
+		_, _ = fmt.Fprintln(r.out, err.Error())
+		return false
+	}
+
+	r.constants = bytecode.Constants
+
+	return true
+}
+
+func (r *REPL) command(line string) {
+	name, arg := line, ""
+	if idx := strings.IndexAny(line, " \t"); idx >= 0 {
+		name, arg = line[:idx], strings.TrimSpace(line[idx+1:])
+	}
+
+	switch name {
+	case ":load":
+		r.load(arg)
+	case ":dis":
+		r.disassemble(arg)
+	case ":type":
+		r.typeOf(arg)
+	case ":globals":
+		r.printGlobals()
+	case ":reset":
+		r.reset()
+	case ":history":
+		for idx, entry := range r.history {
+			_, _ = fmt.Fprintf(r.out, "%4d  %s\n", idx+1, strings.Replace(entry, "\n", "\n      ", -1))
+		}
+	case ":help":
+		for _, cmd := range replCommands {
+			_, _ = fmt.Fprintf(r.out, "%-12s %s\n", cmd.usage, cmd.help)
+		}
+	default:
+		_, _ = fmt.Fprintf(r.out, "unknown command: %s (see :help)\n", name)
+	}
+}
+
+func (r *REPL) load(filename string) {
+	if filename == "" {
+		_, _ = fmt.Fprintln(r.out, "usage: :load file")
+		return
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		_, _ = fmt.Fprintln(r.out, err.Error())
+		return
+	}
+
+	file, err := r.parse(filename, string(data))
+	if err != nil {
+		printError(r.out, err)
+		return
+	}
+
+	r.eval(file)
+}
+
+func (r *REPL) disassemble(src string) {
+	file, _, ok := r.parseExpr(":dis", src)
+	if !ok {
+		return
+	}
+
+	numConstants := len(r.constants)
+	bytecode, err := r.compile(file, false)
+	if err != nil {
+		printError(r.out, err)
+		return
+	}
+
+	for _, l := range bytecode.FormatInstructions() {
+		_, _ = fmt.Fprintln(r.out, strings.TrimRight(l, " "))
+	}
+
+	// the constants added by the expression
+	lines := bytecode.FormatConstants()
+	first := fmt.Sprintf("[% 3d]", numConstants)
+	for idx, l := range lines {
+		if strings.HasPrefix(l, first) {
+			lines = lines[idx:]
+			break
+		}
+	}
+	if numConstants == len(bytecode.Constants) {
+		lines = nil
+	}
+
+	for _, l := range lines {
+		_, _ = fmt.Fprintln(r.out, l)
+	}
+}
+
+func (r *REPL) typeOf(src string) {
+	file, expr, ok := r.parseExpr(":type", src)
+	if !ok {
+		return
+	}
+
+	// __repl_value__ = expr
+	ok = r.eval(&ast.File{
+		InputFile: file.InputFile,
+		Stmts: []ast.Stmt{&ast.AssignStmt{
+			LHS:   []ast.Expr{&ast.Ident{Name: replValue}},
+			RHS:   []ast.Expr{expr},
+			Token: token.Assign,
+		}},
+	})
+	if !ok {
+		return
+	}
+
+	symbol, _, _ := r.symbolTable.Resolve(replValue)
+	_, _ = fmt.Fprintln(r.out, replTypeName(r.globals[symbol.Index]))
+	r.globals[symbol.Index] = nil
+}
+
+// parseExpr parses the source that must be a single expression.
+func (r *REPL) parseExpr(command, src string) (*ast.File, ast.Expr, bool) {
+	if src == "" {
+		_, _ = fmt.Fprintf(r.out, "usage: %s expr\n", command)
+		return nil, nil, false
+	}
+
+	file, err := r.parse("repl", src)
+	if err != nil {
+		printError(r.out, err)
+		return nil, nil, false
+	}
+
+	if len(file.Stmts) == 1 {
+		if stmt, ok := file.Stmts[0].(*ast.ExprStmt); ok {
+			return file, stmt.Expr, true
+		}
+	}
+
+	_, _ = fmt.Fprintf(r.out, "%s: expected an expression\n", command)
+
+	return nil, nil, false
+}
+
+func (r *REPL) printGlobals() {
+	names := r.globalNames()
+	sort.Strings(names)
+
+	for _, name := range names {
+		symbol, _, _ := r.symbolTable.Resolve(name)
+		value := r.globals[symbol.Index]
+		_, _ = fmt.Fprintf(r.out, "%s (%s) = %s\n", name, replTypeName(value), replString(value))
+	}
+}
+
+// globalNames returns the names of the global variables defined by the user.
+func (r *REPL) globalNames() []string {
+	var names []string
+	for _, name := range r.symbolTable.Names() {
+		symbol, _, _ := r.symbolTable.Resolve(name)
+		if symbol.Scope == compiler.ScopeGlobal && !strings.HasPrefix(name, "__repl_") && !strings.HasPrefix(name, ":") {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// complete prints the completions of the name at the end of the input:
+// the global variables and the builtin functions, or the members of the
+// module (or map) for the selector expressions like "fmt.pr".
+func (r *REPL) complete(input string) {
+	start := len(input)
+	for start > 0 && (isNameChar(input[start-1]) || input[start-1] == '.') {
+		start--
+	}
+	word := input[start:]
+
+	var candidates []string
+	if idx := strings.LastIndexByte(word, '.'); idx >= 0 {
+		base, prefix := word[:idx], word[idx+1:]
+		for _, key := range r.memberNames(base) {
+			if strings.HasPrefix(key, prefix) {
+				candidates = append(candidates, base+"."+key)
+			}
+		}
+	} else {
+		names := r.globalNames()
+		for _, symbol := range r.symbolTable.BuiltinSymbols() {
+			names = append(names, symbol.Name)
+		}
+
+		for _, name := range names {
+			if strings.HasPrefix(name, word) {
+				candidates = append(candidates, name)
+			}
+		}
+	}
+
+	sort.Strings(candidates)
+
+	var res []string
+	for i, candidate := range candidates {
+		if i == 0 || candidate != candidates[i-1] {
+			res = append(res, candidate)
+		}
+	}
+
+	if len(res) > 0 {
+		_, _ = fmt.Fprintln(r.out, strings.Join(res, "  "))
+	}
+}
+
+// memberNames returns the keys of the map value of the global variable.
+func (r *REPL) memberNames(name string) []string {
+	symbol, _, ok := r.symbolTable.Resolve(name)
+	if !ok || symbol.Scope != compiler.ScopeGlobal {
+		return nil
+	}
+
+	var m map[string]objects.Object
+	switch value := r.globals[symbol.Index].(type) {
+	case *objects.ImmutableMap:
+		m = value.Value
+	case *objects.Map:
+		m = value.Value
+	}
+
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func (r *REPL) addHistory(entry string) {
+	r.history = append(r.history, entry)
+	if len(r.history) > replHistorySize {
+		r.history = r.history[len(r.history)-replHistorySize:]
+	}
+
+	if r.historyFile == "" {
+		return
+	}
+
+	f, err := os.OpenFile(r.historyFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+
+	_, _ = fmt.Fprintln(f, strconv.Quote(entry))
+	_ = f.Close()
+}
+
+func (r *REPL) saveHistory() {
+	var buf strings.Builder
+	for _, entry := range r.history {
+		buf.WriteString(strconv.Quote(entry))
+		buf.WriteByte('\n')
+	}
+
+	_ = ioutil.WriteFile(r.historyFile, []byte(buf.String()), 0600)
+}
+
+// incomplete returns true if the parse error is caused by the end of the
+// input, so that more lines can complete the statements.
+func incomplete(err error, src string) bool {
+	list, ok := err.(parser.ErrorList)
+	if !ok {
+		return false
+	}
+
+	for _, e := range list {
+		if e.Pos.Offset >= len(src) || e.Msg == "raw string literal not terminated" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func replString(o objects.Object) string {
+	if o == nil {
+		return "<undefined>"
+	}
+
+	if _, isUndefined := o.(*objects.Undefined); isUndefined {
+		return "<undefined>"
+	}
+
+	s, _ := objects.ToString(o)
+
+	return s
+}
+
+func replTypeName(o objects.Object) string {
+	if o == nil {
+		return objects.UndefinedValue.TypeName()
+	}
+
+	return o.TypeName()
+}
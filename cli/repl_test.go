@@ -0,0 +1,111 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/cli"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestREPL(t *testing.T) {
+	expectREPL(t, "a := 1\na + 2\n", "1", "3")
+
+	// multi-line input
+	expectREPL(t, "f := func(a) {\nreturn a * 2\n}\nf(3)\n", "<compiled-function>", "6")
+	expectREPL(t, "a := [1,\n2]\n", "[1, 2]")
+	expectREPL(t, "s := `a\nb`\n", "a\nb")
+
+	// an empty line ends the incomplete input
+	expectREPL(t, "a := [1,\n\n2\n", "Parse Error: expected ']', found 'EOF'\n\tat repl:2:2", "2")
+
+	// errors
+	expectREPL(t, "a := )\nb\n1\n",
+		"Parse Error: expected operand, found ')'\n\tat repl:1:6",
+		"Compile Error: unresolved reference 'b'\n\tat repl:1:1",
+		"1")
+	expectREPL(t, "1 / 0\n", "Runtime Error: runtime error: integer divide by zero\n\tat repl:1:1")
+}
+
+func TestREPL_Completion(t *testing.T) {
+	expectREPL(t, "apple := 1\nap\t\n", "1", "append  apple")
+	expectREPL(t, "fmt := 0\nif true { fmt = import(\"fmt\") }\nfmt.pr\t\nx := fmt.pri\t\n",
+		"0",
+		"fmt.print  fmt.printf  fmt.println",
+		"fmt.print  fmt.printf  fmt.println")
+	expectREPL(t, "zz\t\n")
+
+	// a tab inside the line is not a completion request
+	expectREPL(t, "f := func(x) {\n  y := x * 2\t// double\n  return y\n}\nf(3)\n", "<compiled-function>", "6")
+	expectREPL(t, "a := 1\t+ 2\n", "3")
+}
+
+func TestREPL_Commands(t *testing.T) {
+	expectREPL(t, "a := 1\nb := \"x\"\n:globals\n", "1", "x", "a (int) = 1\nb (string) = x")
+	expectREPL(t, "a := 1\n:reset\na\n", "1", "Compile Error: unresolved reference 'a'\n\tat repl:1:1")
+	expectREPL(t, "a := 1.5\n:type a\n:type [a]\n:type\n:type a := 1\n",
+		"1.5", "float", "array", "usage: :type expr", ":type: expected an expression")
+	expectREPL(t, "a := 1\n:dis a + 2\n",
+		"1",
+		"0000 GETG    2\n0003 CONST   1\n0006 BINARYOP 11\n0008 POP\n[  1] 2 (Int)")
+	expectREPL(t, "a := 1\n:dis a\n", "1", "0000 GETG    2\n0003 POP")
+	expectREPL(t, ":unknown\n", "unknown command: :unknown (see :help)")
+
+	tempDir, err := ioutil.TempDir("", "tengo_repl")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	file := filepath.Join(tempDir, "lib.tengo")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("double := func(x) {\n\treturn x * 2\n}\n"), 0644))
+	expectREPL(t, ":load "+file+"\ndouble(4)\n", "8")
+	expectREPL(t, ":load\n", "usage: :load file")
+}
+
+func TestREPL_History(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "tengo_repl")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	historyFile := filepath.Join(tempDir, "history")
+
+	out := &bytes.Buffer{}
+	repl := cli.NewREPL(nil, strings.NewReader("a := 1\nf := func() {\nreturn a\n}\n"), out)
+	repl.SetHistoryFile(historyFile)
+	repl.Run()
+
+	// the history is loaded from the file
+	out.Reset()
+	repl = cli.NewREPL(nil, strings.NewReader(":history\n"), out)
+	repl.SetHistoryFile(historyFile)
+	repl.Run()
+	assert.Equal(t, ">>    1  a := 1\n   2  f := func() {\n      return a\n      }\n   3  :history\n>> ", out.String())
+}
+
+var pointers = regexp.MustCompile(`\|0x[0-9a-f]+\)`)
+
+// expectREPL runs the input in a new REPL and compares the outputs of each
+// input (without the prompts and the pointers) with the expected.
+func expectREPL(t *testing.T, input string, expected ...string) {
+	out := &bytes.Buffer{}
+	cli.RunREPL(stdlib.GetModuleMap(stdlib.AllModuleNames()...), strings.NewReader(input), out)
+
+	var actual []string
+	for _, s := range strings.Split(pointers.ReplaceAllString(out.String(), ")"), ">> ") {
+		s = strings.TrimRight(strings.Replace(s, ".. ", "", -1), "\n")
+		if s != "" {
+			actual = append(actual, s)
+		}
+	}
+
+	assert.Equal(t, expected, actual)
+}
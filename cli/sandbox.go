@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/compiler/token"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+)
+
+// Sandbox represents the restrictions on the scripts run by the CLI. The
+// zero value has no restrictions.
+type Sandbox struct {
+	// Maximum execution time. There is no limit if 0 or less.
+	Timeout time.Duration
+
+	// Maximum number of the objects allocated by the script. There is no
+	// limit if 0 or less.
+	MaxAllocs int64
+
+	// Maximum number of the objects in the compiled constants. There is no
+	// limit if 0 or less.
+	MaxConstObjects int
+
+	// Names of the modules that can be imported. All the modules can be
+	// imported if empty.
+	AllowedModules []string
+
+	// Names of the modules that cannot be imported.
+	DeniedModules []string
+
+	// Disallow importing the modules from the local files.
+	DisableFileImport bool
+}
+
+// Modules returns a copy of the module map with only the modules allowed by
+// the sandbox.
+func (s *Sandbox) Modules(modules *objects.ModuleMap) (*objects.ModuleMap, error) {
+	if modules == nil {
+		modules = objects.NewModuleMap()
+	}
+
+	res := modules.Copy()
+	if len(s.AllowedModules) > 0 {
+		res = objects.NewModuleMap()
+		for _, name := range s.AllowedModules {
+			mod := modules.Get(name)
+			if mod == nil {
+				return nil, fmt.Errorf("unknown module: %s", name)
+			}
+			res.Add(name, mod)
+		}
+	}
+
+	for _, name := range s.DeniedModules {
+		if modules.Get(name) == nil {
+			return nil, fmt.Errorf("unknown module: %s", name)
+		}
+		res.Remove(name)
+	}
+
+	return res, nil
+}
+
+// CompileOnly compiles the source code and writes the compiled binary into outputFile.
+func (s *Sandbox) CompileOnly(modules *objects.ModuleMap, data []byte, inputFile, outputFile string) (err error) {
+	bytecode, err := s.compileSrc(modules, data, filepath.Base(inputFile))
+	if err != nil {
+		return
+	}
+
+	if outputFile == "" {
+		outputFile = basename(inputFile) + ".out"
+	}
+
+	out, err := os.OpenFile(outputFile, os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+		} else {
+			err = out.Close()
+		}
+	}()
+
+	err = bytecode.Encode(out)
+	if err != nil {
+		return
+	}
+
+	fmt.Println(outputFile)
+
+	return
+}
+
+// CompileAndRun compiles the source code and executes it.
+func (s *Sandbox) CompileAndRun(modules *objects.ModuleMap, data []byte, inputFile string) error {
+	bytecode, err := s.compileSrc(modules, data, filepath.Base(inputFile))
+	if err != nil {
+		return err
+	}
+
+	return s.run(bytecode, nil)
+}
+
+// RunCompiled reads the compiled binary or the program bundle from file and
+// executes it.
+func (s *Sandbox) RunCompiled(modules *objects.ModuleMap, data []byte) error {
+	bytecode, err := decodeProgram(modules, data)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkConstObjects(bytecode); err != nil {
+		return err
+	}
+
+	return s.run(bytecode, nil)
+}
+
+// EvalAndPrint compiles and runs the source code, and writes the value of
+// the last statement into out if it is an expression statement and its
+// value is not undefined.
+func (s *Sandbox) EvalAndPrint(modules *objects.ModuleMap, src string, out io.Writer) error {
+	fileSet := source.NewFileSet()
+	srcFile := fileSet.AddFile("(eval)", -1, len(src))
+
+	p := parser.NewParser(srcFile, []byte(src), nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return err
+	}
+
+	symbolTable := compiler.NewSymbolTable()
+	for idx, fn := range objects.Builtins {
+		symbolTable.DefineBuiltin(idx, fn.Name)
+	}
+	result := symbolTable.Define("__eval_result__")
+
+	// __eval_result__ = expr
+	if n := len(file.Stmts); n > 0 {
+		if stmt, ok := file.Stmts[n-1].(*ast.ExprStmt); ok {
+			file.Stmts[n-1] = &ast.AssignStmt{
+				LHS:      []ast.Expr{&ast.Ident{Name: result.Name, NamePos: stmt.Pos()}},
+				RHS:      []ast.Expr{stmt.Expr},
+				Token:    token.Assign,
+				TokenPos: stmt.Pos(),
+			}
+		}
+	}
+
+	c := compiler.NewCompiler(srcFile, symbolTable, nil, modules, nil)
+	c.EnableFileImport(!s.DisableFileImport)
+	if err := c.Compile(file); err != nil {
+		return err
+	}
+
+	bytecode := c.Bytecode()
+	if err := s.checkConstObjects(bytecode); err != nil {
+		return err
+	}
+
+	globals := make([]objects.Object, runtime.GlobalsSize)
+	if err := s.run(bytecode, globals); err != nil {
+		return err
+	}
+
+	value := globals[result.Index]
+	if _, isUndefined := value.(*objects.Undefined); value != nil && !isUndefined {
+		str, _ := objects.ToString(value)
+		_, _ = fmt.Fprintln(out, str)
+	}
+
+	return nil
+}
+
+func (s *Sandbox) compileSrc(modules *objects.ModuleMap, src []byte, filename string) (*compiler.Bytecode, error) {
+	fileSet := source.NewFileSet()
+	srcFile := fileSet.AddFile(filename, -1, len(src))
+
+	p := parser.NewParser(srcFile, src, nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	c := compiler.NewCompiler(srcFile, nil, nil, modules, nil)
+	c.EnableFileImport(!s.DisableFileImport)
+
+	if err := c.Compile(file); err != nil {
+		return nil, err
+	}
+
+	bytecode := c.Bytecode()
+	bytecode.RemoveDuplicates()
+
+	if err := s.checkConstObjects(bytecode); err != nil {
+		return nil, err
+	}
+
+	return bytecode, nil
+}
+
+func (s *Sandbox) checkConstObjects(bytecode *compiler.Bytecode) error {
+	if s.MaxConstObjects <= 0 {
+		return nil
+	}
+
+	if cnt := bytecode.CountObjects(); cnt > s.MaxConstObjects {
+		return fmt.Errorf("exceeding constant objects limit: %d", cnt)
+	}
+
+	return nil
+}
+
+// run runs the bytecode within the allocation limit and the timeout.
+func (s *Sandbox) run(bytecode *compiler.Bytecode, globals []objects.Object) error {
+	maxAllocs := s.MaxAllocs
+	if maxAllocs <= 0 {
+		maxAllocs = -1
+	}
+
+	machine := runtime.NewVM(bytecode, globals, maxAllocs)
+	if s.Timeout <= 0 {
+		return machine.Run()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+
+	err := machine.RunContext(ctx)
+	if err == context.DeadlineExceeded {
+		return fmt.Errorf("execution timeout: %s", s.Timeout)
+	}
+
+	return err
+}
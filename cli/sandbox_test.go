@@ -0,0 +1,80 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/cli"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestSandbox_Modules(t *testing.T) {
+	modules := stdlib.GetModuleMap("fmt", "os", "math", "enum")
+
+	res, err := (&cli.Sandbox{}).Modules(modules)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, res.Len())
+
+	res, err = (&cli.Sandbox{AllowedModules: []string{"fmt", "enum"}}).Modules(modules)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Len())
+	assert.NotNil(t, res.Get("fmt"))
+	assert.NotNil(t, res.Get("enum"))
+
+	res, err = (&cli.Sandbox{DeniedModules: []string{"os"}}).Modules(modules)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, res.Len())
+	assert.Nil(t, res.Get("os"))
+
+	// the original module map is not changed
+	assert.Equal(t, 4, modules.Len())
+
+	_, err = (&cli.Sandbox{AllowedModules: []string{"foo"}}).Modules(modules)
+	assert.Equal(t, "unknown module: foo", err.Error())
+	_, err = (&cli.Sandbox{DeniedModules: []string{"foo"}}).Modules(modules)
+	assert.Equal(t, "unknown module: foo", err.Error())
+}
+
+func TestSandbox_Limits(t *testing.T) {
+	modules := stdlib.GetModuleMap("fmt")
+
+	sandbox := &cli.Sandbox{Timeout: 50 * time.Millisecond}
+	assert.Equal(t, "execution timeout: 50ms", sandbox.CompileAndRun(modules, []byte(`for {}`), "test.tengo").Error())
+	assert.NoError(t, sandbox.CompileAndRun(modules, []byte(`a := 1`), "test.tengo"))
+
+	sandbox = &cli.Sandbox{MaxAllocs: 10}
+	err := sandbox.EvalAndPrint(modules, `a := []; for i := 0; i < 100; i++ { a = append(a, [i]) }`, &bytes.Buffer{})
+	assert.Error(t, err)
+	assert.NoError(t, sandbox.EvalAndPrint(modules, `a := [1]`, &bytes.Buffer{}))
+
+	sandbox = &cli.Sandbox{MaxConstObjects: 2}
+	err = sandbox.CompileAndRun(modules, []byte(`a := [1, 2, 3]`), "test.tengo")
+	assert.Equal(t, "exceeding constant objects limit: 3", err.Error())
+	assert.NoError(t, sandbox.CompileAndRun(modules, []byte(`a := 1`), "test.tengo"))
+}
+
+func TestSandbox_DisableFileImport(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "tengo_sandbox")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(tempDir))
+	defer func() { _ = os.Chdir(wd) }()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "mod.tengo"), []byte(`export 1`), 0644))
+
+	src := `a := import("./mod")`
+	assert.NoError(t, (&cli.Sandbox{}).EvalAndPrint(nil, src, &bytes.Buffer{}))
+
+	err = (&cli.Sandbox{DisableFileImport: true}).EvalAndPrint(nil, src, &bytes.Buffer{})
+	assert.Equal(t, "Compile Error: module './mod' not found\n\tat (eval):1:6", err.Error())
+}
@@ -0,0 +1,406 @@
+package cli
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+)
+
+const (
+	testFileSuffix = "_test" + sourceFileExt
+	testFuncPrefix = "test_"
+	testsVarName   = "__tests__"
+)
+
+// TestOptions represent the options of the test runner.
+type TestOptions struct {
+	// Run only the tests whose names match the regular expression.
+	Run *regexp.Regexp
+
+	// Print the results of all tests, not only the failed ones.
+	Verbose bool
+}
+
+// TestResult represents the result of a single test function.
+type TestResult struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Trace    []string      `json:"trace,omitempty"`
+}
+
+// TestFileResult represents the results of all tests in a test file.
+type TestFileResult struct {
+	File     string        `json:"file"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Tests    []*TestResult `json:"tests"`
+}
+
+// TestReport represents the results of a test run.
+type TestReport struct {
+	Passed bool              `json:"passed"`
+	Files  []*TestFileResult `json:"files"`
+}
+
+// FindTestFiles returns the test files found in the given paths. Directories
+// are searched recursively for the files ending with "_test.tengo", and
+// other paths are returned as they are.
+func FindTestFiles(paths []string) ([]string, error) {
+	return findFiles(paths, func(path string) bool {
+		return strings.HasSuffix(path, testFileSuffix)
+	})
+}
+
+// RunTests runs the tests in the given test files and writes the progress
+// into out. A test file is imported as a module, and each of its exported
+// functions whose name starts with "test_" is run in a fresh VM with its own
+// globals. A test fails if it returns a run-time error.
+func RunTests(modules *objects.ModuleMap, files []string, options *TestOptions, out io.Writer) *TestReport {
+	if options == nil {
+		options = &TestOptions{}
+	}
+
+	report := &TestReport{Passed: true}
+	for _, file := range files {
+		res := runTestFile(modules, file, options, out)
+		if !res.Passed {
+			report.Passed = false
+		}
+
+		report.Files = append(report.Files, res)
+	}
+
+	return report
+}
+
+func runTestFile(modules *objects.ModuleMap, file string, options *TestOptions, out io.Writer) *TestFileResult {
+	res := &TestFileResult{File: file, Passed: true}
+	start := time.Now()
+	defer func() {
+		res.Duration = time.Since(start)
+		if res.Passed {
+			_, _ = fmt.Fprintf(out, "ok  \t%s\t%.3fs\n", file, res.Duration.Seconds())
+		} else {
+			_, _ = fmt.Fprintf(out, "FAIL\t%s\t%.3fs\n", file, res.Duration.Seconds())
+		}
+	}()
+
+	bytecode, index, err := compileTestHarness(modules, file)
+	if err != nil {
+		res.Passed = false
+		res.Error = err.Error()
+		_, _ = fmt.Fprintln(out, err.Error())
+		return res
+	}
+
+	tests, err := loadTests(bytecode, index)
+	if err != nil {
+		res.Passed = false
+		res.Error = err.Error()
+		_, _ = fmt.Fprintln(out, err.Error())
+		return res
+	}
+
+	for _, name := range tests {
+		if options.Run != nil && !options.Run.MatchString(name) {
+			continue
+		}
+
+		if options.Verbose {
+			_, _ = fmt.Fprintf(out, "=== RUN   %s\n", name)
+		}
+
+		tr := runTest(bytecode, index, name)
+		if !tr.Passed {
+			res.Passed = false
+		}
+		res.Tests = append(res.Tests, tr)
+
+		if tr.Passed {
+			if options.Verbose {
+				_, _ = fmt.Fprintf(out, "--- PASS: %s (%.2fs)\n", name, tr.Duration.Seconds())
+			}
+			continue
+		}
+
+		_, _ = fmt.Fprintf(out, "--- FAIL: %s (%.2fs)\n", name, tr.Duration.Seconds())
+		_, _ = fmt.Fprintf(out, "    %s\n", tr.Error)
+		for _, pos := range tr.Trace {
+			_, _ = fmt.Fprintf(out, "    \tat %s\n", pos)
+		}
+	}
+
+	return res
+}
+
+// compileTestHarness compiles a main function that imports the test file and
+// stores the module into a global variable. It returns the index of the
+// global variable along with the bytecode.
+func compileTestHarness(modules *objects.ModuleMap, file string) (*compiler.Bytecode, int, error) {
+	src := []byte(fmt.Sprintf("%s := import(%s)", testsVarName, strconv.Quote(file)))
+
+	fileSet := source.NewFileSet()
+	srcFile := fileSet.AddFile("(test)", -1, len(src))
+
+	p := parser.NewParser(srcFile, src, nil)
+	parsed, err := p.ParseFile()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	symbolTable := compiler.NewSymbolTable()
+	for idx, fn := range objects.Builtins {
+		symbolTable.DefineBuiltin(idx, fn.Name)
+	}
+
+	c := compiler.NewCompiler(srcFile, symbolTable, nil, modules, nil)
+	c.EnableFileImport(true)
+	if err := c.Compile(parsed); err != nil {
+		return nil, 0, err
+	}
+
+	symbol, _, _ := symbolTable.Resolve(testsVarName)
+
+	return c.Bytecode(), symbol.Index, nil
+}
+
+// loadTests runs the test harness once and returns the sorted names of the
+// test functions exported by the test file.
+func loadTests(bytecode *compiler.Bytecode, index int) ([]string, error) {
+	module, _, err := runTestHarness(bytecode, index)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name, value := range module.Value {
+		if !strings.HasPrefix(name, testFuncPrefix) {
+			continue
+		}
+
+		switch value.(type) {
+		case *objects.CompiledFunction, *objects.Closure:
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func runTestHarness(bytecode *compiler.Bytecode, index int) (*objects.ImmutableMap, *runtime.VM, error) {
+	globals := make([]objects.Object, runtime.GlobalsSize)
+
+	v := runtime.NewVM(bytecode, globals, -1)
+	if err := v.Run(); err != nil {
+		return nil, nil, err
+	}
+
+	module, ok := globals[index].(*objects.ImmutableMap)
+	if !ok {
+		return nil, nil, fmt.Errorf("test file must export a map: found %s", globals[index].TypeName())
+	}
+
+	return module, v, nil
+}
+
+func runTest(bytecode *compiler.Bytecode, index int, name string) *TestResult {
+	res := &TestResult{Name: name}
+
+	// run the harness again so that each test gets fresh module state
+	module, v, err := runTestHarness(bytecode, index)
+	if err != nil {
+		res.Error, res.Trace = splitTestError(err)
+		return res
+	}
+
+	start := time.Now()
+	_, err = v.Call(module.Value[name])
+	res.Duration = time.Since(start)
+
+	if err != nil {
+		res.Error, res.Trace = splitTestError(err)
+		return res
+	}
+
+	res.Passed = true
+
+	return res
+}
+
+func splitTestError(err error) (msg string, trace []string) {
+	lines := strings.Split(err.Error(), "\n")
+	for _, line := range lines[1:] {
+		trace = append(trace, strings.TrimPrefix(line, "\tat "))
+	}
+
+	return lines[0], trace
+}
+
+// WriteTestJSON writes the test report in JSON format.
+func WriteTestJSON(report *TestReport, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(report)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name          `xml:"testsuites"`
+	Suites  []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Error    *junitFailure    `xml:"error,omitempty"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message  string `xml:"message,attr"`
+	Contents string `xml:",chardata"`
+}
+
+// WriteTestJUnit writes the test report in JUnit XML format.
+func WriteTestJUnit(report *TestReport, w io.Writer) error {
+	suites := &junitTestSuites{}
+	for _, f := range report.Files {
+		suite := &junitTestSuite{
+			Name: f.File,
+			Time: fmt.Sprintf("%.3f", f.Duration.Seconds()),
+		}
+
+		if f.Error != "" {
+			suite.Errors = 1
+			suite.Error = &junitFailure{Message: f.Error, Contents: f.Error}
+		}
+
+		for _, t := range f.Tests {
+			tc := &junitTestCase{
+				Name:      t.Name,
+				ClassName: f.File,
+				Time:      fmt.Sprintf("%.3f", t.Duration.Seconds()),
+			}
+
+			if !t.Passed {
+				suite.Failures++
+				contents := t.Error
+				for _, pos := range t.Trace {
+					contents += "\n\tat " + pos
+				}
+				tc.Failure = &junitFailure{Message: t.Error, Contents: contents}
+			}
+
+			suite.Tests++
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+func runTestCommand(options *Options) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	run := flags.String("run", "", "run only the tests matching the regular expression")
+	verbose := flags.Bool("v", false, "print the results of all tests")
+	jsonOutput := flags.String("json", "", "write the report in JSON format to the file")
+	junitOutput := flags.String("junit", "", "write the report in JUnit XML format to the file")
+	if err := flags.Parse(options.Args); err != nil {
+		return 2
+	}
+
+	testOptions := &TestOptions{Verbose: *verbose}
+	if *run != "" {
+		re, err := regexp.Compile(*run)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Invalid -run pattern: %s\n", err.Error())
+			return 2
+		}
+		testOptions.Run = re
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := FindTestFiles(paths)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+
+	report := RunTests(options.Modules, files, testOptions, os.Stdout)
+
+	if *jsonOutput != "" {
+		if err := writeTestReport(*jsonOutput, report, WriteTestJSON); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error writing JSON report: %s\n", err.Error())
+			return 1
+		}
+	}
+
+	if *junitOutput != "" {
+		if err := writeTestReport(*junitOutput, report, WriteTestJUnit); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error writing JUnit report: %s\n", err.Error())
+			return 1
+		}
+	}
+
+	if !report.Passed {
+		return 1
+	}
+
+	return 0
+}
+
+func writeTestReport(path string, report *TestReport, write func(*TestReport, io.Writer) error) error {
+	var sb strings.Builder
+	if err := write(report, &sb); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, []byte(sb.String()), 0644)
+}
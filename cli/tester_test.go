@@ -0,0 +1,100 @@
+package cli_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/cli"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestCLITest(t *testing.T) {
+	tempDir := filepath.Join(os.TempDir(), "tengo_test_runner")
+	_ = os.MkdirAll(filepath.Join(tempDir, "sub"), os.ModePerm)
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	writeFile(t, filepath.Join(tempDir, "a_test.tengo"), `
+testing := import("testing")
+counter := 0
+export {
+	test_add: func() {
+		counter += 1
+		testing.equal(counter, 1)
+		testing.equal(1 + 1, 2)
+	},
+	test_fail: func() {
+		counter += 1
+		testing.equal(counter, 1)
+		testing.deep_equal([1, 2], [1, 3])
+	},
+	test_panics: func() {
+		testing.panics(func() { return 1 + "a" })
+	},
+	helper: func() {
+		testing.fail()
+	}
+}
+`)
+	writeFile(t, filepath.Join(tempDir, "sub", "b_test.tengo"), `export { test_ok: func() {} }`)
+	writeFile(t, filepath.Join(tempDir, "sub", "c_test.tengo"), `export { test_ok: func() {`)
+	writeFile(t, filepath.Join(tempDir, "sub", "d.tengo"), `export { test_ok: func() { 1 + "a" } }`)
+
+	files, err := cli.FindTestFiles([]string{tempDir})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(files))
+
+	mods := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+
+	out := &bytes.Buffer{}
+	report := cli.RunTests(mods, files, &cli.TestOptions{Verbose: true}, out)
+	assert.False(t, report.Passed)
+	assert.Equal(t, 3, len(report.Files))
+
+	a := report.Files[0]
+	assert.False(t, a.Passed)
+	assert.Equal(t, 3, len(a.Tests))
+	assert.Equal(t, "test_add", a.Tests[0].Name)
+	assert.True(t, a.Tests[0].Passed)
+	assert.Equal(t, "test_fail", a.Tests[1].Name)
+	assert.False(t, a.Tests[1].Passed)
+	assert.Equal(t, "assertion failed: expected array([1, 2]), found array([1, 3]) (at [1])", a.Tests[1].Error)
+	assert.Equal(t, []string{filepath.Join(tempDir, "a_test.tengo") + ":13:3"}, a.Tests[1].Trace)
+	assert.Equal(t, "test_panics", a.Tests[2].Name)
+	assert.True(t, a.Tests[2].Passed)
+
+	assert.True(t, report.Files[1].Passed)
+	assert.False(t, report.Files[2].Passed)
+	assert.True(t, strings.HasPrefix(report.Files[2].Error, "Parse Error"), report.Files[2].Error)
+
+	assert.True(t, strings.Contains(out.String(), "--- PASS: test_add"))
+	assert.True(t, strings.Contains(out.String(), "--- FAIL: test_fail"))
+	assert.True(t, strings.Contains(out.String(), "ok  \t"+files[1]))
+
+	// filter tests by name
+	out.Reset()
+	report = cli.RunTests(mods, files[:1], &cli.TestOptions{Run: regexp.MustCompile("add|panics")}, out)
+	assert.True(t, report.Passed)
+	assert.Equal(t, 2, len(report.Files[0].Tests))
+	assert.False(t, strings.Contains(out.String(), "--- PASS"))
+
+	// reports
+	buf := &bytes.Buffer{}
+	assert.NoError(t, cli.WriteTestJSON(report, buf))
+	assert.True(t, strings.Contains(buf.String(), `"name": "test_add"`), buf.String())
+
+	buf.Reset()
+	assert.NoError(t, cli.WriteTestJUnit(report, buf))
+	assert.True(t, strings.Contains(buf.String(), `<testsuite name="`+files[0]+`" tests="2" failures="0" errors="0"`), buf.String())
+}
+
+func writeFile(t *testing.T, path, src string) {
+	assert.NoError(t, ioutil.WriteFile(path, []byte(src), 0644))
+}
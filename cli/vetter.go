@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/compiler/vet"
+)
+
+// VetOptions represent the options of the linter.
+type VetOptions struct {
+	// Check all the files as modules.
+	Module bool
+}
+
+// VetFiles checks the source files for suspicious constructs and returns
+// the diagnostics in the order of the files. Test files and the files
+// imported by other files in the list are checked as modules.
+func VetFiles(files []string, options *VetOptions) ([]*vet.Diagnostic, error) {
+	if options == nil {
+		options = &VetOptions{}
+	}
+
+	parsed := make([]*ast.File, len(files))
+	imported := make(map[string]bool)
+	for i, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		fileSet := source.NewFileSet()
+		srcFile := fileSet.AddFile(file, -1, len(src))
+		p := parser.NewParserWithMode(srcFile, src, nil, parser.ParseComments)
+		parsed[i], err = p.ParseFile()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, path := range importPaths(parsed[i]) {
+			imported[path] = true
+		}
+	}
+
+	var res []*vet.Diagnostic
+	for i, file := range files {
+		absPath, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+
+		config := &vet.Config{
+			Module: options.Module || imported[absPath] || strings.HasSuffix(file, testFileSuffix),
+		}
+		res = append(res, vet.File(parsed[i], config)...)
+	}
+
+	return res, nil
+}
+
+// importPaths returns the absolute paths of the source files imported by
+// the file. The paths are relative to the current directory as they are
+// when the file is compiled.
+func importPaths(file *ast.File) []string {
+	var paths []string
+	ast.Inspect(file, func(node ast.Node) bool {
+		if x, ok := node.(*ast.ImportExpr); ok {
+			name := x.ModuleName
+			if !strings.HasSuffix(name, sourceFileExt) {
+				name += sourceFileExt
+			}
+
+			if path, err := filepath.Abs(name); err == nil {
+				paths = append(paths, path)
+			}
+		}
+
+		return true
+	})
+
+	return paths
+}
+
+func runVetCommand(options *Options) int {
+	flags := flag.NewFlagSet("vet", flag.ContinueOnError)
+	module := flags.Bool("module", false, "check all the files as modules")
+	if err := flags.Parse(options.Args); err != nil {
+		return 2
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := FindSourceFiles(paths)
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		return 2
+	}
+
+	diagnostics, err := VetFiles(files, &VetOptions{Module: *module})
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+
+	for _, d := range diagnostics {
+		_, _ = fmt.Fprintln(os.Stderr, d.String())
+	}
+
+	if len(diagnostics) > 0 {
+		return 1
+	}
+
+	return 0
+}
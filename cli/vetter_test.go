@@ -0,0 +1,47 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/cli"
+)
+
+func TestCLIVet(t *testing.T) {
+	tempDir := filepath.Join(os.TempDir(), "tengo_vet")
+	_ = os.MkdirAll(tempDir, os.ModePerm)
+	defer func() {
+		_ = os.RemoveAll(tempDir)
+	}()
+
+	main := filepath.Join(tempDir, "main.tengo")
+	lib := filepath.Join(tempDir, "lib.tengo")
+	writeFile(t, main, "fmt := import(\"fmt\")\nlib := import(\""+filepath.Join(tempDir, "lib")+"\")\nlib()\n")
+	writeFile(t, lib, "a := 1\nexport func() { return a }\n")
+
+	files, err := cli.FindSourceFiles([]string{tempDir})
+	assert.NoError(t, err)
+
+	// lib.tengo is a module because main.tengo imports it
+	diagnostics, err := cli.VetFiles(files, nil)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(diagnostics)) {
+		assert.Equal(t, main+":1:1: imported and not used: 'fmt' (unused-import)", diagnostics[0].String())
+	}
+
+	diagnostics, err = cli.VetFiles([]string{lib}, nil)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(diagnostics)) {
+		assert.Equal(t, lib+":2:1: export has no effect outside module (export)", diagnostics[0].String())
+	}
+
+	diagnostics, err = cli.VetFiles([]string{lib}, &cli.VetOptions{Module: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(diagnostics))
+
+	writeFile(t, lib, "a :=")
+	_, err = cli.VetFiles(files, nil)
+	assert.Error(t, err)
+}
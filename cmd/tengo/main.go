@@ -2,6 +2,7 @@ package main
 
 import (
 	"flag"
+	"strings"
 
 	"github.com/d5/tengo/cli"
 	"github.com/d5/tengo/stdlib"
@@ -9,6 +10,12 @@ import (
 
 var (
 	compileOutput string
+	evalSource    string
+	sandbox       cli.Sandbox
+	allowModules  string
+	denyModules   string
+	fileImport    bool
+	moduleBundles string
 	showHelp      bool
 	showVersion   bool
 	version       = "dev"
@@ -17,17 +24,61 @@ var (
 func init() {
 	flag.BoolVar(&showHelp, "help", false, "Show help")
 	flag.StringVar(&compileOutput, "o", "", "Compile output file")
+	flag.StringVar(&evalSource, "e", "", "Run the source code")
+	flag.DurationVar(&sandbox.Timeout, "timeout", 0, "Maximum execution time")
+	flag.Int64Var(&sandbox.MaxAllocs, "max-allocs", 0, "Maximum number of allocated objects")
+	flag.IntVar(&sandbox.MaxConstObjects, "max-const-objects", 0, "Maximum number of constant objects")
+	flag.StringVar(&allowModules, "allow-modules", "", "Comma-separated modules that can be imported")
+	flag.StringVar(&denyModules, "deny-modules", "", "Comma-separated modules that cannot be imported")
+	flag.BoolVar(&fileImport, "file-import", true, "Allow importing modules from files")
+	flag.StringVar(&moduleBundles, "module-bundle", "", "Comma-separated module bundle files to import from")
 	flag.BoolVar(&showVersion, "version", false, "Show version")
-	flag.Parse()
 }
 
 func main() {
+	modules := stdlib.GetModuleMap(stdlib.AllModuleNames()...)
+
+	// the executable built by "tengo build" runs the embedded program
+	// with all the arguments.
+	if cli.RunEmbedded(modules) {
+		return
+	}
+
+	flag.Parse()
+
+	sandbox.AllowedModules = list(allowModules)
+	sandbox.DeniedModules = list(denyModules)
+	sandbox.DisableFileImport = !fileImport
+
 	cli.Run(&cli.Options{
 		ShowHelp:      showHelp,
 		ShowVersion:   showVersion,
 		Version:       version,
 		CompileOutput: compileOutput,
-		Modules:       stdlib.GetModuleMap(stdlib.AllModuleNames()...),
+		Modules:       modules,
 		InputFile:     flag.Arg(0),
+		Args:          args(),
+		Eval:          evalSource,
+		Sandbox:       sandbox,
+		ModuleBundles: list(moduleBundles),
 	})
 }
+
+func args() []string {
+	if flag.NArg() < 2 {
+		return nil
+	}
+
+	return flag.Args()[1:]
+}
+
+func list(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/d5/tengo/stdlib/funcgen"
+)
+
+var (
+	outputFile     string
+	packageName    string
+	signaturesFile string
+	fromDir        string
+)
+
+func init() {
+	flag.StringVar(&outputFile, "o", "", "Output file (default: standard output)")
+	flag.StringVar(&packageName, "pkg", os.Getenv("GOPACKAGE"), "Package name of the output file")
+	flag.StringVar(&signaturesFile, "f", "", "File of the function signatures, one per line")
+	flag.StringVar(&fromDir, "from", "", "Directory of the package whose exported functions are adapted")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: tengofunc [flags] [signature ...]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Generates the CallableFunc adapters for the function signatures,")
+		fmt.Fprintln(os.Stderr, "e.g. 'func(string, int) (string, error)'.")
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	if packageName == "" {
+		fmt.Fprintln(os.Stderr, "package name is not specified: use -pkg")
+		os.Exit(2)
+	}
+
+	var sigs []*funcgen.Signature
+
+	if signaturesFile != "" {
+		fileSigs, err := readSignatures(signaturesFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		sigs = append(sigs, fileSigs...)
+	}
+
+	for _, arg := range flag.Args() {
+		sig, err := funcgen.ParseSignature(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		sigs = append(sigs, sig)
+	}
+
+	if fromDir != "" {
+		pkgSigs, skipped, err := funcgen.PackageSignatures(fromDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		for _, s := range skipped {
+			fmt.Fprintf(os.Stderr, "skipped %s\n", s)
+		}
+		sigs = append(sigs, pkgSigs...)
+	}
+
+	if len(sigs) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	out, err := funcgen.Generate(packageName, sigs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	if outputFile == "" {
+		_, _ = os.Stdout.Write(out)
+		return
+	}
+
+	if err := ioutil.WriteFile(outputFile, out, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+// readSignatures reads the signatures from the file, skipping the empty
+// lines and the comments starting with '#' or '//'.
+func readSignatures(file string) ([]*funcgen.Signature, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var sigs []*funcgen.Signature
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		sig, err := funcgen.ParseSignature(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", file, lineNum, err.Error())
+		}
+		sigs = append(sigs, sig)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return sigs, nil
+}
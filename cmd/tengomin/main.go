@@ -17,15 +17,31 @@ func init() {
 	flag.BoolVar(&showHelp, "help", false, "Show help")
 	flag.StringVar(&compileOutput, "o", "", "Compile output file")
 	flag.BoolVar(&showVersion, "version", false, "Show version")
-	flag.Parse()
 }
 
 func main() {
+	// the executable built by "tengo build" runs the embedded program
+	// with all the arguments.
+	if cli.RunEmbedded(nil) {
+		return
+	}
+
+	flag.Parse()
+
 	cli.Run(&cli.Options{
 		ShowHelp:      showHelp,
 		ShowVersion:   showVersion,
 		Version:       version,
 		CompileOutput: compileOutput,
 		InputFile:     flag.Arg(0),
+		Args:          args(),
 	})
 }
+
+func args() []string {
+	if flag.NArg() < 2 {
+		return nil
+	}
+
+	return flag.Args()[1:]
+}
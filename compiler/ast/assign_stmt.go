@@ -13,6 +13,8 @@ type AssignStmt struct {
 	RHS      []Expr
 	Token    token.Token
 	TokenPos source.Pos
+	Doc      *CommentGroup // leading comments; or nil
+	Comment  *CommentGroup // line comments; or nil
 }
 
 func (s *AssignStmt) stmtNode() {}
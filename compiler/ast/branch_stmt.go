@@ -10,6 +10,8 @@ type BranchStmt struct {
 	Token    token.Token
 	TokenPos source.Pos
 	Label    *Ident
+	Doc      *CommentGroup // leading comments; or nil
+	Comment  *CommentGroup // line comments; or nil
 }
 
 func (s *BranchStmt) stmtNode() {}
@@ -0,0 +1,23 @@
+package ast
+
+import "github.com/d5/tengo/compiler/source"
+
+// Comment represents a single //-style or /*-style comment.
+type Comment struct {
+	Slash source.Pos // position of "/" starting the comment
+	Text  string     // comment text (excluding '\n' for //-style comments)
+}
+
+// Pos returns the position of first character belonging to the node.
+func (c *Comment) Pos() source.Pos {
+	return c.Slash
+}
+
+// End returns the position of first character immediately after the node.
+func (c *Comment) End() source.Pos {
+	return c.Slash + source.Pos(len(c.Text))
+}
+
+func (c *Comment) String() string {
+	return c.Text
+}
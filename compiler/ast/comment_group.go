@@ -0,0 +1,72 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/d5/tengo/compiler/source"
+)
+
+// CommentGroup represents a sequence of comments with no other tokens and
+// no empty lines between.
+type CommentGroup struct {
+	List []*Comment
+}
+
+// Pos returns the position of first character belonging to the node.
+func (g *CommentGroup) Pos() source.Pos {
+	return g.List[0].Pos()
+}
+
+// End returns the position of first character immediately after the node.
+func (g *CommentGroup) End() source.Pos {
+	return g.List[len(g.List)-1].End()
+}
+
+func (g *CommentGroup) String() string {
+	var list []string
+	for _, c := range g.List {
+		list = append(list, c.String())
+	}
+
+	return strings.Join(list, "\n")
+}
+
+// Text returns the text of the comment group without the comment markers
+// and the leading and trailing blank lines. It returns an empty string if
+// the group is nil.
+func (g *CommentGroup) Text() string {
+	if g == nil {
+		return ""
+	}
+
+	var lines []string
+	for _, c := range g.List {
+		text := c.Text
+		switch text[1] {
+		case '/':
+			text = text[2:]
+			if len(text) > 0 && text[0] == ' ' {
+				text = text[1:]
+			}
+		case '*':
+			text = text[2 : len(text)-2]
+		}
+
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+	}
+
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
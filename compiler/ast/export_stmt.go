@@ -8,6 +8,8 @@ import (
 type ExportStmt struct {
 	ExportPos source.Pos
 	Result    Expr
+	Doc       *CommentGroup // leading comments; or nil
+	Comment   *CommentGroup // line comments; or nil
 }
 
 func (s *ExportStmt) stmtNode() {}
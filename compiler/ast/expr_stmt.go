@@ -4,7 +4,9 @@ import "github.com/d5/tengo/compiler/source"
 
 // ExprStmt represents an expression statement.
 type ExprStmt struct {
-	Expr Expr
+	Expr    Expr
+	Doc     *CommentGroup // leading comments; or nil
+	Comment *CommentGroup // line comments; or nil
 }
 
 func (s *ExprStmt) stmtNode() {}
@@ -10,6 +10,7 @@ import (
 type File struct {
 	InputFile *source.File
 	Stmts     []Stmt
+	Comments  []*CommentGroup // list of all comments in the source file
 }
 
 // Pos returns the position of first character belonging to the node.
@@ -9,6 +9,8 @@ type ForInStmt struct {
 	Value    *Ident
 	Iterable Expr
 	Body     *BlockStmt
+	Doc      *CommentGroup // leading comments; or nil
+	Comment  *CommentGroup // line comments; or nil
 }
 
 func (s *ForInStmt) stmtNode() {}
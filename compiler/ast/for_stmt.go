@@ -4,11 +4,13 @@ import "github.com/d5/tengo/compiler/source"
 
 // ForStmt represents a for statement.
 type ForStmt struct {
-	ForPos source.Pos
-	Init   Stmt
-	Cond   Expr
-	Post   Stmt
-	Body   *BlockStmt
+	ForPos  source.Pos
+	Init    Stmt
+	Cond    Expr
+	Post    Stmt
+	Body    *BlockStmt
+	Doc     *CommentGroup // leading comments; or nil
+	Comment *CommentGroup // line comments; or nil
 }
 
 func (s *ForStmt) stmtNode() {}
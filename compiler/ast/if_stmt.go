@@ -4,11 +4,13 @@ import "github.com/d5/tengo/compiler/source"
 
 // IfStmt represents an if statement.
 type IfStmt struct {
-	IfPos source.Pos
-	Init  Stmt
-	Cond  Expr
-	Body  *BlockStmt
-	Else  Stmt // else branch; or nil
+	IfPos   source.Pos
+	Init    Stmt
+	Cond    Expr
+	Body    *BlockStmt
+	Else    Stmt          // else branch; or nil
+	Doc     *CommentGroup // leading comments; or nil
+	Comment *CommentGroup // line comments; or nil
 }
 
 func (s *IfStmt) stmtNode() {}
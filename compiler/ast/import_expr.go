@@ -25,5 +25,5 @@ func (e *ImportExpr) End() source.Pos {
 }
 
 func (e *ImportExpr) String() string {
-	return `import("` + e.ModuleName + `")"`
+	return `import("` + e.ModuleName + `")`
 }
@@ -10,6 +10,8 @@ type IncDecStmt struct {
 	Expr     Expr
 	Token    token.Token
 	TokenPos source.Pos
+	Doc      *CommentGroup // leading comments; or nil
+	Comment  *CommentGroup // line comments; or nil
 }
 
 func (s *IncDecStmt) stmtNode() {}
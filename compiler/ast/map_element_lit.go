@@ -8,6 +8,8 @@ type MapElementLit struct {
 	KeyPos   source.Pos
 	ColonPos source.Pos
 	Value    Expr
+	Doc      *CommentGroup // leading comments; or nil
+	Comment  *CommentGroup // line comments; or nil
 }
 
 func (e *MapElementLit) exprNode() {}
@@ -0,0 +1,140 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/compiler/token"
+)
+
+var (
+	posType   = reflect.TypeOf(source.Pos(0))
+	tokenType = reflect.TypeOf(token.Illegal)
+	fileType  = reflect.TypeOf(&source.File{})
+)
+
+// Fprint prints the AST node and its children into w for debugging. The
+// positions are printed as file positions if fileSet is not nil, and as
+// raw offsets otherwise. Nil fields are omitted.
+func Fprint(w io.Writer, fileSet *source.FileSet, node Node) error {
+	p := &astPrinter{w: w, fileSet: fileSet}
+	p.print(reflect.ValueOf(node))
+	p.printf("\n")
+
+	return p.err
+}
+
+// Print prints the AST node and its children into the standard output.
+func Print(fileSet *source.FileSet, node Node) error {
+	return Fprint(os.Stdout, fileSet, node)
+}
+
+type astPrinter struct {
+	w       io.Writer
+	fileSet *source.FileSet
+	indent  int
+	err     error
+}
+
+func (p *astPrinter) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
+
+func (p *astPrinter) newline() {
+	p.printf("\n")
+	for i := 0; i < p.indent; i++ {
+		p.printf(".  ")
+	}
+}
+
+func (p *astPrinter) print(v reflect.Value) {
+	if !v.IsValid() {
+		p.printf("nil")
+		return
+	}
+
+	switch v.Type() {
+	case posType:
+		pos := source.Pos(v.Int())
+		if p.fileSet != nil && pos.IsValid() {
+			p.printf("%s", p.fileSet.Position(pos))
+		} else {
+			p.printf("%d", pos)
+		}
+		return
+	case tokenType:
+		p.printf("%s", token.Token(v.Int()))
+		return
+	case fileType:
+		if v.IsNil() {
+			p.printf("nil")
+		} else {
+			p.printf("*source.File(%s)", strconv.Quote(v.Interface().(*source.File).Name))
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			p.printf("nil")
+			return
+		}
+
+		if v.Kind() == reflect.Ptr {
+			p.printf("*")
+		}
+		p.print(v.Elem())
+	case reflect.Slice:
+		p.printf("%s (len = %d) {", v.Type(), v.Len())
+		p.indent++
+		for i := 0; i < v.Len(); i++ {
+			p.newline()
+			p.printf("%d: ", i)
+			p.print(v.Index(i))
+		}
+		p.indent--
+		if v.Len() > 0 {
+			p.newline()
+		}
+		p.printf("}")
+	case reflect.Struct:
+		t := v.Type()
+		p.printf("%s {", t)
+		p.indent++
+		for i := 0; i < t.NumField(); i++ {
+			f := v.Field(i)
+			if isNilValue(f) {
+				continue
+			}
+
+			p.newline()
+			p.printf("%s: ", t.Field(i).Name)
+			p.print(f)
+		}
+		p.indent--
+		p.newline()
+		p.printf("}")
+	case reflect.String:
+		p.printf("%s", strconv.Quote(v.String()))
+	default:
+		p.printf("%v", v.Interface())
+	}
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+
+	return false
+}
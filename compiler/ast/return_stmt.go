@@ -8,6 +8,8 @@ import (
 type ReturnStmt struct {
 	ReturnPos source.Pos
 	Result    Expr
+	Doc       *CommentGroup // leading comments; or nil
+	Comment   *CommentGroup // line comments; or nil
 }
 
 func (s *ReturnStmt) stmtNode() {}
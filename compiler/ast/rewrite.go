@@ -0,0 +1,278 @@
+package ast
+
+import "fmt"
+
+// Rewrite traverses the AST in depth-first order and replaces each node with
+// the result of f. The children of a node are rewritten before f is called
+// on the node itself, and the function returns the result of f on the root
+// node. If f returns nil, the node is removed from the list it belongs to,
+// or the field that holds the node is set to nil. Rewrite panics if f
+// returns a node that cannot be stored in the field of its parent, e.g. a
+// statement in place of an expression.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch n := node.(type) {
+	case *Comment, *BadExpr, *BadStmt, *BoolLit, *CharLit, *EmptyStmt,
+		*FloatLit, *Ident, *ImportExpr, *IntLit, *StringLit, *UndefinedLit:
+		// nothing to do
+	case *CommentGroup:
+		var list []*Comment
+		for _, c := range n.List {
+			if c = rewriteComment(c, f); c != nil {
+				list = append(list, c)
+			}
+		}
+		n.List = list
+	case *File:
+		n.Stmts = rewriteStmts(n.Stmts, f)
+	case *ArrayLit:
+		n.Elements = rewriteExprs(n.Elements, f)
+	case *AssignStmt:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.LHS = rewriteExprs(n.LHS, f)
+		n.RHS = rewriteExprs(n.RHS, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *BinaryExpr:
+		n.LHS = rewriteExpr(n.LHS, f)
+		n.RHS = rewriteExpr(n.RHS, f)
+	case *BlockStmt:
+		n.Stmts = rewriteStmts(n.Stmts, f)
+	case *BranchStmt:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.Label = rewriteIdent(n.Label, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *CallExpr:
+		n.Func = rewriteExpr(n.Func, f)
+		n.Args = rewriteExprs(n.Args, f)
+	case *CondExpr:
+		n.Cond = rewriteExpr(n.Cond, f)
+		n.True = rewriteExpr(n.True, f)
+		n.False = rewriteExpr(n.False, f)
+	case *ErrorExpr:
+		n.Expr = rewriteExpr(n.Expr, f)
+	case *ExportStmt:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.Result = rewriteExpr(n.Result, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *ExprStmt:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.Expr = rewriteExpr(n.Expr, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *ForInStmt:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.Key = rewriteIdent(n.Key, f)
+		n.Value = rewriteIdent(n.Value, f)
+		n.Iterable = rewriteExpr(n.Iterable, f)
+		n.Body = rewriteBlockStmt(n.Body, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *ForStmt:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.Init = rewriteStmt(n.Init, f)
+		n.Cond = rewriteExpr(n.Cond, f)
+		n.Post = rewriteStmt(n.Post, f)
+		n.Body = rewriteBlockStmt(n.Body, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *FuncLit:
+		if n.Type != nil {
+			n.Type, _ = rewriteNode(n.Type, f, func(n Node) bool {
+				_, ok := n.(*FuncType)
+				return ok
+			}).(*FuncType)
+		}
+		n.Body = rewriteBlockStmt(n.Body, f)
+	case *FuncType:
+		if n.Params != nil {
+			n.Params, _ = rewriteNode(n.Params, f, func(n Node) bool {
+				_, ok := n.(*IdentList)
+				return ok
+			}).(*IdentList)
+		}
+	case *IdentList:
+		var list []*Ident
+		for _, ident := range n.List {
+			if ident = rewriteIdent(ident, f); ident != nil {
+				list = append(list, ident)
+			}
+		}
+		n.List = list
+	case *IfStmt:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.Init = rewriteStmt(n.Init, f)
+		n.Cond = rewriteExpr(n.Cond, f)
+		n.Body = rewriteBlockStmt(n.Body, f)
+		n.Else = rewriteStmt(n.Else, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *ImmutableExpr:
+		n.Expr = rewriteExpr(n.Expr, f)
+	case *IncDecStmt:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.Expr = rewriteExpr(n.Expr, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *IndexExpr:
+		n.Expr = rewriteExpr(n.Expr, f)
+		n.Index = rewriteExpr(n.Index, f)
+	case *MapElementLit:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.Value = rewriteExpr(n.Value, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *MapLit:
+		var list []*MapElementLit
+		for _, e := range n.Elements {
+			if e == nil {
+				continue
+			}
+
+			res := rewriteNode(e, f, func(n Node) bool {
+				_, ok := n.(*MapElementLit)
+				return ok
+			})
+			if res != nil {
+				list = append(list, res.(*MapElementLit))
+			}
+		}
+		n.Elements = list
+	case *ParenExpr:
+		n.Expr = rewriteExpr(n.Expr, f)
+	case *ReturnStmt:
+		n.Doc = rewriteCommentGroup(n.Doc, f)
+		n.Result = rewriteExpr(n.Result, f)
+		n.Comment = rewriteCommentGroup(n.Comment, f)
+	case *SelectorExpr:
+		n.Expr = rewriteExpr(n.Expr, f)
+		n.Sel = rewriteExpr(n.Sel, f)
+	case *SliceExpr:
+		n.Expr = rewriteExpr(n.Expr, f)
+		n.Low = rewriteExpr(n.Low, f)
+		n.High = rewriteExpr(n.High, f)
+	case *UnaryExpr:
+		n.Expr = rewriteExpr(n.Expr, f)
+	default:
+		panic(fmt.Errorf("ast.Rewrite: unexpected node type %T", n))
+	}
+
+	return f(node)
+}
+
+// rewriteNode rewrites the node and checks if the result is acceptable.
+func rewriteNode(node Node, f func(Node) Node, accept func(Node) bool) Node {
+	res := Rewrite(node, f)
+	if res != nil && !accept(res) {
+		panic(fmt.Errorf("ast.Rewrite: cannot replace %T with %T", node, res))
+	}
+
+	return res
+}
+
+func rewriteExpr(x Expr, f func(Node) Node) Expr {
+	if x == nil {
+		return nil
+	}
+
+	res := rewriteNode(x, f, func(n Node) bool {
+		_, ok := n.(Expr)
+		return ok
+	})
+	if res == nil {
+		return nil
+	}
+
+	return res.(Expr)
+}
+
+func rewriteStmt(s Stmt, f func(Node) Node) Stmt {
+	if s == nil {
+		return nil
+	}
+
+	res := rewriteNode(s, f, func(n Node) bool {
+		_, ok := n.(Stmt)
+		return ok
+	})
+	if res == nil {
+		return nil
+	}
+
+	return res.(Stmt)
+}
+
+func rewriteExprs(list []Expr, f func(Node) Node) []Expr {
+	var res []Expr
+	for _, x := range list {
+		if x = rewriteExpr(x, f); x != nil {
+			res = append(res, x)
+		}
+	}
+
+	return res
+}
+
+func rewriteStmts(list []Stmt, f func(Node) Node) []Stmt {
+	var res []Stmt
+	for _, s := range list {
+		if s = rewriteStmt(s, f); s != nil {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
+
+func rewriteIdent(ident *Ident, f func(Node) Node) *Ident {
+	if ident == nil {
+		return nil
+	}
+
+	res := rewriteNode(ident, f, func(n Node) bool {
+		_, ok := n.(*Ident)
+		return ok
+	})
+	if res == nil {
+		return nil
+	}
+
+	return res.(*Ident)
+}
+
+func rewriteBlockStmt(block *BlockStmt, f func(Node) Node) *BlockStmt {
+	if block == nil {
+		return nil
+	}
+
+	res := rewriteNode(block, f, func(n Node) bool {
+		_, ok := n.(*BlockStmt)
+		return ok
+	})
+	if res == nil {
+		return nil
+	}
+
+	return res.(*BlockStmt)
+}
+
+func rewriteCommentGroup(g *CommentGroup, f func(Node) Node) *CommentGroup {
+	if g == nil {
+		return nil
+	}
+
+	res := rewriteNode(g, f, func(n Node) bool {
+		_, ok := n.(*CommentGroup)
+		return ok
+	})
+	if res == nil || len(res.(*CommentGroup).List) == 0 {
+		// a comment group must not be empty
+		return nil
+	}
+
+	return res.(*CommentGroup)
+}
+
+func rewriteComment(c *Comment, f func(Node) Node) *Comment {
+	res := rewriteNode(c, f, func(n Node) bool {
+		_, ok := n.(*Comment)
+		return ok
+	})
+	if res == nil {
+		return nil
+	}
+
+	return res.(*Comment)
+}
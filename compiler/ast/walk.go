@@ -0,0 +1,186 @@
+package ast
+
+import "fmt"
+
+// Visitor represents a visitor for the AST. The Visit method is invoked for
+// each node encountered by Walk. If the result visitor w is not nil, Walk
+// visits each of the children of node with the visitor w, followed by a call
+// of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the AST in depth-first order. It starts by calling
+// v.Visit(node); node must not be nil.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Comment, *BadExpr, *BadStmt, *BoolLit, *CharLit, *EmptyStmt,
+		*FloatLit, *Ident, *ImportExpr, *IntLit, *StringLit, *UndefinedLit:
+		// nothing to do
+	case *CommentGroup:
+		for _, c := range n.List {
+			Walk(v, c)
+		}
+	case *File:
+		walkStmts(v, n.Stmts)
+	case *ArrayLit:
+		walkExprs(v, n.Elements)
+	case *AssignStmt:
+		walkCommentGroup(v, n.Doc)
+		walkExprs(v, n.LHS)
+		walkExprs(v, n.RHS)
+		walkCommentGroup(v, n.Comment)
+	case *BinaryExpr:
+		Walk(v, n.LHS)
+		Walk(v, n.RHS)
+	case *BlockStmt:
+		walkStmts(v, n.Stmts)
+	case *BranchStmt:
+		walkCommentGroup(v, n.Doc)
+		if n.Label != nil {
+			Walk(v, n.Label)
+		}
+		walkCommentGroup(v, n.Comment)
+	case *CallExpr:
+		Walk(v, n.Func)
+		walkExprs(v, n.Args)
+	case *CondExpr:
+		Walk(v, n.Cond)
+		Walk(v, n.True)
+		Walk(v, n.False)
+	case *ErrorExpr:
+		Walk(v, n.Expr)
+	case *ExportStmt:
+		walkCommentGroup(v, n.Doc)
+		Walk(v, n.Result)
+		walkCommentGroup(v, n.Comment)
+	case *ExprStmt:
+		walkCommentGroup(v, n.Doc)
+		Walk(v, n.Expr)
+		walkCommentGroup(v, n.Comment)
+	case *ForInStmt:
+		walkCommentGroup(v, n.Doc)
+		Walk(v, n.Key)
+		Walk(v, n.Value)
+		Walk(v, n.Iterable)
+		Walk(v, n.Body)
+		walkCommentGroup(v, n.Comment)
+	case *ForStmt:
+		walkCommentGroup(v, n.Doc)
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+		if n.Cond != nil {
+			Walk(v, n.Cond)
+		}
+		if n.Post != nil {
+			Walk(v, n.Post)
+		}
+		Walk(v, n.Body)
+		walkCommentGroup(v, n.Comment)
+	case *FuncLit:
+		Walk(v, n.Type)
+		Walk(v, n.Body)
+	case *FuncType:
+		Walk(v, n.Params)
+	case *IdentList:
+		for _, ident := range n.List {
+			Walk(v, ident)
+		}
+	case *IfStmt:
+		walkCommentGroup(v, n.Doc)
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+		Walk(v, n.Cond)
+		Walk(v, n.Body)
+		if n.Else != nil {
+			Walk(v, n.Else)
+		}
+		walkCommentGroup(v, n.Comment)
+	case *ImmutableExpr:
+		Walk(v, n.Expr)
+	case *IncDecStmt:
+		walkCommentGroup(v, n.Doc)
+		Walk(v, n.Expr)
+		walkCommentGroup(v, n.Comment)
+	case *IndexExpr:
+		Walk(v, n.Expr)
+		if n.Index != nil {
+			Walk(v, n.Index)
+		}
+	case *MapElementLit:
+		walkCommentGroup(v, n.Doc)
+		Walk(v, n.Value)
+		walkCommentGroup(v, n.Comment)
+	case *MapLit:
+		for _, e := range n.Elements {
+			Walk(v, e)
+		}
+	case *ParenExpr:
+		Walk(v, n.Expr)
+	case *ReturnStmt:
+		walkCommentGroup(v, n.Doc)
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+		walkCommentGroup(v, n.Comment)
+	case *SelectorExpr:
+		Walk(v, n.Expr)
+		Walk(v, n.Sel)
+	case *SliceExpr:
+		Walk(v, n.Expr)
+		if n.Low != nil {
+			Walk(v, n.Low)
+		}
+		if n.High != nil {
+			Walk(v, n.High)
+		}
+	case *UnaryExpr:
+		Walk(v, n.Expr)
+	default:
+		panic(fmt.Errorf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkCommentGroup(v Visitor, g *CommentGroup) {
+	if g != nil {
+		Walk(v, g)
+	}
+}
+
+func walkExprs(v Visitor, list []Expr) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+func walkStmts(v Visitor, list []Stmt) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses the AST in depth-first order. It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the children of node, followed by a call of
+// f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,230 @@
+package ast_test
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+)
+
+// allNodesSrc contains every node type that can be produced by the parser.
+const allNodesSrc = `
+// doc
+fmt := import("fmt") // line
+a := [1, 2.5, 'c', "s", true, undefined]
+m := {k: -a[0], l: a[1:2]}
+f := func(x, ...y) {
+	if x > 0 { return x ? y : (x) } else { x++ }
+	for i := 0; i < 10; i++ { continue }
+	for k, v in m { break }
+	;
+	return error(immutable(fmt.sprintf("%d", x)))
+}
+export f
+`
+
+var allNodeTypes = []string{
+	"*ast.ArrayLit", "*ast.AssignStmt", "*ast.BadExpr", "*ast.BadStmt",
+	"*ast.BinaryExpr", "*ast.BlockStmt", "*ast.BoolLit", "*ast.BranchStmt",
+	"*ast.CallExpr", "*ast.CharLit", "*ast.Comment", "*ast.CommentGroup",
+	"*ast.CondExpr", "*ast.EmptyStmt", "*ast.ErrorExpr", "*ast.ExportStmt",
+	"*ast.ExprStmt", "*ast.File", "*ast.FloatLit", "*ast.ForInStmt",
+	"*ast.ForStmt", "*ast.FuncLit", "*ast.FuncType", "*ast.Ident",
+	"*ast.IdentList", "*ast.IfStmt", "*ast.ImmutableExpr", "*ast.ImportExpr",
+	"*ast.IncDecStmt", "*ast.IndexExpr", "*ast.IntLit", "*ast.MapElementLit",
+	"*ast.MapLit", "*ast.ParenExpr", "*ast.ReturnStmt", "*ast.SelectorExpr",
+	"*ast.SliceExpr", "*ast.StringLit", "*ast.UnaryExpr", "*ast.UndefinedLit",
+}
+
+func TestInspect(t *testing.T) {
+	file := parseAllNodes(t)
+
+	var depth, maxDepth int
+	found := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil {
+			depth--
+			return false
+		}
+
+		depth++
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+
+		found[fmt.Sprintf("%T", n)] = true
+
+		return true
+	})
+	assert.Equal(t, 0, depth)
+	assert.True(t, maxDepth > 5)
+
+	// the parser does not produce bad nodes from valid source
+	ast.Inspect(&ast.BlockStmt{Stmts: []ast.Stmt{
+		&ast.BadStmt{},
+		&ast.ExprStmt{Expr: &ast.BadExpr{}},
+	}}, func(n ast.Node) bool {
+		if n != nil {
+			found[fmt.Sprintf("%T", n)] = true
+		}
+		return true
+	})
+
+	var types []string
+	for typ := range found {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	assert.Equal(t, allNodeTypes, types)
+
+	// skip children
+	var idents []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			idents = append(idents, ident.Name)
+		}
+		_, isFunc := n.(*ast.FuncLit)
+		return !isFunc
+	})
+	assert.Equal(t, []string{"fmt", "a", "m", "a", "a", "f", "f"}, idents)
+}
+
+type countVisitor map[string]int
+
+func (v countVisitor) Visit(n ast.Node) ast.Visitor {
+	if n != nil {
+		v[fmt.Sprintf("%T", n)]++
+	}
+
+	return v
+}
+
+func TestWalk(t *testing.T) {
+	file := parseAllNodes(t)
+
+	v := countVisitor{}
+	ast.Walk(v, file)
+	assert.Equal(t, 1, v["*ast.File"])
+	assert.Equal(t, 7, v["*ast.IntLit"])
+	assert.Equal(t, 2, v["*ast.CommentGroup"])
+	assert.Equal(t, 1, v["*ast.ForStmt"])
+	assert.Equal(t, 1, v["*ast.ForInStmt"])
+	assert.Equal(t, 2, v["*ast.MapElementLit"])
+}
+
+func TestRewrite(t *testing.T) {
+	file := parseAllNodes(t)
+
+	res := ast.Rewrite(file, func(n ast.Node) ast.Node {
+		switch n := n.(type) {
+		case *ast.IntLit:
+			v := n.Value * 2
+			return &ast.IntLit{Value: v, ValuePos: n.ValuePos, Literal: strconv.FormatInt(v, 10)}
+		case *ast.Ident:
+			if n.Name == "x" {
+				return &ast.Ident{Name: "z", NamePos: n.NamePos}
+			}
+		case *ast.EmptyStmt, *ast.CommentGroup:
+			return nil
+		case *ast.ForStmt:
+			return &ast.ExprStmt{Expr: n.Cond}
+		}
+		return n
+	})
+	assert.True(t, res == file)
+
+	var stmts []string
+	for _, s := range file.Stmts {
+		stmts = append(stmts, s.String())
+	}
+	assert.Equal(t, `fmt := import("fmt")`, stmts[0])
+	assert.Equal(t, `a := [2, 2.5, 'c', "s", true, undefined]`, stmts[1])
+	assert.Equal(t, `m := {k: (-a[0]), l: a[2:4]}`, stmts[2])
+	assert.Equal(t, `f := func(z, ...y) {if (z > 0) {return (z ? y : (z))} else {z++}; (i < 20); for k, v in m {break}; return error(immutable(fmt.sprintf("%d", z)))}`, stmts[3])
+	assert.Nil(t, file.Stmts[0].(*ast.AssignStmt).Doc)
+	assert.Nil(t, file.Stmts[0].(*ast.AssignStmt).Comment)
+
+	// replace the root node
+	res = ast.Rewrite(&ast.IntLit{Value: 1}, func(n ast.Node) ast.Node {
+		return &ast.StringLit{Value: "a", Literal: `"a"`}
+	})
+	assert.Equal(t, `"a"`, res.String())
+
+	// invalid replacement
+	defer func() {
+		r := recover()
+		assert.NotNil(t, r)
+		assert.Equal(t, "ast.Rewrite: cannot replace *ast.IntLit with *ast.ExprStmt", fmt.Sprint(r))
+	}()
+	ast.Rewrite(&ast.ExprStmt{Expr: &ast.IntLit{Value: 1}}, func(n ast.Node) ast.Node {
+		if n, ok := n.(*ast.IntLit); ok {
+			return &ast.ExprStmt{Expr: n}
+		}
+		return n
+	})
+}
+
+func TestPrint(t *testing.T) {
+	fileSet := source.NewFileSet()
+	src := []byte("a := b + 1")
+	file, err := parser.NewParser(fileSet.AddFile("test", -1, len(src)), src, nil).ParseFile()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var sb strings.Builder
+	assert.NoError(t, ast.Fprint(&sb, fileSet, file.Stmts[0]))
+	assert.Equal(t, `*ast.AssignStmt {
+.  LHS: []ast.Expr (len = 1) {
+.  .  0: *ast.Ident {
+.  .  .  Name: "a"
+.  .  .  NamePos: test:1:1
+.  .  }
+.  }
+.  RHS: []ast.Expr (len = 1) {
+.  .  0: *ast.BinaryExpr {
+.  .  .  LHS: *ast.Ident {
+.  .  .  .  Name: "b"
+.  .  .  .  NamePos: test:1:6
+.  .  .  }
+.  .  .  RHS: *ast.IntLit {
+.  .  .  .  Value: 1
+.  .  .  .  ValuePos: test:1:10
+.  .  .  .  Literal: "1"
+.  .  .  }
+.  .  .  Token: +
+.  .  .  TokenPos: test:1:8
+.  .  }
+.  }
+.  Token: :=
+.  TokenPos: test:1:3
+}
+`, sb.String())
+
+	sb.Reset()
+	assert.NoError(t, ast.Fprint(&sb, nil, &ast.ReturnStmt{ReturnPos: 3}))
+	assert.Equal(t, "*ast.ReturnStmt {\n.  ReturnPos: 3\n}\n", sb.String())
+
+	sb.Reset()
+	assert.NoError(t, ast.Fprint(&sb, nil, &ast.File{InputFile: fileSet.File(1)}))
+	assert.Equal(t, "*ast.File {\n.  InputFile: *source.File(\"test\")\n}\n", sb.String())
+}
+
+func parseAllNodes(t *testing.T) *ast.File {
+	fileSet := source.NewFileSet()
+	src := []byte(allNodesSrc)
+
+	p := parser.NewParserWithMode(fileSet.AddFile("test", -1, len(src)), src, nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return file
+}
@@ -0,0 +1,289 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/objects"
+)
+
+// bundleMagic is the header of the encoded bundles.
+const bundleMagic = "\x00TENGOBUNDLE"
+
+// CompiledModule is a module compiled from the Tengo source code. It can be
+// added to a module map, and is imported without compiling the source code.
+type CompiledModule struct {
+	FileSet   *source.FileSet
+	Function  *objects.CompiledFunction
+	Constants []objects.Object
+}
+
+// Import returns the compiled module.
+func (m *CompiledModule) Import(_ string) (interface{}, error) {
+	return m, nil
+}
+
+// fingerprint returns a hash of the instructions and the constants of the
+// module, which identifies the module, e.g. in the module cache.
+func (m *CompiledModule) fingerprint() string {
+	return (&Bytecode{MainFunction: m.Function, Constants: m.Constants}).Fingerprint()
+}
+
+// ManifestFile represents a source file compiled into a bundle.
+type ManifestFile struct {
+	// Path is the path of the file as it was imported, relative to the
+	// working directory.
+	Path string `json:"path"`
+
+	// Hash is the SHA-256 hash of the file content in hexadecimal.
+	Hash string `json:"hash"`
+}
+
+// Manifest describes the contents of a bundle.
+type Manifest struct {
+	// Entry is the path of the entry file of a program bundle.
+	Entry string `json:"entry,omitempty"`
+
+	// Modules are the names of the modules of a module bundle.
+	Modules []string `json:"modules,omitempty"`
+
+	// Files are all the source files compiled into the bundle, including
+	// the modules imported from the files.
+	Files []*ManifestFile `json:"files"`
+}
+
+// Bundle is a single compiled artifact of multiple source files. A program
+// bundle has the bytecode of the entry file and all the modules it imports
+// from the files. A module bundle has the precompiled modules that other
+// programs can import through the module map.
+type Bundle struct {
+	Manifest *Manifest
+	Program  *Bytecode
+	Modules  map[string]*CompiledModule
+}
+
+// ModuleSource is the source file of a module in a module bundle.
+type ModuleSource struct {
+	// Name is the name the module is imported with.
+	Name     string
+	Filename string
+	Src      []byte
+}
+
+// NewProgramBundle compiles the entry file and all the modules it imports
+// from the files into a program bundle.
+func NewProgramBundle(modules *objects.ModuleMap, filename string, src []byte) (*Bundle, error) {
+	fileSet := source.NewFileSet()
+	srcFile := fileSet.AddFile(filepath.Base(filename), -1, len(src))
+
+	p := parser.NewParser(srcFile, src, nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewCompiler(srcFile, nil, nil, modules, nil)
+	c.EnableFileImport(true)
+	if err := c.Compile(file); err != nil {
+		return nil, err
+	}
+
+	bytecode := c.Bytecode()
+	bytecode.RemoveDuplicates()
+
+	entry := newManifestFile(filename, src)
+
+	return &Bundle{
+		Manifest: &Manifest{
+			Entry: entry.Path,
+			Files: append([]*ManifestFile{entry}, manifestFiles(c.dependencies)...),
+		},
+		Program: bytecode,
+	}, nil
+}
+
+// NewModuleBundle compiles the module sources, including the modules they
+// import from the files, into a module bundle.
+func NewModuleBundle(modules *objects.ModuleMap, srcs []*ModuleSource) (*Bundle, error) {
+	b := &Bundle{
+		Manifest: &Manifest{},
+		Modules:  make(map[string]*CompiledModule),
+	}
+
+	paths := make(map[string]bool)
+	addFile := func(file *ManifestFile) {
+		if !paths[file.Path] {
+			paths[file.Path] = true
+			b.Manifest.Files = append(b.Manifest.Files, file)
+		}
+	}
+
+	for _, src := range srcs {
+		if _, exists := b.Modules[src.Name]; exists {
+			return nil, fmt.Errorf("duplicate module: %s", src.Name)
+		}
+
+		modulePath, err := filepath.Abs(src.Filename)
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := compileStandaloneModule(nil, modules, src.Filename, modulePath, src.Src)
+		if err != nil {
+			return nil, err
+		}
+
+		b.Modules[src.Name] = entry.Module
+		b.Manifest.Modules = append(b.Manifest.Modules, src.Name)
+
+		addFile(newManifestFile(src.Filename, src.Src))
+		for _, f := range manifestFiles(entry.Dependencies) {
+			addFile(f)
+		}
+	}
+
+	return b, nil
+}
+
+// ModuleMap returns a module map that has the modules of the bundle.
+func (b *Bundle) ModuleMap() *objects.ModuleMap {
+	modules := objects.NewModuleMap()
+	for name, m := range b.Modules {
+		modules.Add(name, m)
+	}
+
+	return modules
+}
+
+// bundlePayload is the compiled code of a bundle, encoded after the
+// manifest so that the manifest can be read without decoding it.
+type bundlePayload struct {
+	Program *Bytecode
+	Modules map[string]*CompiledModule
+}
+
+// Encode writes the bundle into the writer.
+func (b *Bundle) Encode(w io.Writer) error {
+	if _, err := io.WriteString(w, bundleMagic); err != nil {
+		return err
+	}
+
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(b.Manifest); err != nil {
+		return err
+	}
+
+	return enc.Encode(&bundlePayload{Program: b.Program, Modules: b.Modules})
+}
+
+// Decode reads the bundle from the reader. The builtin modules compiled into
+// the bundle are replaced with the ones in the module map.
+func (b *Bundle) Decode(r io.Reader, modules *objects.ModuleMap) error {
+	if modules == nil {
+		modules = objects.NewModuleMap()
+	}
+
+	dec, err := newBundleDecoder(r)
+	if err != nil {
+		return err
+	}
+
+	if err := dec.Decode(&b.Manifest); err != nil {
+		return err
+	}
+
+	payload := &bundlePayload{}
+	if err := dec.Decode(payload); err != nil {
+		return err
+	}
+	b.Program = payload.Program
+	b.Modules = payload.Modules
+
+	fix := func(constants []objects.Object) error {
+		for i, v := range constants {
+			fv, err := fixDecoded(v, modules)
+			if err != nil {
+				return err
+			}
+			constants[i] = fv
+		}
+
+		return nil
+	}
+
+	if b.Program != nil {
+		if err := fix(b.Program.Constants); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range b.Modules {
+		if err := fix(m.Constants); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadManifest reads only the manifest of the bundle from the reader.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	dec, err := newBundleDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func newBundleDecoder(r io.Reader) (*gob.Decoder, error) {
+	magic := make([]byte, len(bundleMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != bundleMagic {
+		return nil, errors.New("invalid bundle")
+	}
+
+	return gob.NewDecoder(r), nil
+}
+
+// IsBundle returns true if the data is an encoded bundle.
+func IsBundle(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(bundleMagic))
+}
+
+func newManifestFile(path string, src []byte) *ManifestFile {
+	return &ManifestFile{
+		Path: filepath.ToSlash(filepath.Clean(path)),
+		Hash: hashSource(src),
+	}
+}
+
+// manifestFiles returns the manifest files of the file modules in the
+// dependencies.
+func manifestFiles(deps []*moduleDependency) []*ManifestFile {
+	var files []*ManifestFile
+	paths := make(map[string]bool)
+	for _, dep := range deps {
+		if dep.Kind != fileDependency || paths[dep.Path] {
+			continue
+		}
+		paths[dep.Path] = true
+
+		files = append(files, &ManifestFile{
+			Path: filepath.ToSlash(filepath.Clean(dep.File)),
+			Hash: dep.Hash,
+		})
+	}
+
+	return files
+}
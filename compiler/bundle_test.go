@@ -0,0 +1,153 @@
+package compiler_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestBundle(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "tengo_bundle")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(tempDir))
+	defer func() { _ = os.Chdir(wd) }()
+
+	assert.NoError(t, os.Mkdir("lib", 0755))
+	assert.NoError(t, ioutil.WriteFile("lib/add.tengo", []byte(`export func(a, b) { return a + b }`), 0644))
+	assert.NoError(t, ioutil.WriteFile("lib/sum.tengo", []byte(`
+add := import("./lib/add")
+text := import("text")
+export func(...args) {
+	s := 0
+	for a in args { s = add(s, a) }
+	return text.repeat("*", s)
+}`), 0644))
+
+	modules := stdlib.GetModuleMap("text")
+
+	// program bundle
+	main := []byte(`out := import("./lib/sum")(1, 2)`)
+	b, err := compiler.NewProgramBundle(modules, "main.tengo", main)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "main.tengo", b.Manifest.Entry)
+	assert.Equal(t, []string{"main.tengo", "lib/sum.tengo", "lib/add.tengo"}, manifestPaths(b.Manifest))
+	hash := sha256.Sum256(main)
+	assert.Equal(t, hex.EncodeToString(hash[:]), b.Manifest.Files[0].Hash)
+
+	b = encodeDecodeBundle(t, b, modules)
+	assert.Equal(t, "main.tengo", b.Manifest.Entry)
+	assert.Equal(t, `"***"`, runBundleProgram(t, b.Program))
+
+	// module bundle
+	b, err = compiler.NewModuleBundle(modules, []*compiler.ModuleSource{
+		{Name: "sum", Filename: "lib/sum.tengo", Src: readFile(t, "lib/sum.tengo")},
+		{Name: "add", Filename: "lib/add.tengo", Src: readFile(t, "lib/add.tengo")},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []string{"sum", "add"}, b.Manifest.Modules)
+	assert.Equal(t, []string{"lib/sum.tengo", "lib/add.tengo"}, manifestPaths(b.Manifest))
+
+	b = encodeDecodeBundle(t, b, modules)
+
+	// the modules are imported without the source files
+	assert.NoError(t, os.RemoveAll("lib"))
+
+	bundleModules := modules.Copy()
+	bundleModules.AddMap(b.ModuleMap())
+	bytecode := compileBundleProgram(t, `
+out := [import("sum")(1, 1), import("add")(2, 3)]
+out = append(out, import("sum")(import("add")(1, 1), 2))`, bundleModules)
+	assert.Equal(t, `["**", 5, "****"]`, runBundleProgram(t, bytecode))
+
+	// the source positions are relocated
+	bytecode = compileBundleProgram(t, `out := import("add")(1, "x")`, bundleModules)
+	err = runtime.NewVM(bytecode, nil, -1).Run()
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "at lib/add.tengo:1:"), err.Error())
+
+	_, err = compiler.NewModuleBundle(modules, []*compiler.ModuleSource{
+		{Name: "a", Filename: "a.tengo", Src: []byte(`export 1`)},
+		{Name: "a", Filename: "b.tengo", Src: []byte(`export 2`)},
+	})
+	assert.Equal(t, "duplicate module: a", err.Error())
+
+	assert.False(t, compiler.IsBundle([]byte("abc")))
+	assert.Error(t, (&compiler.Bundle{}).Decode(bytes.NewReader([]byte("abc")), nil))
+}
+
+func encodeDecodeBundle(t *testing.T, b *compiler.Bundle, modules *objects.ModuleMap) *compiler.Bundle {
+	var buf bytes.Buffer
+	assert.NoError(t, b.Encode(&buf))
+	assert.True(t, compiler.IsBundle(buf.Bytes()))
+
+	manifest, err := compiler.ReadManifest(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, b.Manifest.Entry, manifest.Entry)
+	assert.Equal(t, manifestPaths(b.Manifest), manifestPaths(manifest))
+
+	decoded := &compiler.Bundle{}
+	assert.NoError(t, decoded.Decode(&buf, modules))
+
+	return decoded
+}
+
+func compileBundleProgram(t *testing.T, input string, modules *objects.ModuleMap) *compiler.Bytecode {
+	fileSet := source.NewFileSet()
+	file := fileSet.AddFile("test", -1, len(input))
+
+	parsed, err := parser.NewParser(file, []byte(input), nil).ParseFile()
+	assert.NoError(t, err)
+
+	c := compiler.NewCompiler(file, nil, nil, modules, nil)
+	assert.NoError(t, c.Compile(parsed))
+
+	return c.Bytecode()
+}
+
+// runBundleProgram runs the bytecode and returns the value of the first
+// global variable.
+func runBundleProgram(t *testing.T, bytecode *compiler.Bytecode) string {
+	globals := make([]objects.Object, runtime.GlobalsSize)
+	assert.NoError(t, runtime.NewVM(bytecode, globals, -1).Run())
+
+	return globals[0].String()
+}
+
+func manifestPaths(m *compiler.Manifest) []string {
+	var paths []string
+	for _, f := range m.Files {
+		paths = append(paths, f.Path)
+	}
+
+	return paths
+}
+
+func readFile(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(filepath.FromSlash(path))
+	assert.NoError(t, err)
+
+	return data
+}
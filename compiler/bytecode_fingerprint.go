@@ -0,0 +1,68 @@
+package compiler
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"sort"
+
+	"github.com/d5/tengo/objects"
+)
+
+// Fingerprint returns a hash of the instructions and the constants of the
+// bytecode, which identifies the bytecode, e.g. to check that a VM snapshot
+// is restored with the same bytecode. The source positions are not included.
+func (b *Bytecode) Fingerprint() string {
+	h := sha256.New()
+
+	writeFingerprint(h, b.MainFunction)
+	for _, c := range b.Constants {
+		writeFingerprint(h, c)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeFingerprint(h hash.Hash, o objects.Object) {
+	writeString := func(s string) {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(s)))
+		_, _ = h.Write(n[:])
+		_, _ = h.Write([]byte(s))
+	}
+
+	writeString(o.TypeName())
+
+	switch o := o.(type) {
+	case *objects.CompiledFunction:
+		var n [8]byte
+		binary.BigEndian.PutUint32(n[:4], uint32(o.NumLocals))
+		binary.BigEndian.PutUint32(n[4:], uint32(o.NumParameters))
+		_, _ = h.Write(n[:])
+		if o.VarArgs {
+			_, _ = h.Write([]byte{1})
+		} else {
+			_, _ = h.Write([]byte{0})
+		}
+		writeString(string(o.Instructions))
+	case *objects.ImmutableMap:
+		if name := moduleName(o); name != "" {
+			writeString(name)
+			return
+		}
+
+		keys := make([]string, 0, len(o.Value))
+		for k := range o.Value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			writeString(k)
+			writeFingerprint(h, o.Value[k])
+		}
+	default:
+		writeString(o.String())
+	}
+}
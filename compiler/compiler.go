@@ -1,6 +1,7 @@
 package compiler
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -17,20 +18,29 @@ import (
 
 // Compiler compiles the AST into a bytecode.
 type Compiler struct {
-	file            *source.File
-	parent          *Compiler
-	modulePath      string
-	constants       []objects.Object
-	symbolTable     *SymbolTable
-	scopes          []CompilationScope
-	scopeIndex      int
-	modules         *objects.ModuleMap
-	compiledModules map[string]*objects.CompiledFunction
-	allowFileImport bool
-	loops           []*Loop
-	loopIndex       int
-	trace           io.Writer
-	indent          int
+	file             *source.File
+	parent           *Compiler
+	importer         *Compiler
+	modulePath       string
+	constants        []objects.Object
+	symbolTable      *SymbolTable
+	scopes           []CompilationScope
+	scopeIndex       int
+	modules          *objects.ModuleMap
+	compiledModules  map[string]*objects.CompiledFunction
+	moduleCache      *ModuleCache
+	dependencies     []*moduleDependency
+	allowFileImport  bool
+	loops            []*Loop
+	loopIndex        int
+	trace            io.Writer
+	indent           int
+	errors           ErrorList
+	maxErrors        int
+	warnings         []*Warning
+	warningsAsErrors bool
+	localDefs        []localDefinition
+	symbolReads      map[*Symbol]bool
 }
 
 // NewCompiler creates a Compiler.
@@ -65,11 +75,52 @@ func NewCompiler(file *source.File, symbolTable *SymbolTable, constants []object
 		trace:           trace,
 		modules:         modules,
 		compiledModules: make(map[string]*objects.CompiledFunction),
+		maxErrors:       DefaultMaxErrors,
 	}
 }
 
-// Compile compiles the AST node.
+// Compile compiles the AST node. The compiler continues with the next
+// statement after a semantic error, and returns all errors as an ErrorList
+// sorted by their positions. At most the number of errors set by
+// SetMaxErrors are reported.
 func (c *Compiler) Compile(node ast.Node) error {
+	c.errors = nil
+	c.warnings = nil
+	c.localDefs = nil
+	c.symbolReads = nil
+
+	if err := c.compile(node); err != nil && err != errTooManyErrors {
+		if err := c.addError(err); err != nil && err != errTooManyErrors {
+			return err
+		}
+	}
+
+	c.checkUnusedLocals()
+	c.warnings = sortWarnings(c.warnings)
+
+	if c.warningsAsErrors {
+		for _, w := range c.warnings {
+			c.errors = append(c.errors, &Error{
+				fileSet: w.fileSet,
+				pos:     w.pos,
+				error:   errors.New(w.message),
+			})
+		}
+	}
+
+	if len(c.errors) == 0 {
+		return nil
+	}
+
+	c.errors.RemoveDuplicates()
+	if c.maxErrors > 0 && len(c.errors) > c.maxErrors {
+		c.errors = c.errors[:c.maxErrors]
+	}
+
+	return c.errors
+}
+
+func (c *Compiler) compile(node ast.Node) error {
 	if c.trace != nil {
 		if node != nil {
 			defer un(trace(c, fmt.Sprintf("%s (%s)", node.String(), reflect.TypeOf(node).Elem().Name())))
@@ -81,13 +132,13 @@ func (c *Compiler) Compile(node ast.Node) error {
 	switch node := node.(type) {
 	case *ast.File:
 		for _, stmt := range node.Stmts {
-			if err := c.Compile(stmt); err != nil {
+			if err := c.compileStmt(stmt); err != nil {
 				return err
 			}
 		}
 
 	case *ast.ExprStmt:
-		if err := c.Compile(node.Expr); err != nil {
+		if err := c.compile(node.Expr); err != nil {
 			return err
 		}
 		c.emit(node, OpPop)
@@ -101,7 +152,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		return c.compileAssign(node, []ast.Expr{node.Expr}, []ast.Expr{&ast.IntLit{Value: 1}}, op)
 
 	case *ast.ParenExpr:
-		if err := c.Compile(node.Expr); err != nil {
+		if err := c.compile(node.Expr); err != nil {
 			return err
 		}
 
@@ -111,11 +162,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		if node.Token == token.Less {
-			if err := c.Compile(node.RHS); err != nil {
+			if err := c.compile(node.RHS); err != nil {
 				return err
 			}
 
-			if err := c.Compile(node.LHS); err != nil {
+			if err := c.compile(node.LHS); err != nil {
 				return err
 			}
 
@@ -123,10 +174,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 			return nil
 		} else if node.Token == token.LessEq {
-			if err := c.Compile(node.RHS); err != nil {
+			if err := c.compile(node.RHS); err != nil {
 				return err
 			}
-			if err := c.Compile(node.LHS); err != nil {
+			if err := c.compile(node.LHS); err != nil {
 				return err
 			}
 
@@ -135,10 +186,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return nil
 		}
 
-		if err := c.Compile(node.LHS); err != nil {
+		if err := c.compile(node.LHS); err != nil {
 			return err
 		}
-		if err := c.Compile(node.RHS); err != nil {
+		if err := c.compile(node.RHS); err != nil {
 			return err
 		}
 
@@ -158,8 +209,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case token.GreaterEq:
 			c.emit(node, OpBinaryOp, int(token.GreaterEq))
 		case token.Equal:
+			c.checkComparison(node)
 			c.emit(node, OpEqual)
 		case token.NotEqual:
+			c.checkComparison(node)
 			c.emit(node, OpNotEqual)
 		case token.And:
 			c.emit(node, OpBinaryOp, int(token.And))
@@ -204,7 +257,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(node, OpNull)
 
 	case *ast.UnaryExpr:
-		if err := c.Compile(node.Expr); err != nil {
+		if err := c.compile(node.Expr); err != nil {
 			return err
 		}
 
@@ -229,19 +282,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}()
 
 		if node.Init != nil {
-			if err := c.Compile(node.Init); err != nil {
+			if err := c.compile(node.Init); err != nil {
 				return err
 			}
 		}
 
-		if err := c.Compile(node.Cond); err != nil {
+		if err := c.compile(node.Cond); err != nil {
 			return err
 		}
 
 		// first jump placeholder
 		jumpPos1 := c.emit(node, OpJumpFalsy, 0)
 
-		if err := c.Compile(node.Body); err != nil {
+		if err := c.compile(node.Body); err != nil {
 			return err
 		}
 
@@ -253,7 +306,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			curPos := len(c.currentInstructions())
 			c.changeOperand(jumpPos1, curPos)
 
-			if err := c.Compile(node.Else); err != nil {
+			if err := c.compile(node.Else); err != nil {
 				return err
 			}
 
@@ -302,7 +355,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}()
 
 		for _, stmt := range node.Stmts {
-			if err := c.Compile(stmt); err != nil {
+			if err := c.compileStmt(stmt); err != nil {
 				return err
 			}
 		}
@@ -315,9 +368,17 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.Ident:
 		symbol, _, ok := c.symbolTable.Resolve(node.Name)
 		if !ok {
-			return c.errorf(node, "unresolved reference '%s'", node.Name)
+			if err := c.addError(c.errorf(node, "unresolved reference '%s'", node.Name)); err != nil {
+				return err
+			}
+
+			// continue with a placeholder value
+			c.emit(node, OpNull)
+			break
 		}
 
+		c.markRead(symbol)
+
 		switch symbol.Scope {
 		case ScopeGlobal:
 			c.emit(node, OpGetGlobal, symbol.Index)
@@ -331,7 +392,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	case *ast.ArrayLit:
 		for _, elem := range node.Elements {
-			if err := c.Compile(elem); err != nil {
+			if err := c.compile(elem); err != nil {
 				return err
 			}
 		}
@@ -347,7 +408,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(node, OpConstant, c.addConstant(&objects.String{Value: elt.Key}))
 
 			// value
-			if err := c.Compile(elt.Value); err != nil {
+			if err := c.compile(elt.Value); err != nil {
 				return err
 			}
 		}
@@ -355,34 +416,34 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(node, OpMap, len(node.Elements)*2)
 
 	case *ast.SelectorExpr: // selector on RHS side
-		if err := c.Compile(node.Expr); err != nil {
+		if err := c.compile(node.Expr); err != nil {
 			return err
 		}
 
-		if err := c.Compile(node.Sel); err != nil {
+		if err := c.compile(node.Sel); err != nil {
 			return err
 		}
 
 		c.emit(node, OpIndex)
 
 	case *ast.IndexExpr:
-		if err := c.Compile(node.Expr); err != nil {
+		if err := c.compile(node.Expr); err != nil {
 			return err
 		}
 
-		if err := c.Compile(node.Index); err != nil {
+		if err := c.compile(node.Index); err != nil {
 			return err
 		}
 
 		c.emit(node, OpIndex)
 
 	case *ast.SliceExpr:
-		if err := c.Compile(node.Expr); err != nil {
+		if err := c.compile(node.Expr); err != nil {
 			return err
 		}
 
 		if node.Low != nil {
-			if err := c.Compile(node.Low); err != nil {
+			if err := c.compile(node.Low); err != nil {
 				return err
 			}
 		} else {
@@ -390,7 +451,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		if node.High != nil {
-			if err := c.Compile(node.High); err != nil {
+			if err := c.compile(node.High); err != nil {
 				return err
 			}
 		} else {
@@ -409,7 +470,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			s.LocalAssigned = true
 		}
 
-		if err := c.Compile(node.Body); err != nil {
+		if err := c.compile(node.Body); err != nil {
 			return err
 		}
 
@@ -421,6 +482,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 		instructions, sourceMap := c.leaveScope()
 
 		for _, s := range freeSymbols {
+			// capturing by a closure counts as a read
+			c.markRead(s)
+
 			switch s.Scope {
 			case ScopeLocal:
 				if !s.LocalAssigned {
@@ -496,7 +560,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if node.Result == nil {
 			c.emit(node, OpReturn, 0)
 		} else {
-			if err := c.Compile(node.Result); err != nil {
+			if err := c.compile(node.Result); err != nil {
 				return err
 			}
 
@@ -504,12 +568,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 	case *ast.CallExpr:
-		if err := c.Compile(node.Func); err != nil {
+		if err := c.compile(node.Func); err != nil {
 			return err
 		}
 
 		for _, arg := range node.Args {
-			if err := c.Compile(arg); err != nil {
+			if err := c.compile(arg); err != nil {
 				return err
 			}
 		}
@@ -529,13 +593,31 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 			switch v := v.(type) {
 			case []byte: // module written in Tengo
+				c.addDependency(&moduleDependency{
+					Kind: sourceDependency,
+					Name: node.ModuleName,
+					Hash: hashSource(v),
+				})
+
 				compiled, err := c.compileModule(node, node.ModuleName, node.ModuleName, v)
 				if err != nil {
 					return err
 				}
 				c.emit(node, OpConstant, c.addConstant(compiled))
 				c.emit(node, OpCall, 0)
+			case *CompiledModule: // precompiled module
+				c.addDependency(&moduleDependency{
+					Kind: compiledDependency,
+					Name: node.ModuleName,
+					Hash: v.fingerprint(),
+				})
+
+				compiled := c.importCompiledModule(node.ModuleName, v)
+				c.emit(node, OpConstant, c.addConstant(compiled))
+				c.emit(node, OpCall, 0)
 			case objects.Object: // builtin module
+				c.addDependency(&moduleDependency{Kind: builtinDependency, Name: node.ModuleName})
+
 				c.emit(node, OpConstant, c.addConstant(v))
 			default:
 				panic(fmt.Errorf("invalid import value type: %T", v))
@@ -560,6 +642,14 @@ func (c *Compiler) Compile(node ast.Node) error {
 				return c.errorf(node, "module file read error: %s", err.Error())
 			}
 
+			c.addDependency(&moduleDependency{
+				Kind: fileDependency,
+				Name: node.ModuleName,
+				File: moduleName,
+				Path: modulePath,
+				Hash: hashSource(moduleSrc),
+			})
+
 			compiled, err := c.compileModule(node, moduleName, modulePath, moduleSrc)
 			if err != nil {
 				return err
@@ -577,11 +667,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		// export statement is simply ignore when compiling non-module code
-		if c.parent == nil {
+		if c.modulePath == "" {
 			break
 		}
 
-		if err := c.Compile(node.Result); err != nil {
+		if err := c.compile(node.Result); err != nil {
 			return err
 		}
 
@@ -589,28 +679,28 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(node, OpReturn, 1)
 
 	case *ast.ErrorExpr:
-		if err := c.Compile(node.Expr); err != nil {
+		if err := c.compile(node.Expr); err != nil {
 			return err
 		}
 
 		c.emit(node, OpError)
 
 	case *ast.ImmutableExpr:
-		if err := c.Compile(node.Expr); err != nil {
+		if err := c.compile(node.Expr); err != nil {
 			return err
 		}
 
 		c.emit(node, OpImmutable)
 
 	case *ast.CondExpr:
-		if err := c.Compile(node.Cond); err != nil {
+		if err := c.compile(node.Cond); err != nil {
 			return err
 		}
 
 		// first jump placeholder
 		jumpPos1 := c.emit(node, OpJumpFalsy, 0)
 
-		if err := c.Compile(node.True); err != nil {
+		if err := c.compile(node.True); err != nil {
 			return err
 		}
 
@@ -621,7 +711,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		curPos := len(c.currentInstructions())
 		c.changeOperand(jumpPos1, curPos)
 
-		if err := c.Compile(node.False); err != nil {
+		if err := c.compile(node.False); err != nil {
 			return err
 		}
 
@@ -645,6 +735,31 @@ func (c *Compiler) Bytecode() *Bytecode {
 	}
 }
 
+// Warnings returns the warnings found by the last Compile, sorted by their
+// positions.
+func (c *Compiler) Warnings() []*Warning {
+	return c.warnings
+}
+
+// SetWarningsAsErrors makes Compile report the warnings as errors.
+func (c *Compiler) SetWarningsAsErrors(enable bool) {
+	c.warningsAsErrors = enable
+}
+
+// SetMaxErrors sets the maximum number of errors reported by Compile. The
+// compilation stops when the number of errors reaches the maximum. There is
+// no limit if max is 0 or less.
+func (c *Compiler) SetMaxErrors(max int) {
+	c.maxErrors = max
+}
+
+// SetModuleCache sets the cache of the compiled modules. The source modules
+// and the file modules are imported from the cache instead of being compiled
+// again if their sources have not changed.
+func (c *Compiler) SetModuleCache(cache *ModuleCache) {
+	c.moduleCache = cache
+}
+
 // EnableFileImport enables or disables module loading from local files.
 // Local file modules are disabled by default.
 func (c *Compiler) EnableFileImport(enable bool) {
@@ -655,6 +770,9 @@ func (c *Compiler) fork(file *source.File, modulePath string, symbolTable *Symbo
 	child := NewCompiler(file, symbolTable, nil, c.modules, c.trace)
 	child.modulePath = modulePath // module file path
 	child.parent = c              // parent to set to current compiler
+	child.maxErrors = c.maxErrors
+	child.allowFileImport = c.allowFileImport
+	child.moduleCache = c.moduleCache
 
 	return child
 }
@@ -662,7 +780,7 @@ func (c *Compiler) fork(file *source.File, modulePath string, symbolTable *Symbo
 func (c *Compiler) error(node ast.Node, err error) error {
 	return &Error{
 		fileSet: c.file.Set(),
-		node:    node,
+		pos:     node.Pos(),
 		error:   err,
 	}
 }
@@ -670,11 +788,19 @@ func (c *Compiler) error(node ast.Node, err error) error {
 func (c *Compiler) errorf(node ast.Node, format string, args ...interface{}) error {
 	return &Error{
 		fileSet: c.file.Set(),
-		node:    node,
+		pos:     node.Pos(),
 		error:   fmt.Errorf(format, args...),
 	}
 }
 
+func (c *Compiler) warnf(pos source.Pos, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, &Warning{
+		fileSet: c.file.Set(),
+		pos:     pos,
+		message: fmt.Sprintf(format, args...),
+	})
+}
+
 func (c *Compiler) addConstant(o objects.Object) int {
 	if c.parent != nil {
 		// module compilers will use their parent's constants array
@@ -734,6 +860,19 @@ func (c *Compiler) optimizeFunc(node ast.Node) {
 
 	var newInsts []byte
 
+	// the instructions generated for the enclosing statements, e.g. the
+	// jump back to the loop start, are positioned before the return, and
+	// are not reported as unreachable.
+	sourceMap := c.scopes[c.scopeIndex].sourceMap
+	var returnPos source.Pos
+	var reported bool
+	unreachable := func(pos int) {
+		if srcPos := sourceMap[pos]; !reported && srcPos > returnPos {
+			c.warnf(srcPos, "unreachable code")
+			reported = true
+		}
+	}
+
 	// pass 2. eliminate dead code
 	posMap := make(map[int]int) // old position to new position
 	var dstIdx int
@@ -742,13 +881,17 @@ func (c *Compiler) optimizeFunc(node ast.Node) {
 		switch {
 		case opcode == OpReturn:
 			if deadCode {
+				unreachable(pos)
 				return true
 			}
 			deadCode = true
+			returnPos = sourceMap[pos]
+			reported = false
 		case dsts[pos]:
 			dstIdx++
 			deadCode = false
 		case deadCode:
+			unreachable(pos)
 			return true
 		}
 
@@ -28,23 +28,33 @@ func (c *Compiler) compileAssign(node ast.Node, lhs, rhs []ast.Expr, op token.To
 			return c.errorf(node, "'%s' redeclared in this block", ident)
 		}
 
+		if exists && symbol.Scope == ScopeBuiltin {
+			c.warnf(lhs[0].Pos(), "'%s' redefines the builtin function", ident)
+		}
+
 		symbol = c.symbolTable.Define(ident)
+		c.defineLocal(symbol, lhs[0])
 	} else {
 		if !exists {
 			return c.errorf(node, "unresolved reference '%s'", ident)
 		}
+
+		if numSel > 0 {
+			// assigning to an element or a field uses the value
+			c.markRead(symbol)
+		}
 	}
 
 	// +=, -=, *=, /=
 	if op != token.Assign && op != token.Define {
-		if err := c.Compile(lhs[0]); err != nil {
+		if err := c.compile(lhs[0]); err != nil {
 			return err
 		}
 	}
 
 	// compile RHSs
 	for _, expr := range rhs {
-		if err := c.Compile(expr); err != nil {
+		if err := c.compile(expr); err != nil {
 			return err
 		}
 	}
@@ -76,7 +86,7 @@ func (c *Compiler) compileAssign(node ast.Node, lhs, rhs []ast.Expr, op token.To
 
 	// compile selector expressions (right to left)
 	for i := numSel - 1; i >= 0; i-- {
-		if err := c.Compile(selectors[i]); err != nil {
+		if err := c.compile(selectors[i]); err != nil {
 			return err
 		}
 	}
@@ -1,6 +1,15 @@
 package compiler_test
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/objects"
+)
 
 func TestCompilerErrorReport(t *testing.T) {
 	expectError(t, `import("user1")`, "Compile Error: module 'user1' not found\n\tat test:1:1")
@@ -15,3 +24,59 @@ func TestCompilerErrorReport(t *testing.T) {
 	expectError(t, `func() { continue }`, "Compile Error: continue not allowed outside loop\n\tat test:1:10")
 	expectError(t, `func() { export 5 }`, "Compile Error: export not allowed inside function\n\tat test:1:10")
 }
+
+func TestCompilerErrorList(t *testing.T) {
+	expectErrors(t, `a = 1; b := c; d = 2`, nil, 0,
+		"Compile Error: unresolved reference 'a'\n\tat test:1:1",
+		"Compile Error: unresolved reference 'c'\n\tat test:1:13",
+		"Compile Error: unresolved reference 'd'\n\tat test:1:16")
+
+	// errors in expressions and nested blocks
+	expectErrors(t, `x := y + z; f := func() { if true { return w }; break }`, nil, 0,
+		"Compile Error: unresolved reference 'y'\n\tat test:1:6",
+		"Compile Error: unresolved reference 'z'\n\tat test:1:10",
+		"Compile Error: unresolved reference 'w'\n\tat test:1:44",
+		"Compile Error: break not allowed outside loop\n\tat test:1:49")
+
+	// the same module errors are reported once
+	modules := objects.NewModuleMap()
+	modules.AddSourceModule("mod", []byte(`export a`))
+	expectErrors(t, `m1 := import("mod"); m2 := import("mod"); b = 1`, modules, 0,
+		"Compile Error: unresolved reference 'a'\n\tat mod:1:8",
+		"Compile Error: unresolved reference 'b'\n\tat test:1:43")
+
+	// maximum number of errors
+	src := strings.Repeat("a = 1\n", 15)
+	assert.Equal(t, compiler.DefaultMaxErrors, len(compileErrors(t, src, nil, compiler.DefaultMaxErrors)))
+	assert.Equal(t, 3, len(compileErrors(t, src, nil, 3)))
+	assert.Equal(t, 15, len(compileErrors(t, src, nil, 0)))
+	assert.Equal(t, "Compile Error: unresolved reference 'a'\n\tat test:1:1 (and 2 more errors)",
+		compileErrors(t, src, nil, 3).Error())
+}
+
+func expectErrors(t *testing.T, input string, modules *objects.ModuleMap, maxErrors int, expected ...string) {
+	var actual []string
+	for _, err := range compileErrors(t, input, modules, maxErrors) {
+		actual = append(actual, err.Error())
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func compileErrors(t *testing.T, input string, modules *objects.ModuleMap, maxErrors int) compiler.ErrorList {
+	fileSet := source.NewFileSet()
+	file := fileSet.AddFile("test", -1, len(input))
+
+	parsed, err := parser.NewParser(file, []byte(input), nil).ParseFile()
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	c := compiler.NewCompiler(file, nil, nil, modules, nil)
+	c.SetMaxErrors(maxErrors)
+
+	list, ok := c.Compile(parsed).(compiler.ErrorList)
+	assert.True(t, ok)
+
+	return list
+}
@@ -0,0 +1,45 @@
+package compiler
+
+import "github.com/d5/tengo/compiler/ast"
+
+// compileStmt compiles the statement. If the statement has a semantic
+// error, the error is recorded and the compiler state is restored so that
+// the compilation can continue with the next statement.
+func (c *Compiler) compileStmt(stmt ast.Stmt) error {
+	symbolTable := c.symbolTable
+	scopeIndex := c.scopeIndex
+	loopIndex := c.loopIndex
+
+	err := c.compile(stmt)
+	if err == nil {
+		return nil
+	}
+
+	c.symbolTable = symbolTable
+	c.scopes = c.scopes[:scopeIndex+1]
+	c.scopeIndex = scopeIndex
+	c.loops = c.loops[:loopIndex+1]
+	c.loopIndex = loopIndex
+
+	return c.addError(err)
+}
+
+// addError records the semantic errors. It returns the error back if the
+// compilation cannot continue, or errTooManyErrors if the number of errors
+// reached the maximum.
+func (c *Compiler) addError(err error) error {
+	switch err := err.(type) {
+	case *Error:
+		c.errors = append(c.errors, err)
+	case ErrorList:
+		c.errors = append(c.errors, err...)
+	default:
+		return err
+	}
+
+	if c.maxErrors > 0 && len(c.errors) >= c.maxErrors {
+		return errTooManyErrors
+	}
+
+	return nil
+}
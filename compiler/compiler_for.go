@@ -12,7 +12,7 @@ func (c *Compiler) compileForStmt(stmt *ast.ForStmt) error {
 
 	// init statement
 	if stmt.Init != nil {
-		if err := c.Compile(stmt.Init); err != nil {
+		if err := c.compile(stmt.Init); err != nil {
 			return err
 		}
 	}
@@ -23,7 +23,7 @@ func (c *Compiler) compileForStmt(stmt *ast.ForStmt) error {
 	// condition expression
 	postCondPos := -1
 	if stmt.Cond != nil {
-		if err := c.Compile(stmt.Cond); err != nil {
+		if err := c.compile(stmt.Cond); err != nil {
 			return err
 		}
 		// condition jump position
@@ -34,7 +34,7 @@ func (c *Compiler) compileForStmt(stmt *ast.ForStmt) error {
 	loop := c.enterLoop()
 
 	// body statement
-	if err := c.Compile(stmt.Body); err != nil {
+	if err := c.compile(stmt.Body); err != nil {
 		c.leaveLoop()
 		return err
 	}
@@ -46,7 +46,7 @@ func (c *Compiler) compileForStmt(stmt *ast.ForStmt) error {
 
 	// post statement
 	if stmt.Post != nil {
-		if err := c.Compile(stmt.Post); err != nil {
+		if err := c.compile(stmt.Post); err != nil {
 			return err
 		}
 	}
@@ -91,7 +91,7 @@ func (c *Compiler) compileForInStmt(stmt *ast.ForInStmt) error {
 	// init
 	//   :it = iterator(iterable)
 	itSymbol := c.symbolTable.Define(":it")
-	if err := c.Compile(stmt.Iterable); err != nil {
+	if err := c.compile(stmt.Iterable); err != nil {
 		return err
 	}
 	c.emit(stmt, OpIteratorInit)
@@ -122,6 +122,7 @@ func (c *Compiler) compileForInStmt(stmt *ast.ForInStmt) error {
 	// assign key variable
 	if stmt.Key.Name != "_" {
 		keySymbol := c.symbolTable.Define(stmt.Key.Name)
+		c.defineLocal(keySymbol, stmt.Key)
 		if itSymbol.Scope == ScopeGlobal {
 			c.emit(stmt, OpGetGlobal, itSymbol.Index)
 		} else {
@@ -138,6 +139,7 @@ func (c *Compiler) compileForInStmt(stmt *ast.ForInStmt) error {
 	// assign value variable
 	if stmt.Value.Name != "_" {
 		valueSymbol := c.symbolTable.Define(stmt.Value.Name)
+		c.defineLocal(valueSymbol, stmt.Value)
 		if itSymbol.Scope == ScopeGlobal {
 			c.emit(stmt, OpGetGlobal, itSymbol.Index)
 		} else {
@@ -152,7 +154,7 @@ func (c *Compiler) compileForInStmt(stmt *ast.ForInStmt) error {
 	}
 
 	// body statement
-	if err := c.Compile(stmt.Body); err != nil {
+	if err := c.compile(stmt.Body); err != nil {
 		c.leaveLoop()
 		return err
 	}
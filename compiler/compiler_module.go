@@ -3,14 +3,22 @@ package compiler
 import (
 	"github.com/d5/tengo/compiler/ast"
 	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
 	"github.com/d5/tengo/objects"
 )
 
 func (c *Compiler) checkCyclicImports(node ast.Node, modulePath string) error {
-	if c.modulePath == modulePath {
-		return c.errorf(node, "cyclic module import: %s", modulePath)
-	} else if c.parent != nil {
-		return c.parent.checkCyclicImports(node, modulePath)
+	for m := c; m != nil; {
+		if m.modulePath == modulePath {
+			return c.errorf(node, "cyclic module import: %s", modulePath)
+		}
+
+		if m.parent != nil {
+			m = m.parent
+		} else {
+			// the compiler of a standalone module
+			m = m.importer
+		}
 	}
 
 	return nil
@@ -26,6 +34,10 @@ func (c *Compiler) compileModule(node ast.Node, moduleName, modulePath string, s
 		return compiledModule, nil
 	}
 
+	if c.moduleCache != nil {
+		return c.compileCachedModule(moduleName, modulePath, src)
+	}
+
 	modFile := c.file.Set().AddFile(moduleName, -1, len(src))
 	p := parser.NewParser(modFile, src, nil)
 	file, err := p.ParseFile()
@@ -52,6 +64,11 @@ func (c *Compiler) compileModule(node ast.Node, moduleName, modulePath string, s
 	// code optimization
 	moduleCompiler.optimizeFunc(node)
 
+	// the warnings in the modules of the module map are not reported
+	if c.modules.Get(moduleName) == nil {
+		c.warnings = append(c.warnings, moduleCompiler.warnings...)
+	}
+
 	compiledFunc := moduleCompiler.Bytecode().MainFunction
 	compiledFunc.NumLocals = symbolTable.MaxSymbols()
 
@@ -77,3 +94,191 @@ func (c *Compiler) storeCompiledModule(modulePath string, module *objects.Compil
 
 	c.compiledModules[modulePath] = module
 }
+
+// compileCachedModule imports the module from the module cache. The module
+// is compiled and stored in the cache if it is not found, or the source of
+// the module or the modules it imports has changed.
+func (c *Compiler) compileCachedModule(moduleName, modulePath string, src []byte) (*objects.CompiledFunction, error) {
+	hash := hashSource(src)
+
+	entry := c.moduleCache.get(modulePath, hash)
+	if entry == nil || !c.validDependencies(entry.Dependencies) {
+		var err error
+		entry, err = compileStandaloneModule(c, c.modules, moduleName, modulePath, src)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Hash = hash
+		c.moduleCache.put(modulePath, entry)
+	}
+
+	for _, dep := range entry.Dependencies {
+		c.addDependency(dep)
+	}
+
+	compiledFunc, pos := c.addCompiledModule(entry.Module)
+
+	// the warnings in the modules of the module map are not reported
+	if c.modules.Get(moduleName) == nil {
+		for _, w := range entry.Warnings {
+			c.warnings = append(c.warnings, &Warning{
+				fileSet: c.file.Set(),
+				pos:     pos(w.Pos),
+				message: w.Message,
+			})
+		}
+	}
+
+	c.storeCompiledModule(modulePath, compiledFunc)
+
+	return compiledFunc, nil
+}
+
+// compileStandaloneModule compiles the module source into a compiled module
+// that has its own file set and constants. If importer is not nil, the
+// module compiler inherits its options, and file imports are allowed
+// otherwise.
+func compileStandaloneModule(importer *Compiler, modules *objects.ModuleMap, moduleName, modulePath string, src []byte) (*moduleCacheEntry, error) {
+	fileSet := source.NewFileSet()
+	modFile := fileSet.AddFile(moduleName, -1, len(src))
+
+	p := parser.NewParser(modFile, src, nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	symbolTable := NewSymbolTable()
+	for idx, fn := range objects.Builtins {
+		symbolTable.DefineBuiltin(idx, fn.Name)
+	}
+
+	// no global scope for the module
+	symbolTable = symbolTable.Fork(false)
+
+	c := NewCompiler(modFile, symbolTable, nil, modules, nil)
+	c.modulePath = modulePath
+	c.allowFileImport = true
+	if importer != nil {
+		c.importer = importer
+		c.allowFileImport = importer.allowFileImport
+		c.maxErrors = importer.maxErrors
+		c.moduleCache = importer.moduleCache
+	}
+
+	if err := c.Compile(file); err != nil {
+		return nil, err
+	}
+
+	// code optimization
+	c.optimizeFunc(file)
+
+	bytecode := c.Bytecode()
+	bytecode.MainFunction.NumLocals = symbolTable.MaxSymbols()
+
+	entry := &moduleCacheEntry{
+		Module: &CompiledModule{
+			FileSet:   fileSet,
+			Function:  bytecode.MainFunction,
+			Constants: bytecode.Constants,
+		},
+		Dependencies: c.dependencies,
+	}
+	for _, w := range c.warnings {
+		entry.Warnings = append(entry.Warnings, &moduleWarning{Pos: w.pos, Message: w.message})
+	}
+
+	return entry, nil
+}
+
+// importCompiledModule imports the precompiled module of the module map.
+func (c *Compiler) importCompiledModule(moduleName string, m *CompiledModule) *objects.CompiledFunction {
+	compiledModule, exists := c.loadCompiledModule(moduleName)
+	if exists {
+		return compiledModule
+	}
+
+	compiledFunc, _ := c.addCompiledModule(m)
+
+	c.storeCompiledModule(moduleName, compiledFunc)
+
+	return compiledFunc
+}
+
+// addCompiledModule adds the constants of the compiled module to the
+// constants, and returns the module function and the function that
+// translates the source positions of the module. The constant indexes and
+// the source positions in the module functions are relocated, and the
+// builtin modules are replaced with the ones in the module map.
+func (c *Compiler) addCompiledModule(m *CompiledModule) (*objects.CompiledFunction, func(source.Pos) source.Pos) {
+	root := c
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	indexMap := make(map[int]int)
+	for idx := range m.Constants {
+		indexMap[idx] = len(root.constants) + idx
+	}
+
+	pos := c.relocatePositions(m.FileSet)
+	relocate := func(fn *objects.CompiledFunction) *objects.CompiledFunction {
+		insts := append([]byte{}, fn.Instructions...)
+		updateConstIndexes(insts, indexMap)
+
+		sourceMap := make(map[int]source.Pos, len(fn.SourceMap))
+		for offset, p := range fn.SourceMap {
+			sourceMap[offset] = pos(p)
+		}
+
+		return &objects.CompiledFunction{
+			Instructions:  insts,
+			NumLocals:     fn.NumLocals,
+			NumParameters: fn.NumParameters,
+			VarArgs:       fn.VarArgs,
+			SourceMap:     sourceMap,
+		}
+	}
+
+	for _, cn := range m.Constants {
+		switch v := cn.(type) {
+		case *objects.CompiledFunction:
+			cn = relocate(v)
+		case *objects.ImmutableMap:
+			name := moduleName(v)
+			if mod := c.modules.GetBuiltinModule(name); name != "" && mod != nil {
+				cn = mod.AsImmutableMap(name)
+			}
+		}
+		c.addConstant(cn)
+	}
+
+	return relocate(m.Function), pos
+}
+
+// relocatePositions adds the files of the file set to the file set of the
+// compiler, and returns the function that translates the positions in the
+// file set into the positions in the compiler's file set.
+func (c *Compiler) relocatePositions(fileSet *source.FileSet) func(p source.Pos) source.Pos {
+	if fileSet == nil {
+		return func(source.Pos) source.Pos { return source.NoPos }
+	}
+
+	// the new bases keyed by the old bases
+	bases := make(map[int]int)
+	for _, f := range fileSet.Files {
+		file := c.file.Set().AddFile(f.Name, -1, f.Size)
+		file.Lines = append([]int{}, f.Lines...)
+		bases[f.Base] = file.Base
+	}
+
+	return func(p source.Pos) source.Pos {
+		f := fileSet.File(p)
+		if f == nil {
+			return source.NoPos
+		}
+
+		return source.Pos(bases[f.Base] + int(p) - f.Base)
+	}
+}
@@ -0,0 +1,112 @@
+package compiler_test
+
+import (
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/objects"
+)
+
+func TestCompilerWarnings(t *testing.T) {
+	// unused variables
+	expectWarnings(t, `a := 1`)
+	expectWarnings(t, `func() { a := 1 }`,
+		"Compile Warning: 'a' declared and not used\n\tat test:1:10")
+	expectWarnings(t, `func() { a := 1; b := a; _ := 2 }`,
+		"Compile Warning: 'b' declared and not used\n\tat test:1:18")
+	expectWarnings(t, `func() { a := 1; return func() { return a } }`)
+	expectWarnings(t, `func() { a := []; a[0] = 1 }`)
+	expectWarnings(t, `func() { a := 1; a += 2 }`)
+	expectWarnings(t, `if a := 1; true { }`)
+	expectWarnings(t, `func() { if a := 1; true { } }`,
+		"Compile Warning: 'a' declared and not used\n\tat test:1:13")
+	expectWarnings(t, `func() { for k, v in [1] { } }`,
+		"Compile Warning: 'k' declared and not used\n\tat test:1:14",
+		"Compile Warning: 'v' declared and not used\n\tat test:1:17")
+	expectWarnings(t, `func() { for _, v in [1] { v } }`)
+
+	// builtin functions
+	expectWarnings(t, `func() { len := 3; return len }`,
+		"Compile Warning: 'len' redefines the builtin function\n\tat test:1:10")
+
+	// comparisons
+	expectWarnings(t, `a := 1 == "1"`,
+		"Compile Warning: comparison of int and string is always false\n\tat test:1:6")
+	expectWarnings(t, `a := 1.0 != (1)`,
+		"Compile Warning: comparison of float and int is always true\n\tat test:1:6")
+	expectWarnings(t, `a := len([]) == "0"`,
+		"Compile Warning: comparison of int and string is always false\n\tat test:1:6")
+	expectWarnings(t, `a := [] == immutable([]); b := 1 == 2; c := !a == (b == true)`)
+	expectWarnings(t, `x := 1; a := x == "1"`)
+
+	// unreachable code
+	expectWarnings(t, `func() { return 1; a := 2; return a }`,
+		"Compile Warning: unreachable code\n\tat test:1:25")
+	expectWarnings(t, `func() { if true { return 1 }; return 2 }`)
+	expectWarnings(t, `func() { for { return 1 } }`)
+}
+
+func TestCompilerWarnings_Modules(t *testing.T) {
+	modules := objects.NewModuleMap()
+	modules.AddSourceModule("mod", []byte(`a := 1; export func() { b := 1 }`))
+
+	// warnings in the modules of the module map are not reported
+	c := compileWithWarnings(t, `m := import("mod")`, modules, false)
+	assert.Equal(t, 0, len(c.Warnings()))
+}
+
+func TestCompilerWarnings_AsErrors(t *testing.T) {
+	c := compileWithWarnings(t, `func() { a := 1 }`, nil, true)
+	assert.Equal(t, 1, len(c.Warnings()))
+
+	c = compiler.NewCompiler(source.NewFileSet().AddFile("test", -1, 1), nil, nil, nil, nil)
+	assert.Equal(t, 0, len(c.Warnings()))
+
+	input := `func() { a := 1; b := c }`
+	fileSet := source.NewFileSet()
+	file := fileSet.AddFile("test", -1, len(input))
+	parsed, err := parser.NewParser(file, []byte(input), nil).ParseFile()
+	assert.NoError(t, err)
+
+	c = compiler.NewCompiler(file, nil, nil, nil, nil)
+	c.SetWarningsAsErrors(true)
+	list, ok := c.Compile(parsed).(compiler.ErrorList)
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(list))
+	assert.Equal(t, "'a' declared and not used", list[0].Message())
+	assert.Equal(t, "'b' declared and not used", list[1].Message())
+	assert.Equal(t, "unresolved reference 'c'", list[2].Message())
+}
+
+func expectWarnings(t *testing.T, input string, expected ...string) {
+	var actual []string
+	for _, w := range compileWithWarnings(t, input, nil, false).Warnings() {
+		actual = append(actual, w.String())
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func compileWithWarnings(t *testing.T, input string, modules *objects.ModuleMap, warningsAsErrors bool) *compiler.Compiler {
+	fileSet := source.NewFileSet()
+	file := fileSet.AddFile("test", -1, len(input))
+
+	parsed, err := parser.NewParser(file, []byte(input), nil).ParseFile()
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	c := compiler.NewCompiler(file, nil, nil, modules, nil)
+	c.SetWarningsAsErrors(warningsAsErrors)
+	err = c.Compile(parsed)
+	if warningsAsErrors {
+		assert.Error(t, err)
+	} else {
+		assert.NoError(t, err)
+	}
+
+	return c
+}
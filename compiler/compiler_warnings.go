@@ -0,0 +1,117 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/token"
+)
+
+// localDefinition represents a variable defined in the source.
+type localDefinition struct {
+	symbol *Symbol
+	node   ast.Node
+}
+
+// defineLocal records the definition of the variable so that it can be
+// reported if its value is never read.
+func (c *Compiler) defineLocal(symbol *Symbol, node ast.Node) {
+	c.localDefs = append(c.localDefs, localDefinition{symbol: symbol, node: node})
+}
+
+// markRead marks that the value of the variable is read.
+func (c *Compiler) markRead(symbol *Symbol) {
+	if c.symbolReads == nil {
+		c.symbolReads = make(map[*Symbol]bool)
+	}
+
+	c.symbolReads[symbol] = true
+}
+
+// checkUnusedLocals warns about the local variables whose values are never
+// read. The global variables are not reported because they can be read by
+// the host application.
+func (c *Compiler) checkUnusedLocals() {
+	for _, def := range c.localDefs {
+		if def.symbol.Scope == ScopeLocal && def.symbol.Name != "_" && !c.symbolReads[def.symbol] {
+			c.warnf(def.node.Pos(), "'%s' declared and not used", def.symbol.Name)
+		}
+	}
+}
+
+// checkComparison warns about the equality comparisons whose operands are
+// always of different types.
+func (c *Compiler) checkComparison(node *ast.BinaryExpr) {
+	lhs, rhs := c.staticType(node.LHS), c.staticType(node.RHS)
+	if lhs == "" || rhs == "" || lhs == rhs {
+		return
+	}
+
+	result := "false"
+	if node.Token == token.NotEqual {
+		result = "true"
+	}
+
+	c.warnf(node.Pos(), "comparison of %s and %s is always %s", lhs, rhs, result)
+}
+
+// staticType returns the type of the expression value if it can be known
+// at compile time, or an empty string otherwise.
+func (c *Compiler) staticType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.IntLit:
+		return "int"
+	case *ast.FloatLit:
+		return "float"
+	case *ast.StringLit:
+		return "string"
+	case *ast.CharLit:
+		return "char"
+	case *ast.BoolLit:
+		return "bool"
+	case *ast.ArrayLit:
+		return "array"
+	case *ast.MapLit:
+		return "map"
+	case *ast.FuncLit:
+		return "function"
+	case *ast.UndefinedLit:
+		return "undefined"
+	case *ast.ErrorExpr:
+		return "error"
+	case *ast.ImmutableExpr:
+		return c.staticType(expr.Expr)
+	case *ast.ParenExpr:
+		return c.staticType(expr.Expr)
+	case *ast.UnaryExpr:
+		if expr.Token == token.Not {
+			return "bool"
+		}
+	case *ast.BinaryExpr:
+		switch expr.Token {
+		case token.Equal, token.NotEqual, token.Less, token.LessEq, token.Greater, token.GreaterEq:
+			return "bool"
+		}
+	case *ast.CallExpr:
+		ident, ok := expr.Func.(*ast.Ident)
+		if !ok {
+			return ""
+		}
+
+		symbol, _, ok := c.symbolTable.Resolve(ident.Name)
+		if !ok || symbol.Scope != ScopeBuiltin {
+			return ""
+		}
+
+		switch {
+		case ident.Name == "len":
+			return "int"
+		case ident.Name == "type_name":
+			return "string"
+		case strings.HasPrefix(ident.Name, "is_"):
+			return "bool"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,345 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/compiler/token"
+	"github.com/d5/tengo/objects"
+)
+
+// Disassembly is the human and machine readable representation of the
+// compiled functions of a Bytecode.
+type Disassembly struct {
+	Functions []*DisassembledFunction `json:"functions"`
+}
+
+// DisassembledFunction represents a disassembled compiled function.
+type DisassembledFunction struct {
+	// Name is "main" for the main function, "module <name>" for the
+	// modules, the name of the variable, the field or the map key that the
+	// function literal is assigned to, or "func" for the anonymous functions.
+	Name string `json:"name"`
+
+	// Constant is the index of the function in the constants, or -1 for the
+	// main function.
+	Constant int `json:"constant"`
+
+	// Position is the position of the function literal, or the filename of
+	// the main function and the modules.
+	Position string `json:"position"`
+
+	NumParameters int                        `json:"numParameters"`
+	VarArgs       bool                       `json:"varArgs"`
+	NumLocals     int                        `json:"numLocals"`
+	NumFree       int                        `json:"numFree"`
+	Instructions  []*DisassembledInstruction `json:"instructions"`
+}
+
+// DisassembledInstruction represents a disassembled instruction.
+type DisassembledInstruction struct {
+	Offset int `json:"offset"`
+
+	// Label is set if the instruction is the target of a jump.
+	Label string `json:"label,omitempty"`
+
+	// Line is the line of the source that the instruction is compiled from.
+	Line int `json:"line,omitempty"`
+
+	Opcode   string `json:"opcode"`
+	Operands []int  `json:"operands"`
+
+	// Comment describes the operands, e.g. the constant value or the
+	// builtin function name.
+	Comment string `json:"comment,omitempty"`
+
+	// Target is the label of the jump destination.
+	Target string `json:"target,omitempty"`
+}
+
+// functionLoader is the instruction that pushes a compiled function.
+type functionLoader struct {
+	pos     source.Pos
+	numFree int
+}
+
+// Disassemble disassembles the main function and all the compiled functions
+// in the constants. If src is not nil, it is called with the filenames of
+// the file set to get the sources, which are used to find the names of the
+// functions. src may return nil if the source is not available.
+func (b *Bytecode) Disassemble(src func(filename string) []byte) *Disassembly {
+	// the instructions that push the functions
+	loaders := make(map[int]functionLoader)
+	collect := func(fn *objects.CompiledFunction) {
+		iterateInstructions(fn.Instructions, func(pos int, opcode Opcode, operands []int) bool {
+			switch opcode {
+			case OpConstant:
+				loaders[operands[0]] = functionLoader{pos: fn.SourceMap[pos]}
+			case OpClosure:
+				loaders[operands[0]] = functionLoader{pos: fn.SourceMap[pos], numFree: operands[1]}
+			}
+
+			return true
+		})
+	}
+
+	collect(b.MainFunction)
+	for _, cn := range b.Constants {
+		if fn, ok := cn.(*objects.CompiledFunction); ok {
+			collect(fn)
+		}
+	}
+
+	names := b.functionNames(src)
+
+	// the names and the positions of the functions in the constants
+	fnNames := make(map[int]string)
+	fnPositions := make(map[int]string)
+	for idx, cn := range b.Constants {
+		fn, ok := cn.(*objects.CompiledFunction)
+		if !ok {
+			continue
+		}
+
+		loader := loaders[idx]
+		loaderFile := b.FileSet.File(loader.pos)
+		file := b.FileSet.File(b.firstPos(fn))
+		if file != nil && loaderFile != file {
+			// the module function is pushed by the import expression
+			fnNames[idx] = "module " + file.Name
+			fnPositions[idx] = file.Name
+		} else {
+			fnNames[idx] = "func"
+			if name, ok := names[loader.pos]; ok {
+				fnNames[idx] = name
+			}
+			fnPositions[idx] = b.FileSet.Position(loader.pos).String()
+		}
+	}
+
+	d := &Disassembly{}
+
+	mainFn := b.disassembleFunction(b.MainFunction, fnNames)
+	mainFn.Name = "main"
+	mainFn.Constant = -1
+	if file := b.FileSet.File(b.firstPos(b.MainFunction)); file != nil {
+		mainFn.Position = file.Name
+	}
+	d.Functions = append(d.Functions, mainFn)
+
+	for idx, cn := range b.Constants {
+		fn, ok := cn.(*objects.CompiledFunction)
+		if !ok {
+			continue
+		}
+
+		df := b.disassembleFunction(fn, fnNames)
+		df.Name = fnNames[idx]
+		df.Constant = idx
+		df.Position = fnPositions[idx]
+		df.NumFree = loaders[idx].numFree
+		d.Functions = append(d.Functions, df)
+	}
+
+	return d
+}
+
+// firstPos returns the source position of the first instruction of the
+// function.
+func (b *Bytecode) firstPos(fn *objects.CompiledFunction) source.Pos {
+	first := source.NoPos
+	offset := -1
+	for o, pos := range fn.SourceMap {
+		if offset < 0 || o < offset {
+			first, offset = pos, o
+		}
+	}
+
+	return first
+}
+
+func (b *Bytecode) disassembleFunction(fn *objects.CompiledFunction, fnNames map[int]string) *DisassembledFunction {
+	df := &DisassembledFunction{
+		NumParameters: fn.NumParameters,
+		VarArgs:       fn.VarArgs,
+		NumLocals:     fn.NumLocals,
+	}
+
+	// jump targets
+	var targets []int
+	iterateInstructions(fn.Instructions, func(pos int, opcode Opcode, operands []int) bool {
+		switch opcode {
+		case OpJump, OpJumpFalsy, OpAndJump, OpOrJump:
+			targets = append(targets, operands[0])
+		}
+
+		return true
+	})
+	sort.Ints(targets)
+
+	labels := make(map[int]string)
+	for _, target := range targets {
+		if _, ok := labels[target]; !ok {
+			labels[target] = "L" + strconv.Itoa(len(labels)+1)
+		}
+	}
+
+	iterateInstructions(fn.Instructions, func(pos int, opcode Opcode, operands []int) bool {
+		inst := &DisassembledInstruction{
+			Offset:   pos,
+			Label:    labels[pos],
+			Opcode:   OpcodeNames[opcode],
+			Operands: append([]int{}, operands...),
+		}
+
+		if srcPos, ok := fn.SourceMap[pos]; ok {
+			inst.Line = b.FileSet.Position(srcPos).Line
+		}
+
+		switch opcode {
+		case OpJump, OpJumpFalsy, OpAndJump, OpOrJump:
+			inst.Target = labels[operands[0]]
+		case OpConstant, OpClosure:
+			inst.Comment = b.describeConstant(operands[0], fnNames)
+		case OpGetBuiltin:
+			if operands[0] < len(objects.Builtins) {
+				inst.Comment = objects.Builtins[operands[0]].Name
+			}
+		case OpBinaryOp:
+			inst.Comment = token.Token(operands[0]).String()
+		}
+
+		df.Instructions = append(df.Instructions, inst)
+
+		return true
+	})
+
+	return df
+}
+
+func (b *Bytecode) describeConstant(idx int, fnNames map[int]string) string {
+	if idx >= len(b.Constants) {
+		return ""
+	}
+
+	switch cn := b.Constants[idx].(type) {
+	case *objects.CompiledFunction:
+		return fnNames[idx]
+	case *objects.ImmutableMap:
+		// builtin module
+		if name, ok := cn.Value["__module_name__"].(*objects.String); ok {
+			return "module " + name.Value
+		}
+
+		return fmt.Sprintf("%s (%s)", cn.String(), cn.TypeName())
+	default:
+		return fmt.Sprintf("%s (%s)", cn.String(), cn.TypeName())
+	}
+}
+
+// functionNames finds the names of the function literals in the sources of
+// the file set, keyed by the positions of the literals.
+func (b *Bytecode) functionNames(src func(filename string) []byte) map[source.Pos]string {
+	names := make(map[source.Pos]string)
+	if src == nil || b.FileSet == nil {
+		return names
+	}
+
+	for _, file := range b.FileSet.Files {
+		data := src(file.Name)
+		if data == nil || len(data) != file.Size {
+			continue
+		}
+
+		// parse the source at the same base so that the positions match
+		fileSet := source.NewFileSet()
+		srcFile := fileSet.AddFile(file.Name, file.Base, file.Size)
+
+		parsed, err := parser.NewParser(srcFile, data, nil).ParseFile()
+		if err != nil {
+			continue
+		}
+
+		ast.Inspect(parsed, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.AssignStmt:
+				if len(node.LHS) != len(node.RHS) {
+					break
+				}
+
+				for i, rhs := range node.RHS {
+					if fn, ok := rhs.(*ast.FuncLit); ok {
+						names[fn.Pos()] = node.LHS[i].String()
+					}
+				}
+			case *ast.MapElementLit:
+				if fn, ok := node.Value.(*ast.FuncLit); ok {
+					names[fn.Pos()] = node.Key
+				}
+			case *ast.ExportStmt:
+				if fn, ok := node.Result.(*ast.FuncLit); ok {
+					names[fn.Pos()] = "export"
+				}
+			}
+
+			return true
+		})
+	}
+
+	return names
+}
+
+// String returns the text representation of the disassembly.
+func (d *Disassembly) String() string {
+	var sb strings.Builder
+
+	for i, fn := range d.Functions {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		fmt.Fprintf(&sb, "%s (%s)\n", fn.Name, fn.Position)
+
+		varArgs := ""
+		if fn.VarArgs {
+			varArgs = " (variadic)"
+		}
+		if fn.Constant >= 0 {
+			fmt.Fprintf(&sb, "  constant: %d\n", fn.Constant)
+		}
+		fmt.Fprintf(&sb, "  parameters: %d%s, locals: %d, free: %d\n", fn.NumParameters, varArgs, fn.NumLocals, fn.NumFree)
+
+		for _, inst := range fn.Instructions {
+			if inst.Label != "" {
+				fmt.Fprintf(&sb, "%s:\n", inst.Label)
+			}
+
+			line := "-"
+			if inst.Line > 0 {
+				line = strconv.Itoa(inst.Line)
+			}
+
+			operands := make([]string, len(inst.Operands))
+			for i, o := range inst.Operands {
+				operands[i] = strconv.Itoa(o)
+			}
+			if inst.Target != "" {
+				operands[0] = inst.Target
+			}
+
+			s := fmt.Sprintf("  %04d %5s  %-8s %-8s", inst.Offset, line, inst.Opcode, strings.Join(operands, " "))
+			if inst.Comment != "" {
+				s += " ; " + inst.Comment
+			}
+			sb.WriteString(strings.TrimRight(s, " "))
+			sb.WriteString("\n")
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,109 @@
+package compiler_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/objects"
+)
+
+func TestBytecode_Disassemble(t *testing.T) {
+	input := `mod := import("mod")
+add := func(a, b) {
+	if a > b { return a }
+	return a + b
+}
+m := {
+	f: func(...args) { return func() { return args } }
+}
+len([add(1, 2), m.f()])`
+
+	modules := objects.NewModuleMap()
+	modules.AddSourceModule("mod", []byte(`export func(x) { return x }`))
+
+	bytecode := compileBytecode(t, input, modules)
+	src := func(filename string) []byte {
+		switch filename {
+		case "test":
+			return []byte(input)
+		case "mod":
+			return modules.GetSourceModule("mod").Src
+		}
+		return nil
+	}
+
+	d := bytecode.Disassemble(src)
+	assert.Equal(t, []string{"main", "export", "module mod", "add", "func", "f"}, functionNames(d))
+
+	mainFn := d.Functions[0]
+	assert.Equal(t, -1, mainFn.Constant)
+	assert.Equal(t, "test", mainFn.Position)
+	assert.Equal(t, "module mod", mainFn.Instructions[0].Comment)
+	assert.Equal(t, 1, mainFn.Instructions[0].Line)
+
+	add := d.Functions[3]
+	assert.Equal(t, "test:2:8", add.Position)
+	assert.Equal(t, 2, add.NumParameters)
+	assert.Equal(t, 2, add.NumLocals)
+	assert.Equal(t, `add (test:2:8)
+  constant: 2
+  parameters: 2, locals: 2, free: 0
+  0000     3  GETL     0
+  0002     3  GETL     1
+  0004     3  BINARYOP 39       ; >
+  0006     3  JMPF     L1
+  0009     3  GETL     0
+  0011     3  RET      1
+L1:
+  0013     4  GETL     0
+  0015     4  GETL     1
+  0017     4  BINARYOP 11       ; +
+  0019     4  RET      1
+`, (&compiler.Disassembly{Functions: []*compiler.DisassembledFunction{add}}).String())
+
+	closure := d.Functions[4]
+	assert.Equal(t, 1, closure.NumFree)
+	assert.Equal(t, "test:7:28", closure.Position)
+
+	f := d.Functions[5]
+	assert.True(t, f.VarArgs)
+	assert.Equal(t, "CLOSURE", f.Instructions[1].Opcode)
+	assert.Equal(t, "func", f.Instructions[1].Comment)
+
+	// the builtin functions
+	assert.True(t, strings.Contains(d.String(), "BUILTIN  0        ; len\n"))
+
+	// without the sources
+	d = bytecode.Disassemble(nil)
+	assert.Equal(t, []string{"main", "func", "module mod", "func", "func", "func"}, functionNames(d))
+}
+
+func functionNames(d *compiler.Disassembly) []string {
+	var names []string
+	for _, fn := range d.Functions {
+		names = append(names, fn.Name)
+	}
+
+	return names
+}
+
+func compileBytecode(t *testing.T, input string, modules *objects.ModuleMap) *compiler.Bytecode {
+	fileSet := source.NewFileSet()
+	file := fileSet.AddFile("test", -1, len(input))
+
+	parsed, err := parser.NewParser(file, []byte(input), nil).ParseFile()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	c := compiler.NewCompiler(file, nil, nil, modules, nil)
+	if !assert.NoError(t, c.Compile(parsed)) {
+		t.FailNow()
+	}
+
+	return c.Bytecode()
+}
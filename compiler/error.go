@@ -3,18 +3,27 @@ package compiler
 import (
 	"fmt"
 
-	"github.com/d5/tengo/compiler/ast"
 	"github.com/d5/tengo/compiler/source"
 )
 
 // Error represents a compiler error.
 type Error struct {
 	fileSet *source.FileSet
-	node    ast.Node
+	pos     source.Pos
 	error   error
 }
 
 func (e *Error) Error() string {
-	filePos := e.fileSet.Position(e.node.Pos())
+	filePos := e.fileSet.Position(e.pos)
 	return fmt.Sprintf("Compile Error: %s\n\tat %s", e.error.Error(), filePos)
 }
+
+// Pos returns the position in the source where the error occurred.
+func (e *Error) Pos() source.FilePos {
+	return e.fileSet.Position(e.pos)
+}
+
+// Message returns the error message without the position.
+func (e *Error) Message() string {
+	return e.error.Error()
+}
@@ -0,0 +1,86 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// DefaultMaxErrors is the default maximum number of errors reported by the
+// compiler.
+const DefaultMaxErrors = 10
+
+// errTooManyErrors stops the compilation when the number of errors reaches
+// the maximum.
+var errTooManyErrors = errors.New("too many errors")
+
+// ErrorList is a collection of compiler errors.
+type ErrorList []*Error
+
+// Len returns the number of elements in the collection.
+func (p ErrorList) Len() int {
+	return len(p)
+}
+
+func (p ErrorList) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+
+func (p ErrorList) Less(i, j int) bool {
+	e := p[i].Pos()
+	f := p[j].Pos()
+
+	if e.Filename != f.Filename {
+		return e.Filename < f.Filename
+	}
+
+	if e.Line != f.Line {
+		return e.Line < f.Line
+	}
+
+	if e.Column != f.Column {
+		return e.Column < f.Column
+	}
+
+	return p[i].Message() < p[j].Message()
+}
+
+// Sort sorts the collection.
+func (p ErrorList) Sort() {
+	sort.Stable(p)
+}
+
+// RemoveDuplicates sorts the collection and removes the errors with the
+// same position and message.
+func (p *ErrorList) RemoveDuplicates() {
+	p.Sort()
+
+	var res ErrorList
+	for i, e := range *p {
+		if i > 0 && e.Pos() == (*p)[i-1].Pos() && e.Message() == (*p)[i-1].Message() {
+			continue
+		}
+		res = append(res, e)
+	}
+
+	*p = res
+}
+
+func (p ErrorList) Error() string {
+	switch len(p) {
+	case 0:
+		return "no errors"
+	case 1:
+		return p[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", p[0], len(p)-1)
+}
+
+// Err returns an error.
+func (p ErrorList) Err() error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	return p
+}
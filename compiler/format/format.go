@@ -0,0 +1,57 @@
+package format
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+)
+
+// Source formats the Tengo source code in the canonical style. The source
+// must be syntactically correct.
+func Source(src []byte) ([]byte, error) {
+	fileSet := source.NewFileSet()
+	srcFile := fileSet.AddFile("(format)", -1, len(src))
+
+	p := parser.NewParserWithMode(srcFile, src, nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	// keep the interpreter line of the executable scripts
+	if bytes.HasPrefix(src, []byte("#!")) {
+		line := src
+		if idx := bytes.IndexByte(src, '\n'); idx >= 0 {
+			line = src[:idx]
+		}
+		buf.Write(bytes.TrimRight(line, " \t\r"))
+		buf.WriteByte('\n')
+	}
+
+	if err := File(&buf, file); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// File writes the canonically formatted source code of the file unit into w.
+// The comments of the file are printed only if the file was parsed with
+// parser.ParseComments mode.
+func File(w io.Writer, file *ast.File) error {
+	p := &printer{
+		file:     file.InputFile,
+		comments: file.Comments,
+	}
+
+	p.stmtList(file.Stmts, file.End())
+
+	_, err := w.Write(p.bytes())
+
+	return err
+}
@@ -0,0 +1,87 @@
+package format_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler/format"
+)
+
+func TestSource(t *testing.T) {
+	expect(t, ``, ``)
+	expect(t, `a:=1`, "a := 1\n")
+	expect(t, `a,b:=1,   "x";c=a+b*-2`, "a, b := 1, \"x\"\nc = a + b * -2\n")
+	expect(t, `x:=[1,2,3];y:={a:1,"b c":2,"if":3};z:={}`, "x := [1, 2, 3]\ny := {a: 1, \"b c\": 2, \"if\": 3}\nz := {}\n")
+	expect(t, "x := [1,\n2]", "x := [\n\t1,\n\t2\n]\n")
+	expect(t, `x++;y--;x+=2;a[1]=b.c[2:];d=a[:3]`, "x++\ny--\nx += 2\na[1] = b.c[2:]\nd = a[:3]\n")
+	expect(t, `f:=func(a,...b){return}`, "f := func(a, ...b) { return }\n")
+	expect(t, "f:=func(){\na:=1;return a}", "f := func() {\n\ta := 1\n\treturn a\n}\n")
+	expect(t, `f:=func(){}`, "f := func() {}\n")
+	expect(t, `x:=a?b:c;y:=!a;z:=(a+b)*c`, "x := a ? b : c\ny := !a\nz := (a + b) * c\n")
+	expect(t, `x:=- -5;y:=+ +a;z:=-+a;w:=!!a;v:=a- -b`, "x := - -5\ny := + +a\nz := -+a\nw := !!a\nv := a - -b\n")
+	expect(t, `x:=- - -a;y:=-(-a)`, "x := - - -a\ny := -(-a)\n")
+	expect(t, `e:=error("x");i:=immutable([1]);m:=import("fmt");u:=undefined`, "e := error(\"x\")\ni := immutable([1])\nm := import(\"fmt\")\nu := undefined\n")
+	expect(t, "if a{b()}else if c{d()}else{\ne()}", "if a { b() } else if c { d() } else {\n\te()\n}\n")
+	expect(t, "if x:=1;x>0{\n}", "if x := 1; x > 0 {}\n")
+	expect(t, "for{break}", "for { break }\n")
+	expect(t, "for a<1{continue}", "for a < 1 { continue }\n")
+	expect(t, "for i:=0;i<1;i++{}", "for i := 0; i < 1; i++ {}\n")
+	expect(t, "for x in y{};for _,v in y{};for k,v in y{}", "for x in y {}\nfor _, v in y {}\nfor k, v in y {}\n")
+	expect(t, "export {a:1}", "export {a: 1}\n")
+	expect(t, `c:='\n';s:=`+"`raw`"+`;f:=1.50`, "c := '\\n'\ns := `raw`\nf := 1.50\n")
+
+	// interpreter line
+	expect(t, "#!/usr/bin/env tengo\na:=1", "#!/usr/bin/env tengo\na := 1\n")
+	expect(t, "#!/usr/bin/env tengo", "#!/usr/bin/env tengo\n")
+
+	// blank lines
+	expect(t, "a := 1\n\n\n\nb := 2\nc := 3", "a := 1\n\nb := 2\nc := 3\n")
+
+	// comments
+	expect(t, "// a\n\n// b\nx := 1 // c\n/* d */", "// a\n\n// b\nx := 1 // c\n/* d */\n")
+	expect(t, "f := func() {\n// a\nx := 1 // b\n\n\n// c\n}", "f := func() {\n\t// a\n\tx := 1 // b\n\n\t// c\n}\n")
+	expect(t, "f := func() { /* a */ }", "f := func() {\n\t/* a */\n}\n")
+	expect(t, "m := {\n// a\na: 1, // b\n\n// c\nc: 2\n// d\n}", "m := {\n\t// a\n\ta: 1, // b\n\n\t// c\n\tc: 2\n\t// d\n}\n")
+	expect(t, "m := {a: 1 /* b */}", "m := {\n\ta: 1 /* b */\n}\n")
+	expect(t, "m := {x: 1, y: 2, // about y\nz: 3}", "m := {\n\tx: 1,\n\ty: 2, // about y\n\tz: 3\n}\n")
+	expect(t, "x := [1, 2, /* before 3 */ 3]", "x := [\n\t1,\n\t2,\n\t/* before 3 */ 3\n]\n")
+	expect(t, "a := 1 /* mid */ + 2", "a := 1 /* mid */ + 2\n")
+	expect(t, "f(1, // first\n 2)", "f(1, // first\n\t2)\n")
+	expect(t, "x := f(1, // a\nfunc() {\ny := 1\nreturn y\n})\nz := 2", "x := f(1, // a\n\tfunc() {\n\t\ty := 1\n\t\treturn y\n\t})\nz := 2\n")
+
+	_, err := format.Source([]byte(`a :=`))
+	assert.Error(t, err)
+}
+
+func TestSourceIdempotent(t *testing.T) {
+	src, err := ioutil.ReadFile("../../stdlib/srcmod_enum.tengo")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	formatted, err := format.Source(src)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	formatted2, err := format.Source(formatted)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, string(formatted), string(formatted2))
+}
+
+func expect(t *testing.T, input, expected string) {
+	actual, err := format.Source([]byte(input))
+	if !assert.NoError(t, err) || !assert.Equal(t, expected, string(actual), "input: %s", input) {
+		return
+	}
+
+	// the output parses, and is formatted the same
+	again, err := format.Source(actual)
+	if assert.NoError(t, err, "output: %s", actual) {
+		assert.Equal(t, expected, string(again), "output: %s", actual)
+	}
+}
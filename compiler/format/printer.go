@@ -0,0 +1,540 @@
+package format
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/compiler/token"
+)
+
+type printer struct {
+	buf         bytes.Buffer
+	file        *source.File
+	comments    []*ast.CommentGroup
+	cindex      int // index of the next comment group to print
+	indent      int
+	atLineStart bool
+	space       bool // print a space before the next text
+	continued   bool // the current statement or element continues on the next line
+}
+
+func (p *printer) bytes() []byte {
+	out := p.buf.Bytes()
+	if len(out) == 0 {
+		return out
+	}
+
+	return append(bytes.TrimRight(out, "\n"), '\n')
+}
+
+func (p *printer) print(s string) {
+	if s == "" {
+		return
+	}
+
+	if p.atLineStart {
+		p.buf.WriteString(strings.Repeat("\t", p.indent))
+		p.atLineStart = false
+	}
+
+	if p.space {
+		p.space = false
+		if strings.IndexByte(" ,:)]}", s[0]) < 0 {
+			p.buf.WriteByte(' ')
+		}
+	}
+
+	p.buf.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+	p.atLineStart = true
+	p.space = false
+}
+
+// endContinuation restores the indentation after a statement or an element
+// that continued on the next lines.
+func (p *printer) endContinuation() {
+	if p.continued {
+		p.indent--
+		p.continued = false
+	}
+}
+
+func (p *printer) line(pos source.Pos) int {
+	if p.file == nil || !pos.IsValid() {
+		return 0
+	}
+
+	return p.file.Line(pos)
+}
+
+// nextComment returns the next comment group to print, or nil if there are
+// no more comments.
+func (p *printer) nextComment() *ast.CommentGroup {
+	if p.cindex < len(p.comments) {
+		return p.comments[p.cindex]
+	}
+
+	return nil
+}
+
+// hasComments returns true if there is a comment group to print before the
+// position end.
+func (p *printer) hasComments(end source.Pos) bool {
+	c := p.nextComment()
+
+	return c != nil && c.Pos() < end
+}
+
+// leadingComments prints the comment groups that appear before the position
+// pos, each on its own lines. prevLine is the source line of the last
+// printed element, or 0 if there was none, and the function returns the
+// updated value.
+func (p *printer) leadingComments(pos source.Pos, prevLine int) int {
+	for p.hasComments(pos) {
+		c := p.nextComment()
+		p.cindex++
+
+		if prevLine > 0 && p.line(c.Pos()) > prevLine+1 {
+			p.newline()
+		}
+
+		for _, comment := range c.List {
+			p.print(comment.Text)
+			p.newline()
+		}
+
+		prevLine = p.line(c.End())
+	}
+
+	return prevLine
+}
+
+// trailingComments prints the comment groups that appear before the
+// position end or on the same line as the position end, and terminates
+// the line. If the position next of the following element is on the same
+// line, the comments before it are left to be printed with it. It returns
+// the source line of the last printed element.
+func (p *printer) trailingComments(end, next source.Pos) int {
+	endLine := p.line(end - 1)
+	nextOnLine := next.IsValid() && p.line(next) == endLine
+
+	for {
+		c := p.nextComment()
+		if c == nil || (c.Pos() >= end && (nextOnLine || p.line(c.Pos()) != endLine)) {
+			break
+		}
+		p.cindex++
+
+		for i, comment := range c.List {
+			if i > 0 {
+				p.newline()
+			} else {
+				p.print(" ")
+			}
+			p.print(comment.Text)
+		}
+
+		if last := c.List[len(c.List)-1]; last.Text[1] == '/' {
+			// //-style comment terminates the line
+			p.newline()
+		}
+
+		endLine = p.line(c.End())
+	}
+
+	if !p.atLineStart {
+		p.newline()
+	}
+
+	return endLine
+}
+
+// inlineComments prints the comment groups that appear before the position
+// pos inside a statement or an element, where they are. A //-style comment
+// terminates the line, and the rest is indented on the next line.
+func (p *printer) inlineComments(pos source.Pos) {
+	for p.hasComments(pos) {
+		c := p.nextComment()
+		p.cindex++
+
+		for _, comment := range c.List {
+			if !p.atLineStart && p.buf.Len() > 0 && strings.IndexByte(" ([", p.buf.Bytes()[p.buf.Len()-1]) < 0 {
+				p.space = true
+			}
+			p.print(comment.Text)
+
+			if comment.Text[1] == '/' {
+				p.newline()
+				if !p.continued {
+					p.indent++
+					p.continued = true
+				}
+			} else {
+				p.space = true
+			}
+		}
+	}
+}
+
+func (p *printer) stmtList(stmts []ast.Stmt, end source.Pos) {
+	continued := p.continued
+	p.continued = false
+	defer func() { p.continued = continued }()
+
+	prevLine := 0
+	for _, s := range stmts {
+		if _, isEmpty := s.(*ast.EmptyStmt); isEmpty {
+			continue
+		}
+
+		prevLine = p.leadingComments(s.Pos(), prevLine)
+		if prevLine > 0 && p.line(s.Pos()) > prevLine+1 {
+			p.newline()
+		}
+
+		p.stmt(s)
+		prevLine = p.trailingComments(s.End(), source.NoPos)
+		p.endContinuation()
+	}
+
+	p.leadingComments(end, prevLine)
+}
+
+func (p *printer) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.ExprStmt:
+		p.expr(s.Expr)
+	case *ast.AssignStmt:
+		p.exprList(s.LHS)
+		p.print(" " + s.Token.String() + " ")
+		p.exprList(s.RHS)
+	case *ast.IncDecStmt:
+		p.expr(s.Expr)
+		p.print(s.Token.String())
+	case *ast.ReturnStmt:
+		p.print("return")
+		if s.Result != nil {
+			p.print(" ")
+			p.expr(s.Result)
+		}
+	case *ast.ExportStmt:
+		p.print("export ")
+		p.expr(s.Result)
+	case *ast.BranchStmt:
+		p.print(s.Token.String())
+		if s.Label != nil {
+			p.print(" " + s.Label.Name)
+		}
+	case *ast.BlockStmt:
+		p.block(s)
+	case *ast.IfStmt:
+		p.print("if ")
+		if s.Init != nil {
+			p.stmt(s.Init)
+			p.print("; ")
+		}
+		p.expr(s.Cond)
+		p.print(" ")
+		p.block(s.Body)
+		if s.Else != nil {
+			p.print(" else ")
+			p.stmt(s.Else)
+		}
+	case *ast.ForStmt:
+		p.print("for ")
+		if s.Init != nil || s.Post != nil {
+			if s.Init != nil {
+				p.stmt(s.Init)
+			}
+			p.print("; ")
+			if s.Cond != nil {
+				p.expr(s.Cond)
+			}
+			p.print("; ")
+			if s.Post != nil {
+				p.stmt(s.Post)
+			}
+			p.print(" ")
+		} else if s.Cond != nil {
+			p.expr(s.Cond)
+			p.print(" ")
+		}
+		p.block(s.Body)
+	case *ast.ForInStmt:
+		p.print("for ")
+		if s.Key.Name != "_" || s.Key.NamePos != s.Value.NamePos {
+			p.print(s.Key.Name + ", ")
+		}
+		p.print(s.Value.Name + " in ")
+		p.expr(s.Iterable)
+		p.print(" ")
+		p.block(s.Body)
+	default:
+		p.print(s.String())
+	}
+}
+
+// block prints the block statement. A block that fits on a single line in
+// the source is kept on a single line if it has at most one statement.
+func (p *printer) block(b *ast.BlockStmt) {
+	var stmts []ast.Stmt
+	for _, s := range b.Stmts {
+		if _, isEmpty := s.(*ast.EmptyStmt); !isEmpty {
+			stmts = append(stmts, s)
+		}
+	}
+
+	if !p.hasComments(b.RBrace) {
+		if len(stmts) == 0 {
+			p.print("{}")
+			return
+		}
+
+		if len(stmts) == 1 && p.line(b.LBrace) == p.line(b.RBrace) {
+			p.print("{ ")
+			p.stmt(stmts[0])
+			p.print(" }")
+			return
+		}
+	}
+
+	p.print("{")
+	p.newline()
+	p.indent++
+	p.stmtList(stmts, b.RBrace)
+	p.indent--
+	p.print("}")
+}
+
+func (p *printer) exprList(list []ast.Expr) {
+	for i, e := range list {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.expr(e)
+	}
+}
+
+func (p *printer) expr(e ast.Expr) {
+	p.inlineComments(e.Pos())
+
+	switch e := e.(type) {
+	case *ast.Ident:
+		p.print(e.Name)
+	case *ast.IntLit:
+		p.print(literal(e.Literal, strconv.FormatInt(e.Value, 10)))
+	case *ast.FloatLit:
+		p.print(literal(e.Literal, strconv.FormatFloat(e.Value, 'g', -1, 64)))
+	case *ast.CharLit:
+		p.print(literal(e.Literal, strconv.QuoteRune(e.Value)))
+	case *ast.StringLit:
+		p.print(literal(e.Literal, strconv.Quote(e.Value)))
+	case *ast.BoolLit:
+		p.print(strconv.FormatBool(e.Value))
+	case *ast.UndefinedLit:
+		p.print("undefined")
+	case *ast.BinaryExpr:
+		p.expr(e.LHS)
+		p.inlineComments(e.TokenPos)
+		p.print(" " + e.Token.String() + " ")
+		p.expr(e.RHS)
+	case *ast.UnaryExpr:
+		p.print(e.Token.String())
+		// "- -x" and "+ +x" must not be printed as "--x" and "++x"
+		if inner, ok := e.Expr.(*ast.UnaryExpr); ok && inner.Token == e.Token &&
+			(e.Token == token.Sub || e.Token == token.Add) {
+			p.print(" ")
+		}
+		p.expr(e.Expr)
+	case *ast.ParenExpr:
+		p.print("(")
+		p.expr(e.Expr)
+		p.print(")")
+	case *ast.CondExpr:
+		p.expr(e.Cond)
+		p.print(" ? ")
+		p.expr(e.True)
+		p.print(" : ")
+		p.expr(e.False)
+	case *ast.CallExpr:
+		p.expr(e.Func)
+		p.print("(")
+		p.exprList(e.Args)
+		p.print(")")
+	case *ast.SelectorExpr:
+		p.expr(e.Expr)
+		if sel, ok := e.Sel.(*ast.StringLit); ok && isIdent(sel.Value) {
+			p.print("." + sel.Value)
+		} else {
+			p.print("[")
+			p.expr(e.Sel)
+			p.print("]")
+		}
+	case *ast.IndexExpr:
+		p.expr(e.Expr)
+		p.print("[")
+		p.expr(e.Index)
+		p.print("]")
+	case *ast.SliceExpr:
+		p.expr(e.Expr)
+		p.print("[")
+		if e.Low != nil {
+			p.expr(e.Low)
+		}
+		p.print(":")
+		if e.High != nil {
+			p.expr(e.High)
+		}
+		p.print("]")
+	case *ast.FuncLit:
+		p.print("func(")
+		for i, param := range e.Type.Params.List {
+			if i > 0 {
+				p.print(", ")
+			}
+			if e.Type.Params.VarArgs && i == len(e.Type.Params.List)-1 {
+				p.print("...")
+			}
+			p.print(param.Name)
+		}
+		p.print(") ")
+		p.block(e.Body)
+	case *ast.ArrayLit:
+		elements := make([]ast.Node, len(e.Elements))
+		for i, elem := range e.Elements {
+			elements[i] = elem
+		}
+		p.elementList("[", "]", elements, e.LBrack, e.RBrack)
+	case *ast.MapLit:
+		elements := make([]ast.Node, len(e.Elements))
+		for i, elem := range e.Elements {
+			elements[i] = elem
+		}
+		p.elementList("{", "}", elements, e.LBrace, e.RBrace)
+	case *ast.ErrorExpr:
+		p.print("error(")
+		p.expr(e.Expr)
+		p.print(")")
+	case *ast.ImmutableExpr:
+		p.print("immutable(")
+		p.expr(e.Expr)
+		p.print(")")
+	case *ast.ImportExpr:
+		p.print("import(" + strconv.Quote(e.ModuleName) + ")")
+	default:
+		p.print(e.String())
+	}
+}
+
+// elementList prints the elements of an array or map literal. The elements
+// are printed on separate lines if the literal spans multiple lines in the
+// source.
+func (p *printer) elementList(open, close string, elements []ast.Node, lpos, rpos source.Pos) {
+	p.print(open)
+
+	if len(elements) == 0 && !p.hasComments(rpos) {
+		p.print(close)
+		return
+	}
+
+	if p.line(lpos) == p.line(rpos) && !p.hasComments(rpos) {
+		for i, elem := range elements {
+			if i > 0 {
+				p.print(", ")
+			}
+			p.element(elem)
+		}
+		p.print(close)
+		return
+	}
+
+	p.newline()
+	p.indent++
+
+	continued := p.continued
+	p.continued = false
+
+	prevLine := p.line(lpos)
+	for i, elem := range elements {
+		// the comments on the line of the element are printed with it
+		line, start := p.line(elem.Pos()), elem.Pos()
+		if line > 0 {
+			start = p.file.LineStart(line)
+		}
+		prevLine = p.leadingComments(start, prevLine)
+		if line > prevLine+1 && i > 0 {
+			p.newline()
+		}
+
+		p.element(elem)
+		next := source.NoPos
+		if i < len(elements)-1 {
+			p.print(",")
+			next = elements[i+1].Pos()
+		}
+		prevLine = p.trailingComments(elem.End(), next)
+		p.endContinuation()
+	}
+	p.leadingComments(rpos, prevLine)
+
+	p.continued = continued
+	p.indent--
+	p.print(close)
+}
+
+func (p *printer) element(n ast.Node) {
+	p.inlineComments(n.Pos())
+
+	switch n := n.(type) {
+	case *ast.MapElementLit:
+		if isIdent(n.Key) {
+			p.print(n.Key)
+		} else {
+			p.print(strconv.Quote(n.Key))
+		}
+		p.print(": ")
+		p.expr(n.Value)
+	case ast.Expr:
+		p.expr(n)
+	}
+}
+
+// literal returns the source literal if it is present, and the canonical
+// representation otherwise.
+func literal(lit, canonical string) string {
+	if lit != "" {
+		return lit
+	}
+
+	return canonical
+}
+
+// isIdent returns true if s can be used as an identifier or a selector.
+func isIdent(s string) bool {
+	if s == "" || token.Lookup(s) != token.Ident {
+		return false
+	}
+
+	for i, ch := range s {
+		if !isLetter(ch) && (i == 0 || !isDigit(ch)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch >= 0x80 && unicode.IsLetter(ch)
+}
+
+func isDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9' || ch >= 0x80 && unicode.IsDigit(ch)
+}
@@ -0,0 +1,285 @@
+package compiler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/objects"
+)
+
+// kinds of the module dependencies
+const (
+	builtinDependency  = "builtin"
+	sourceDependency   = "source"
+	compiledDependency = "compiled"
+	fileDependency     = "file"
+)
+
+// moduleCacheFileExt is the extension of the module cache files.
+const moduleCacheFileExt = ".tengoc"
+
+// ModuleCache is a cache of the compiled source modules, both from the
+// module map and from the files, that can be shared by the compilers of
+// multiple scripts. The modules are keyed by their module paths (the module
+// names or the absolute file paths), and a cached module is compiled again
+// if its source or the source of a module it imports has changed, or if a
+// precompiled module it imports is replaced.
+//
+// ModuleCache is safe for concurrent use.
+type ModuleCache struct {
+	mu      sync.RWMutex
+	entries map[string]*moduleCacheEntry
+	dir     string
+}
+
+// moduleCacheEntry is a compiled module in the module cache.
+type moduleCacheEntry struct {
+	// Hash is the hash of the module source.
+	Hash         string
+	Module       *CompiledModule
+	Dependencies []*moduleDependency
+	Warnings     []*moduleWarning
+}
+
+// moduleDependency is a module imported while compiling a module.
+type moduleDependency struct {
+	Kind string
+
+	// Name is the name in the import expression.
+	Name string
+
+	// File and Path are the file name with the extension and the absolute
+	// path of the file modules.
+	File string
+	Path string
+
+	// Hash is the hash of the source modules and the file modules, and the
+	// fingerprint of the compiled modules.
+	Hash string
+}
+
+// moduleWarning is a warning in the compiled module, positioned in the file
+// set of the module.
+type moduleWarning struct {
+	Pos     source.Pos
+	Message string
+}
+
+// NewModuleCache creates an in-memory ModuleCache.
+func NewModuleCache() *ModuleCache {
+	return &ModuleCache{
+		entries: make(map[string]*moduleCacheEntry),
+	}
+}
+
+// NewFileModuleCache creates a ModuleCache that also stores the compiled
+// modules in the files in dir, so that they can be reused by other
+// processes. The directory is created if it does not exist.
+func NewFileModuleCache(dir string) (*ModuleCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	mc := NewModuleCache()
+	mc.dir = dir
+
+	return mc, nil
+}
+
+// Len returns the number of the modules in memory.
+func (mc *ModuleCache) Len() int {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+
+	return len(mc.entries)
+}
+
+// Clear removes all the modules from the memory and the cache directory.
+func (mc *ModuleCache) Clear() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.entries = make(map[string]*moduleCacheEntry)
+
+	if mc.dir == "" {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(mc.dir, "*"+moduleCacheFileExt))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// get returns the cached module of the module path if the hash of its
+// source matches.
+func (mc *ModuleCache) get(modulePath, hash string) *moduleCacheEntry {
+	mc.mu.RLock()
+	entry := mc.entries[modulePath]
+	mc.mu.RUnlock()
+
+	if entry == nil && mc.dir != "" {
+		entry = mc.readFile(modulePath)
+		if entry != nil {
+			mc.mu.Lock()
+			mc.entries[modulePath] = entry
+			mc.mu.Unlock()
+		}
+	}
+
+	if entry == nil || entry.Hash != hash {
+		return nil
+	}
+
+	return entry
+}
+
+// put stores the compiled module, replacing the previous one of the module
+// path.
+func (mc *ModuleCache) put(modulePath string, entry *moduleCacheEntry) {
+	mc.mu.Lock()
+	mc.entries[modulePath] = entry
+	mc.mu.Unlock()
+
+	if mc.dir != "" {
+		// the cache files are only an optimization
+		_ = mc.writeFile(modulePath, entry)
+	}
+}
+
+func (mc *ModuleCache) filename(modulePath string) string {
+	return filepath.Join(mc.dir, hashSource([]byte(modulePath))+moduleCacheFileExt)
+}
+
+func (mc *ModuleCache) readFile(modulePath string) *moduleCacheEntry {
+	data, err := ioutil.ReadFile(mc.filename(modulePath))
+	if err != nil {
+		return nil
+	}
+
+	entry := &moduleCacheEntry{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(entry); err != nil {
+		return nil
+	}
+
+	for i, v := range entry.Module.Constants {
+		// the builtin modules are replaced when the module is imported
+		if _, ok := v.(*objects.ImmutableMap); ok {
+			continue
+		}
+
+		fv, err := fixDecoded(v, objects.NewModuleMap())
+		if err != nil {
+			return nil
+		}
+		entry.Module.Constants[i] = fv
+	}
+
+	return entry
+}
+
+func (mc *ModuleCache) writeFile(modulePath string, entry *moduleCacheEntry) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return err
+	}
+
+	// write into a temporary file and rename it, so that the other processes
+	// do not read the incomplete file.
+	f, err := ioutil.TempFile(mc.dir, "tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), mc.filename(modulePath))
+}
+
+// addDependency records the module imported by the compiler or its module
+// compilers.
+func (c *Compiler) addDependency(dep *moduleDependency) {
+	if c.parent != nil {
+		c.parent.addDependency(dep)
+		return
+	}
+
+	for _, d := range c.dependencies {
+		if d.Kind == dep.Kind && d.Name == dep.Name {
+			return
+		}
+	}
+
+	c.dependencies = append(c.dependencies, dep)
+}
+
+// validDependencies returns true if the dependencies of a cached module
+// resolve to the same modules for the compiler.
+func (c *Compiler) validDependencies(deps []*moduleDependency) bool {
+	for _, dep := range deps {
+		mod := c.modules.Get(dep.Name)
+
+		switch dep.Kind {
+		case builtinDependency:
+			if c.modules.GetBuiltinModule(dep.Name) == nil {
+				return false
+			}
+		case sourceDependency:
+			src := c.modules.GetSourceModule(dep.Name)
+			if src == nil || hashSource(src.Src) != dep.Hash {
+				return false
+			}
+		case compiledDependency:
+			compiled, ok := mod.(*CompiledModule)
+			if !ok || compiled.fingerprint() != dep.Hash {
+				return false
+			}
+		case fileDependency:
+			if mod != nil || !c.allowFileImport {
+				return false
+			}
+
+			if path, err := filepath.Abs(dep.File); err != nil || path != dep.Path {
+				return false
+			}
+
+			src, err := ioutil.ReadFile(dep.File)
+			if err != nil || hashSource(src) != dep.Hash {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// hashSource returns the SHA-256 hash of the source in hexadecimal.
+func hashSource(src []byte) string {
+	hash := sha256.Sum256(src)
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,166 @@
+package compiler_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestModuleCache(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "tengo_module_cache")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(tempDir))
+	defer func() { _ = os.Chdir(wd) }()
+
+	assert.NoError(t, ioutil.WriteFile("a.tengo", []byte(`
+b := import("./b")
+text := import("text")
+export func() {
+	unused := 1
+	return text.join(["a", b], "")
+}`), 0644))
+	assert.NoError(t, ioutil.WriteFile("b.tengo", []byte(`export "b"`), 0644))
+
+	modules := stdlib.GetModuleMap("text")
+	cache := compiler.NewModuleCache()
+	input := `out := import("./a")()`
+
+	bytecode, warnings := compileWithCache(t, input, modules, cache)
+	assert.Equal(t, `"ab"`, runBundleProgram(t, bytecode))
+	assert.Equal(t, 2, cache.Len()) // a.tengo and b.tengo
+	assert.Equal(t, 1, len(warnings))
+
+	// the cached module, the warnings and the source positions are reused
+	bytecode, warnings = compileWithCache(t, input, modules, cache)
+	assert.Equal(t, `"ab"`, runBundleProgram(t, bytecode))
+	assert.Equal(t, 1, len(warnings))
+	assert.Equal(t, "Compile Warning: 'unused' declared and not used\n\tat ./a.tengo:5:2", warnings[0].String())
+
+	// a.tengo is compiled again if the module it imports has changed
+	assert.NoError(t, ioutil.WriteFile("b.tengo", []byte(`export "c"`), 0644))
+	bytecode, _ = compileWithCache(t, input, modules, cache)
+	assert.Equal(t, `"ac"`, runBundleProgram(t, bytecode))
+
+	// the builtin module must be in the module map
+	_, err = compileWithCacheErr(input, objects.NewModuleMap(), cache)
+	assert.Error(t, err)
+
+	// the modules in the cache directory are reused by another cache
+	cacheDir := filepath.Join(tempDir, "cache")
+	fileCache, err := compiler.NewFileModuleCache(cacheDir)
+	assert.NoError(t, err)
+	compileWithCache(t, input, modules, fileCache)
+
+	files, err := ioutil.ReadDir(cacheDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(files))
+
+	fileCache, err = compiler.NewFileModuleCache(cacheDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, fileCache.Len())
+	bytecode, warnings = compileWithCache(t, input, modules, fileCache)
+	assert.Equal(t, `"ac"`, runBundleProgram(t, bytecode))
+	assert.Equal(t, 1, len(warnings))
+	assert.Equal(t, 1, fileCache.Len()) // b.tengo is compiled into a.tengo
+
+	assert.NoError(t, fileCache.Clear())
+	assert.Equal(t, 0, fileCache.Len())
+	files, err = ioutil.ReadDir(cacheDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(files))
+
+	// cyclic imports are detected across the cached modules
+	assert.NoError(t, ioutil.WriteFile("b.tengo", []byte(`export import("./a")`), 0644))
+	_, err = compileWithCacheErr(input, modules, cache)
+	assert.True(t, err != nil && strings.Contains(err.Error(), "cyclic module import"), err)
+}
+
+func TestModuleCache_CompiledModule(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "tengo_module_cache")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(tempDir))
+	defer func() { _ = os.Chdir(wd) }()
+
+	assert.NoError(t, ioutil.WriteFile("a.tengo", []byte(`
+lib := import("lib")
+export func() { return "a" + lib }`), 0644))
+
+	compiledModules := func(src string) *objects.ModuleMap {
+		b, err := compiler.NewModuleBundle(nil, []*compiler.ModuleSource{
+			{Name: "lib", Filename: "lib.tengo", Src: []byte(src)},
+		})
+		assert.NoError(t, err)
+		return b.ModuleMap()
+	}
+
+	cache := compiler.NewModuleCache()
+	input := `out := import("./a")()`
+
+	bytecode, _ := compileWithCache(t, input, compiledModules(`export "b"`), cache)
+	assert.Equal(t, `"ab"`, runBundleProgram(t, bytecode))
+
+	// the same compiled module: the cached module is reused
+	bytecode, _ = compileWithCache(t, input, compiledModules(`export "b"`), cache)
+	assert.Equal(t, `"ab"`, runBundleProgram(t, bytecode))
+
+	// a.tengo is compiled again if the compiled module is replaced
+	bytecode, _ = compileWithCache(t, input, compiledModules(`export "c"`), cache)
+	assert.Equal(t, `"ac"`, runBundleProgram(t, bytecode))
+}
+
+func compileWithCache(t *testing.T, input string, modules *objects.ModuleMap, cache *compiler.ModuleCache) (*compiler.Bytecode, []*compiler.Warning) {
+	fileSet := source.NewFileSet()
+	file := fileSet.AddFile("test", -1, len(input))
+
+	parsed, err := parser.NewParser(file, []byte(input), nil).ParseFile()
+	assert.NoError(t, err)
+
+	c := compiler.NewCompiler(file, nil, nil, modules, nil)
+	c.EnableFileImport(true)
+	c.SetModuleCache(cache)
+	assert.NoError(t, c.Compile(parsed))
+
+	return c.Bytecode(), c.Warnings()
+}
+
+func compileWithCacheErr(input string, modules *objects.ModuleMap, cache *compiler.ModuleCache) (*compiler.Bytecode, error) {
+	fileSet := source.NewFileSet()
+	file := fileSet.AddFile("test", -1, len(input))
+
+	parsed, err := parser.NewParser(file, []byte(input), nil).ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	c := compiler.NewCompiler(file, nil, nil, modules, nil)
+	c.EnableFileImport(true)
+	c.SetModuleCache(cache)
+	if err := c.Compile(parsed); err != nil {
+		return nil, err
+	}
+
+	// the module functions are run to check the imported modules
+	return c.Bytecode(), runtime.NewVM(c.Bytecode(), nil, -1).Run()
+}
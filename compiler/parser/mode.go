@@ -0,0 +1,9 @@
+package parser
+
+// Mode represents a parser mode.
+type Mode int
+
+// List of parser modes.
+const (
+	ParseComments Mode = 1 << iota // parse comments and add them to AST
+)
@@ -25,31 +25,46 @@ type bailout struct{}
 
 // Parser parses the Tengo source files.
 type Parser struct {
-	file      *source.File
-	errors    ErrorList
-	scanner   *scanner.Scanner
-	pos       source.Pos
-	token     token.Token
-	tokenLit  string
-	exprLevel int        // < 0: in control clause, >= 0: in expression
-	syncPos   source.Pos // last sync position
-	syncCount int        // number of advance calls without progress
-	trace     bool
-	indent    int
-	traceOut  io.Writer
+	file        *source.File
+	errors      ErrorList
+	scanner     *scanner.Scanner
+	pos         source.Pos
+	token       token.Token
+	tokenLit    string
+	exprLevel   int        // < 0: in control clause, >= 0: in expression
+	syncPos     source.Pos // last sync position
+	syncCount   int        // number of advance calls without progress
+	trace       bool
+	indent      int
+	traceOut    io.Writer
+	mode        Mode
+	comments    []*ast.CommentGroup
+	leadComment *ast.CommentGroup // last lead comment
+	lineComment *ast.CommentGroup // last line comment
 }
 
 // NewParser creates a Parser.
 func NewParser(file *source.File, src []byte, trace io.Writer) *Parser {
+	return NewParserWithMode(file, src, trace, 0)
+}
+
+// NewParserWithMode creates a Parser with the parser mode flags.
+func NewParserWithMode(file *source.File, src []byte, trace io.Writer, mode Mode) *Parser {
 	p := &Parser{
 		file:     file,
 		trace:    trace != nil,
 		traceOut: trace,
+		mode:     mode,
+	}
+
+	var scanMode scanner.Mode
+	if mode&ParseComments != 0 {
+		scanMode = scanner.ScanComments
 	}
 
 	p.scanner = scanner.NewScanner(p.file, src, func(pos source.FilePos, msg string) {
 		p.errors.Add(pos, msg)
-	}, 0)
+	}, scanMode)
 
 	p.next()
 
@@ -85,6 +100,7 @@ func (p *Parser) ParseFile() (file *ast.File, err error) {
 	file = &ast.File{
 		InputFile: p.file,
 		Stmts:     stmts,
+		Comments:  p.comments,
 	}
 
 	return
@@ -643,6 +659,11 @@ func (p *Parser) parseStmt() (stmt ast.Stmt) {
 		defer un(trace(p, "Statement"))
 	}
 
+	doc := p.leadComment
+	defer func() {
+		setStmtComments(stmt, doc, p.lineComment)
+	}()
+
 	switch p.token {
 	case // simple statements
 		token.Func, token.Error, token.Immutable, token.Ident, token.Int, token.Float, token.Char, token.String, token.True, token.False,
@@ -676,6 +697,31 @@ func (p *Parser) parseStmt() (stmt ast.Stmt) {
 	}
 }
 
+// setStmtComments associates the leading and line comment groups with the
+// statement.
+func setStmtComments(stmt ast.Stmt, doc, comment *ast.CommentGroup) {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		s.Doc, s.Comment = doc, comment
+	case *ast.BranchStmt:
+		s.Doc, s.Comment = doc, comment
+	case *ast.ExportStmt:
+		s.Doc, s.Comment = doc, comment
+	case *ast.ExprStmt:
+		s.Doc, s.Comment = doc, comment
+	case *ast.ForInStmt:
+		s.Doc, s.Comment = doc, comment
+	case *ast.ForStmt:
+		s.Doc, s.Comment = doc, comment
+	case *ast.IfStmt:
+		s.Doc, s.Comment = doc, comment
+	case *ast.IncDecStmt:
+		s.Doc, s.Comment = doc, comment
+	case *ast.ReturnStmt:
+		s.Doc, s.Comment = doc, comment
+	}
+}
+
 func (p *Parser) parseForStmt() ast.Stmt {
 	if p.trace {
 		defer un(trace(p, "ForStmt"))
@@ -1027,6 +1073,7 @@ func (p *Parser) parseMapElementLit() *ast.MapElementLit {
 		defer un(trace(p, "MapElementLit"))
 	}
 
+	doc := p.leadComment
 	pos := p.pos
 	name := "_"
 
@@ -1049,6 +1096,7 @@ func (p *Parser) parseMapElementLit() *ast.MapElementLit {
 		KeyPos:   pos,
 		ColonPos: colonPos,
 		Value:    valueExpr,
+		Doc:      doc,
 	}
 }
 
@@ -1062,9 +1110,13 @@ func (p *Parser) parseMapLit() *ast.MapLit {
 
 	var elements []*ast.MapElementLit
 	for p.token != token.RBrace && p.token != token.EOF {
-		elements = append(elements, p.parseMapElementLit())
+		element := p.parseMapElementLit()
+		elements = append(elements, element)
+
+		more := p.expectComma(token.RBrace, "map element")
+		element.Comment = p.lineComment
 
-		if !p.expectComma(token.RBrace, "map element") {
+		if !more {
 			break
 		}
 	}
@@ -1171,9 +1223,79 @@ func (p *Parser) next() {
 		}
 	}
 
+	p.leadComment = nil
+	p.lineComment = nil
+	prev := p.pos
+	p.next0()
+
+	if p.token == token.Comment {
+		var comment *ast.CommentGroup
+		var endline int
+
+		if p.file.Line(p.pos) == p.file.Line(prev) {
+			// The comment is on same line as the previous token; it
+			// cannot be a lead comment but may be a line comment.
+			comment, endline = p.consumeCommentGroup(0)
+			if p.file.Line(p.pos) != endline || p.token == token.EOF {
+				// The next token is on a different line, thus
+				// the last comment group is a line comment.
+				p.lineComment = comment
+			}
+		}
+
+		// consume successor comments, if any
+		endline = -1
+		for p.token == token.Comment {
+			comment, endline = p.consumeCommentGroup(1)
+		}
+
+		if endline+1 == p.file.Line(p.pos) {
+			// The next token is following on the line immediately after the
+			// comment group, thus the last comment group is a lead comment.
+			p.leadComment = comment
+		}
+	}
+}
+
+func (p *Parser) next0() {
 	p.token, p.tokenLit, p.pos = p.scanner.Scan()
 }
 
+func (p *Parser) consumeComment() (comment *ast.Comment, endline int) {
+	// /*-style comments may end on a different line than where they start.
+	endline = p.file.Line(p.pos)
+	if p.tokenLit[1] == '*' {
+		// don't use range here - no need to decode Unicode code points
+		for i := 0; i < len(p.tokenLit); i++ {
+			if p.tokenLit[i] == '\n' {
+				endline++
+			}
+		}
+	}
+
+	comment = &ast.Comment{Slash: p.pos, Text: p.tokenLit}
+	p.next0()
+
+	return
+}
+
+// consumeCommentGroup consumes a group of adjacent comments and adds it to
+// the comment list. A non-comment token or n empty lines terminate the group.
+func (p *Parser) consumeCommentGroup(n int) (comments *ast.CommentGroup, endline int) {
+	var list []*ast.Comment
+	endline = p.file.Line(p.pos)
+	for p.token == token.Comment && p.file.Line(p.pos) <= endline+n {
+		var comment *ast.Comment
+		comment, endline = p.consumeComment()
+		list = append(list, comment)
+	}
+
+	comments = &ast.CommentGroup{List: list}
+	p.comments = append(p.comments, comments)
+
+	return
+}
+
 func (p *Parser) printTrace(a ...interface{}) {
 	const (
 		dots = ". . . . . . . . . . . . . . . . . . . . . . . . . . . . . . . . "
@@ -0,0 +1,136 @@
+package parser_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+)
+
+func TestComments(t *testing.T) {
+	file := parseComments(t, `
+// a is
+// one
+a := 1 // one
+
+/* b */
+b := 2
+
+// not a doc
+
+c := a /* inline */ + b
+if a { // if
+	// inner
+	a++ // inc
+} // end if
+return
+// trailing
+`)
+	if file == nil {
+		return
+	}
+
+	assert.Equal(t, 10, len(file.Comments))
+	assert.Equal(t, "// a is\n// one", file.Comments[0].String())
+	assert.Equal(t, "a is\none\n", file.Comments[0].Text())
+	assert.Equal(t, " b\n", file.Comments[2].Text())
+	assert.Equal(t, "", (*ast.CommentGroup)(nil).Text())
+
+	a := file.Stmts[0].(*ast.AssignStmt)
+	assert.True(t, file.Comments[0] == a.Doc)
+	assert.True(t, file.Comments[1] == a.Comment)
+
+	b := file.Stmts[1].(*ast.AssignStmt)
+	assert.True(t, file.Comments[2] == b.Doc)
+	assert.Nil(t, b.Comment)
+
+	c := file.Stmts[2].(*ast.AssignStmt)
+	assert.Nil(t, c.Doc)
+	assert.Nil(t, c.Comment)
+
+	i := file.Stmts[3].(*ast.IfStmt)
+	assert.Nil(t, i.Doc)
+	assert.True(t, file.Comments[8] == i.Comment)
+	inc := i.Body.Stmts[0].(*ast.IncDecStmt)
+	assert.Equal(t, "// inner", inc.Doc.String())
+	assert.Equal(t, "// inc", inc.Comment.String())
+
+	r := file.Stmts[4].(*ast.ReturnStmt)
+	assert.Nil(t, r.Doc)
+	assert.Nil(t, r.Comment)
+}
+
+func TestMapElementComments(t *testing.T) {
+	file := parseComments(t, `
+export {
+	// a is
+	// one
+	a: 1, // one
+	b: 2,
+
+	// c is three
+	c: 3 // three
+}
+`)
+	if file == nil {
+		return
+	}
+
+	m := file.Stmts[0].(*ast.ExportStmt).Result.(*ast.MapLit)
+	assert.Equal(t, 3, len(m.Elements))
+	assert.Equal(t, "a is\none\n", m.Elements[0].Doc.Text())
+	assert.Equal(t, "// one", m.Elements[0].Comment.String())
+	assert.Nil(t, m.Elements[1].Doc)
+	assert.Nil(t, m.Elements[1].Comment)
+	assert.Equal(t, "c is three\n", m.Elements[2].Doc.Text())
+	assert.Equal(t, "// three", m.Elements[2].Comment.String())
+}
+
+func TestCommentsNotParsed(t *testing.T) {
+	testFileSet := source.NewFileSet()
+	src := []byte("// a\na := 1 // b")
+	testFile := testFileSet.AddFile("test", -1, len(src))
+
+	file, err := parser.NewParser(testFile, src, nil).ParseFile()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 0, len(file.Comments))
+	assert.Nil(t, file.Stmts[0].(*ast.AssignStmt).Doc)
+	assert.Nil(t, file.Stmts[0].(*ast.AssignStmt).Comment)
+}
+
+func parseComments(t *testing.T, input string) *ast.File {
+	testFileSet := source.NewFileSet()
+	testFile := testFileSet.AddFile("test", -1, len(input))
+
+	p := parser.NewParserWithMode(testFile, []byte(input), nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	return file
+}
+
+func TestSourceModuleDocs(t *testing.T) {
+	src, err := ioutil.ReadFile("../../stdlib/srcmod_enum.tengo")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	file := parseComments(t, string(src))
+	if file == nil {
+		return
+	}
+
+	export := file.Stmts[len(file.Stmts)-1].(*ast.ExportStmt)
+	for _, elem := range export.Result.(*ast.MapLit).Elements {
+		assert.True(t, strings.HasPrefix(elem.Doc.Text(), elem.Key+" "), elem.Key)
+	}
+}
@@ -55,6 +55,14 @@ func NewScanner(file *source.File, src []byte, errorHandler ErrorHandler, mode M
 		s.next() // ignore BOM at file beginning
 	}
 
+	// ignore the interpreter line of the executable scripts, e.g.
+	// "#!/usr/bin/env tengo"
+	if s.ch == '#' && s.peek() == '!' {
+		for s.ch != '\n' && s.ch >= 0 {
+			s.next()
+		}
+	}
+
 	return s
 }
 
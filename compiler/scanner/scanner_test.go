@@ -191,6 +191,19 @@ func TestScanner_Scan(t *testing.T) {
 	scanExpect(t, strings.Join(lines, "\n"), scanner.DontInsertSemis, expectedSkipComments...)
 }
 
+func TestScanner_Shebang(t *testing.T) {
+	scanExpect(t, "#!/usr/bin/env tengo\na", scanner.DontInsertSemis,
+		scanResult{token.Ident, "a", 2, 1})
+	scanExpect(t, "#!/usr/bin/env tengo", scanner.DontInsertSemis)
+
+	// only at the beginning of the file
+	testFile := testFileSet.AddFile("test", -1, len("a\n#!"))
+	s := scanner.NewScanner(testFile, []byte("a\n#!"), nil, scanner.DontInsertSemis)
+	_, _, _ = s.Scan()
+	tok, _, _ := s.Scan()
+	assert.Equal(t, token.Illegal, tok)
+}
+
 func TestStripCR(t *testing.T) {
 	for _, tc := range []struct {
 		input  string
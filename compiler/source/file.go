@@ -75,6 +75,11 @@ func (f *File) Position(p Pos) (pos FilePos) {
 	return
 }
 
+// Line returns the line number for the given file set position.
+func (f *File) Line(p Pos) int {
+	return f.Position(p).Line
+}
+
 func (f *File) position(p Pos) (pos FilePos) {
 	offset := int(p) - f.Base
 	pos.Offset = offset
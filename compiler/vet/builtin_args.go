@@ -0,0 +1,52 @@
+package vet
+
+import "strconv"
+
+// arity represents the number of arguments accepted by a builtin function.
+// The max is -1 if the function is variadic.
+type arity struct {
+	min int
+	max int
+}
+
+func (a arity) String() string {
+	switch {
+	case a.min == a.max:
+		return strconv.Itoa(a.min)
+	case a.max < 0:
+		return "at least " + strconv.Itoa(a.min)
+	}
+
+	return strconv.Itoa(a.min) + " or " + strconv.Itoa(a.max)
+}
+
+var builtinArities = map[string]arity{
+	"len":                {1, 1},
+	"copy":               {1, 1},
+	"append":             {2, -1},
+	"string":             {1, 2},
+	"int":                {1, 2},
+	"bool":               {1, 1},
+	"float":              {1, 2},
+	"char":               {1, 2},
+	"bytes":              {1, 2},
+	"time":               {1, 2},
+	"is_int":             {1, 1},
+	"is_float":           {1, 1},
+	"is_string":          {1, 1},
+	"is_bool":            {1, 1},
+	"is_char":            {1, 1},
+	"is_bytes":           {1, 1},
+	"is_array":           {1, 1},
+	"is_immutable_array": {1, 1},
+	"is_map":             {1, 1},
+	"is_immutable_map":   {1, 1},
+	"is_iterable":        {1, 1},
+	"is_time":            {1, 1},
+	"is_error":           {1, 1},
+	"is_undefined":       {1, 1},
+	"is_function":        {1, 1},
+	"is_callable":        {1, 1},
+	"type_name":          {1, 1},
+	"format":             {1, -1},
+}
@@ -0,0 +1,379 @@
+package vet
+
+import (
+	"fmt"
+
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/compiler/token"
+	"github.com/d5/tengo/objects"
+)
+
+// variable represents a variable defined in the source.
+type variable struct {
+	ident    *ast.Ident
+	symbol   *compiler.Symbol
+	isImport bool
+	global   bool // defined at the top level of the main script
+	used     bool
+}
+
+type checker struct {
+	file        *ast.File
+	config      *Config
+	symbolTable *compiler.SymbolTable
+	funcTables  []*compiler.SymbolTable // symbol tables of the enclosing functions
+	variables   []*variable
+	symbols     map[*compiler.Symbol]*variable
+	diagnostics []*Diagnostic
+}
+
+func newChecker(file *ast.File, config *Config) *checker {
+	symbolTable := compiler.NewSymbolTable()
+	for idx, fn := range objects.Builtins {
+		symbolTable.DefineBuiltin(idx, fn.Name)
+	}
+
+	c := &checker{
+		file:        file,
+		config:      config,
+		symbolTable: symbolTable,
+		symbols:     make(map[*compiler.Symbol]*variable),
+	}
+
+	for _, name := range config.Globals {
+		symbolTable.Define(name)
+	}
+
+	if config.Module {
+		// modules do not have the global scope
+		c.symbolTable = c.symbolTable.Fork(false)
+	}
+	c.funcTables = append(c.funcTables, c.symbolTable)
+
+	return c
+}
+
+func (c *checker) report(pos source.Pos, check, format string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, &Diagnostic{
+		Pos:     c.file.InputFile.Position(pos),
+		Check:   check,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (c *checker) checkFile() {
+	c.stmts(c.file.Stmts)
+
+	for _, v := range c.variables {
+		if v.used || v.ident.Name == "_" {
+			continue
+		}
+
+		if v.isImport {
+			c.report(v.ident.Pos(), CheckUnusedImport, "imported and not used: '%s'", v.ident.Name)
+		} else if !v.global {
+			c.report(v.ident.Pos(), CheckUnusedVariable, "declared and not used: '%s'", v.ident.Name)
+		}
+	}
+}
+
+func (c *checker) openScope(block bool) {
+	c.symbolTable = c.symbolTable.Fork(block)
+	if !block {
+		c.funcTables = append(c.funcTables, c.symbolTable)
+	}
+}
+
+func (c *checker) closeScope(block bool) {
+	c.symbolTable = c.symbolTable.Parent(false)
+	if !block {
+		c.funcTables = c.funcTables[:len(c.funcTables)-1]
+	}
+}
+
+// define defines a new variable in the current scope.
+func (c *checker) define(ident *ast.Ident, isImport bool) {
+	v := &variable{
+		ident:    ident,
+		symbol:   c.symbolTable.Define(ident.Name),
+		isImport: isImport,
+		global:   !c.config.Module && c.symbolTable.Parent(false) == nil,
+	}
+
+	c.variables = append(c.variables, v)
+	c.symbols[v.symbol] = v
+}
+
+// resolve resolves the name and returns the variable it refers to. The
+// variable is nil for the builtin functions and the host globals.
+func (c *checker) resolve(name string) (symbol *compiler.Symbol, depth int, v *variable, ok bool) {
+	symbol, depth, ok = c.symbolTable.Resolve(name)
+	if !ok {
+		return
+	}
+
+	// free variables refer to the symbols of the enclosing functions
+	original := symbol
+	for i := len(c.funcTables) - 1; original.Scope == compiler.ScopeFree && i >= 0; i-- {
+		original = c.funcTables[i].FreeSymbols()[original.Index]
+	}
+	v = c.symbols[original]
+
+	return
+}
+
+// use marks the variable referred by the identifier as used.
+func (c *checker) use(ident *ast.Ident) {
+	_, _, v, ok := c.resolve(ident.Name)
+	if !ok {
+		c.report(ident.Pos(), CheckUndefined, "undefined: '%s'", ident.Name)
+		return
+	}
+
+	if v != nil {
+		v.used = true
+	}
+}
+
+func (c *checker) stmts(list []ast.Stmt) {
+	reported := false
+	terminated := false
+	for _, s := range list {
+		if _, isEmpty := s.(*ast.EmptyStmt); isEmpty {
+			continue
+		}
+
+		if terminated && !reported {
+			c.report(s.Pos(), CheckUnreachable, "unreachable code")
+			reported = true
+		}
+
+		c.stmt(s)
+
+		if isTerminating(s) {
+			terminated = true
+		}
+	}
+}
+
+// isTerminating returns true if no statement can be executed after s in the
+// same block.
+func isTerminating(s ast.Stmt) bool {
+	switch s := s.(type) {
+	case *ast.ReturnStmt, *ast.BranchStmt, *ast.ExportStmt:
+		return true
+	case *ast.BlockStmt:
+		return len(s.Stmts) > 0 && isTerminating(s.Stmts[len(s.Stmts)-1])
+	case *ast.IfStmt:
+		return s.Else != nil && isTerminating(s.Body) && isTerminating(s.Else)
+	}
+
+	return false
+}
+
+func (c *checker) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.ExprStmt:
+		c.expr(s.Expr)
+	case *ast.AssignStmt:
+		c.assign(s)
+	case *ast.IncDecStmt:
+		c.assignTarget(s.Expr, true)
+	case *ast.ReturnStmt:
+		if s.Result != nil {
+			c.expr(s.Result)
+		}
+	case *ast.ExportStmt:
+		if len(c.funcTables) > 1 {
+			c.report(s.Pos(), CheckExport, "export not allowed inside function")
+		} else if !c.config.Module {
+			c.report(s.Pos(), CheckExport, "export has no effect outside module")
+		}
+		c.expr(s.Result)
+	case *ast.BlockStmt:
+		c.openScope(true)
+		c.stmts(s.Stmts)
+		c.closeScope(true)
+	case *ast.IfStmt:
+		c.openScope(true)
+		if s.Init != nil {
+			c.stmt(s.Init)
+		}
+		c.expr(s.Cond)
+		c.stmt(s.Body)
+		if s.Else != nil {
+			c.stmt(s.Else)
+		}
+		c.closeScope(true)
+	case *ast.ForStmt:
+		c.openScope(true)
+		if s.Init != nil {
+			c.stmt(s.Init)
+		}
+		if s.Cond != nil {
+			c.expr(s.Cond)
+		}
+		c.stmt(s.Body)
+		if s.Post != nil {
+			c.stmt(s.Post)
+		}
+		c.closeScope(true)
+	case *ast.ForInStmt:
+		c.openScope(true)
+		c.expr(s.Iterable)
+		if s.Key.Name != "_" {
+			c.define(s.Key, false)
+		}
+		if s.Value.Name != "_" {
+			c.define(s.Value, false)
+		}
+		c.stmt(s.Body)
+		c.closeScope(true)
+	}
+}
+
+func (c *checker) assign(s *ast.AssignStmt) {
+	if s.Token != token.Define {
+		for _, lhs := range s.LHS {
+			c.assignTarget(lhs, s.Token != token.Assign)
+		}
+		c.exprs(s.RHS)
+		return
+	}
+
+	for _, lhs := range s.LHS {
+		ident, ok := lhs.(*ast.Ident)
+		if !ok {
+			c.expr(lhs)
+			continue
+		}
+
+		if _, depth, v, ok := c.resolve(ident.Name); ok && depth > 0 && v != nil {
+			c.report(ident.Pos(), CheckShadow, "declaration of '%s' shadows declaration at %s",
+				ident.Name, c.file.InputFile.Position(v.ident.Pos()))
+		}
+
+		isImport := false
+		if len(s.RHS) == 1 {
+			_, isImport = s.RHS[0].(*ast.ImportExpr)
+		}
+
+		// the variable is defined before the right-hand side is compiled,
+		// so that the functions can refer to themselves.
+		c.define(ident, isImport)
+	}
+
+	c.exprs(s.RHS)
+}
+
+// assignTarget checks the left-hand side expression of an assignment. The
+// variable is used if the value is read, or if the assignment is through
+// a selector.
+func (c *checker) assignTarget(lhs ast.Expr, read bool) {
+	switch x := lhs.(type) {
+	case *ast.Ident:
+		_, _, v, ok := c.resolve(x.Name)
+		if !ok {
+			c.report(x.Pos(), CheckUndefined, "assignment to undefined variable '%s'", x.Name)
+			return
+		}
+
+		if v != nil && read {
+			v.used = true
+		}
+	case *ast.IndexExpr:
+		c.assignTarget(x.Expr, true)
+		c.expr(x.Index)
+	case *ast.SelectorExpr:
+		c.assignTarget(x.Expr, true)
+		c.expr(x.Sel)
+	default:
+		c.expr(lhs)
+	}
+}
+
+func (c *checker) exprs(list []ast.Expr) {
+	for _, x := range list {
+		c.expr(x)
+	}
+}
+
+func (c *checker) expr(x ast.Expr) {
+	switch x := x.(type) {
+	case *ast.Ident:
+		c.use(x)
+	case *ast.BinaryExpr:
+		c.expr(x.LHS)
+		c.expr(x.RHS)
+	case *ast.UnaryExpr:
+		c.expr(x.Expr)
+	case *ast.ParenExpr:
+		c.expr(x.Expr)
+	case *ast.CondExpr:
+		c.expr(x.Cond)
+		c.expr(x.True)
+		c.expr(x.False)
+	case *ast.CallExpr:
+		c.expr(x.Func)
+		c.exprs(x.Args)
+		c.checkBuiltinCall(x)
+	case *ast.SelectorExpr:
+		c.expr(x.Expr)
+		c.expr(x.Sel)
+	case *ast.IndexExpr:
+		c.expr(x.Expr)
+		if x.Index != nil {
+			c.expr(x.Index)
+		}
+	case *ast.SliceExpr:
+		c.expr(x.Expr)
+		if x.Low != nil {
+			c.expr(x.Low)
+		}
+		if x.High != nil {
+			c.expr(x.High)
+		}
+	case *ast.ArrayLit:
+		c.exprs(x.Elements)
+	case *ast.MapLit:
+		for _, e := range x.Elements {
+			c.expr(e.Value)
+		}
+	case *ast.ErrorExpr:
+		c.expr(x.Expr)
+	case *ast.ImmutableExpr:
+		c.expr(x.Expr)
+	case *ast.FuncLit:
+		c.openScope(false)
+		for _, param := range x.Type.Params.List {
+			c.symbolTable.Define(param.Name)
+		}
+		c.stmt(x.Body)
+		c.closeScope(false)
+	}
+}
+
+func (c *checker) checkBuiltinCall(x *ast.CallExpr) {
+	ident, ok := x.Func.(*ast.Ident)
+	if !ok {
+		return
+	}
+
+	symbol, _, _, ok := c.resolve(ident.Name)
+	if !ok || symbol.Scope != compiler.ScopeBuiltin {
+		return
+	}
+
+	arity, ok := builtinArities[ident.Name]
+	if !ok {
+		return
+	}
+
+	numArgs := len(x.Args)
+	if numArgs < arity.min || (arity.max >= 0 && numArgs > arity.max) {
+		c.report(x.Pos(), CheckBuiltinArgs, "wrong number of arguments in call to '%s': want %s, got %d",
+			ident.Name, arity, numArgs)
+	}
+}
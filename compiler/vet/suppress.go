@@ -0,0 +1,75 @@
+package vet
+
+import (
+	"strings"
+
+	"github.com/d5/tengo/compiler/ast"
+)
+
+const ignoreDirective = "vet:ignore"
+
+// suppression represents the checks ignored on a line.
+type suppression struct {
+	all    bool
+	checks []string
+}
+
+// lineSuppressions maps the source lines to the checks ignored on them.
+type lineSuppressions map[int]*suppression
+
+// suppressions collects the "// vet:ignore [check ...]" comments of the
+// file. A comment suppresses the diagnostics on its own line and on the
+// following line. If no checks are listed, all of them are suppressed.
+func suppressions(file *ast.File) lineSuppressions {
+	res := make(lineSuppressions)
+	for _, g := range file.Comments {
+		for _, c := range g.List {
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if !strings.HasPrefix(text, ignoreDirective) {
+				continue
+			}
+
+			checks := strings.FieldsFunc(text[len(ignoreDirective):], func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t'
+			})
+
+			line := file.InputFile.Line(c.Pos())
+			res.add(line, checks)
+			res.add(line+1, checks)
+		}
+	}
+
+	return res
+}
+
+func (s lineSuppressions) add(line int, checks []string) {
+	sup, ok := s[line]
+	if !ok {
+		sup = &suppression{}
+		s[line] = sup
+	}
+
+	if len(checks) == 0 {
+		sup.all = true
+	}
+	sup.checks = append(sup.checks, checks...)
+}
+
+func (s lineSuppressions) ignored(d *Diagnostic) bool {
+	sup, ok := s[d.Pos.Line]
+	if !ok {
+		return false
+	}
+
+	if sup.all {
+		return true
+	}
+
+	for _, check := range sup.checks {
+		if check == d.Check {
+			return true
+		}
+	}
+
+	return false
+}
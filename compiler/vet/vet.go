@@ -0,0 +1,85 @@
+package vet
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+)
+
+// List of the checks.
+const (
+	CheckUnusedVariable = "unused-variable"
+	CheckUnusedImport   = "unused-import"
+	CheckShadow         = "shadow"
+	CheckUndefined      = "undefined"
+	CheckUnreachable    = "unreachable"
+	CheckBuiltinArgs    = "builtin-args"
+	CheckExport         = "export"
+)
+
+// Config represents the configuration of the checker.
+type Config struct {
+	// Module tells that the file is a module, which can export a value and
+	// whose top-level variables are not visible outside.
+	Module bool
+
+	// Globals are the names of global variables defined by the host
+	// application, e.g. the variables added to a script.
+	Globals []string
+}
+
+// Diagnostic represents a problem reported by the checker.
+type Diagnostic struct {
+	Pos     source.FilePos
+	Check   string
+	Message string
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s (%s)", d.Pos, d.Message, d.Check)
+}
+
+// Source parses the source code and checks it for suspicious constructs.
+// It returns an error if the source cannot be parsed.
+func Source(filename string, src []byte, config *Config) ([]*Diagnostic, error) {
+	fileSet := source.NewFileSet()
+	srcFile := fileSet.AddFile(filename, -1, len(src))
+
+	p := parser.NewParserWithMode(srcFile, src, nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return File(file, config), nil
+}
+
+// File checks the file unit for suspicious constructs and returns the
+// diagnostics sorted by their positions. The suppression comments are
+// recognized only if the file was parsed with parser.ParseComments mode.
+func File(file *ast.File, config *Config) []*Diagnostic {
+	if config == nil {
+		config = &Config{}
+	}
+
+	c := newChecker(file, config)
+	c.checkFile()
+
+	ignores := suppressions(file)
+
+	var res []*Diagnostic
+	for _, d := range c.diagnostics {
+		if !ignores.ignored(d) {
+			res = append(res, d)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Pos.Offset < res[j].Pos.Offset
+	})
+
+	return res
+}
@@ -0,0 +1,127 @@
+package vet_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler/vet"
+)
+
+func TestUnused(t *testing.T) {
+	expectVet(t, `a := 1; a = 2`, nil)
+	expectVet(t, `f := func() { a := 1 }`, nil,
+		"test:1:15: declared and not used: 'a' (unused-variable)")
+	expectVet(t, `f := func() { a := 1; a = 2 }`, nil,
+		"test:1:15: declared and not used: 'a' (unused-variable)")
+	expectVet(t, `f := func() { a := 1; a += 2 }`, nil)
+	expectVet(t, `f := func() { a := {}; a.b = 2 }`, nil)
+	expectVet(t, `f := func() { a := [1]; a[0]++ }`, nil)
+	expectVet(t, `f := func() { a := 1; return func() { return a } }`, nil)
+	expectVet(t, `f := func() { a := 1; return func() { return func() { return a } } }`, nil)
+	expectVet(t, `f := func(a, b) { _ := 1 }`, nil)
+	expectVet(t, `f := func() { for k, v in [1] {} }`, nil,
+		"test:1:19: declared and not used: 'k' (unused-variable)",
+		"test:1:22: declared and not used: 'v' (unused-variable)")
+	expectVet(t, `f := func() { for _, v in [1] { v() } }`, nil)
+	expectVet(t, `f := func() { if a := 1; true {} }`, nil,
+		"test:1:18: declared and not used: 'a' (unused-variable)")
+
+	// imports
+	expectVet(t, `fmt := import("fmt")`, nil,
+		"test:1:1: imported and not used: 'fmt' (unused-import)")
+	expectVet(t, `fmt := import("fmt"); fmt.println(1)`, nil)
+	expectVet(t, `f := func() { fmt := import("fmt") }`, nil,
+		"test:1:15: imported and not used: 'fmt' (unused-import)")
+
+	// module top-level variables are locals
+	expectVet(t, `a := 1; export 2`, &vet.Config{Module: true},
+		"test:1:1: declared and not used: 'a' (unused-variable)")
+	expectVet(t, `a := 1; export a`, &vet.Config{Module: true})
+}
+
+func TestUndefined(t *testing.T) {
+	expectVet(t, `a := b`, nil,
+		"test:1:6: undefined: 'b' (undefined)")
+	expectVet(t, `a = 1`, nil,
+		"test:1:1: assignment to undefined variable 'a' (undefined)")
+	expectVet(t, `a.b = 1`, nil,
+		"test:1:1: assignment to undefined variable 'a' (undefined)")
+	expectVet(t, `f := func() { return f() }`, nil)
+	expectVet(t, `a := b`, &vet.Config{Globals: []string{"b"}})
+	expectVet(t, `a := len([])`, nil)
+	expectVet(t, `if true { a := 1 }; a = 2`, nil,
+		"test:1:11: declared and not used: 'a' (unused-variable)",
+		"test:1:21: assignment to undefined variable 'a' (undefined)")
+}
+
+func TestShadow(t *testing.T) {
+	expectVet(t, `a := 1; f := func() { a := 2; return a }`, nil,
+		"test:1:23: declaration of 'a' shadows declaration at test:1:1 (shadow)")
+	expectVet(t, `f := func() { a := 1; if true { a := 2; a() }; a() }`, nil,
+		"test:1:33: declaration of 'a' shadows declaration at test:1:15 (shadow)")
+	expectVet(t, `len := 1`, nil)
+	expectVet(t, `a := 1; f := func(a) { return a }`, nil)
+}
+
+func TestUnreachable(t *testing.T) {
+	expectVet(t, `f := func() { return 1; a := 2; a() }`, nil,
+		"test:1:25: unreachable code (unreachable)")
+	expectVet(t, "for { break\nx := 1\nx() }", nil,
+		"test:2:1: unreachable code (unreachable)")
+	expectVet(t, `f := func(a) { if a { return 1 } else { return 2 }; return 3 }`, nil,
+		"test:1:53: unreachable code (unreachable)")
+	expectVet(t, `f := func(a) { if a { return 1 }; return 3 }`, nil)
+	expectVet(t, `export 1; a := 2`, &vet.Config{Module: true},
+		"test:1:11: unreachable code (unreachable)",
+		"test:1:11: declared and not used: 'a' (unused-variable)")
+}
+
+func TestBuiltinArgs(t *testing.T) {
+	expectVet(t, `a := len()`, nil,
+		"test:1:6: wrong number of arguments in call to 'len': want 1, got 0 (builtin-args)")
+	expectVet(t, `a := string(1, 2, 3)`, nil,
+		"test:1:6: wrong number of arguments in call to 'string': want 1 or 2, got 3 (builtin-args)")
+	expectVet(t, `a := append([])`, nil,
+		"test:1:6: wrong number of arguments in call to 'append': want at least 2, got 1 (builtin-args)")
+	expectVet(t, `a := append([], 1, 2, 3); b := format("%d", 1); c := int("1", 0)`, nil)
+
+	// shadowed builtin functions are not checked
+	expectVet(t, `len := func() {}; a := len()`, nil)
+}
+
+func TestExport(t *testing.T) {
+	expectVet(t, `export 1`, nil,
+		"test:1:1: export has no effect outside module (export)")
+	expectVet(t, `export 1`, &vet.Config{Module: true})
+	expectVet(t, `export func() { export 1 }`, &vet.Config{Module: true},
+		"test:1:17: export not allowed inside function (export)")
+}
+
+func TestSuppression(t *testing.T) {
+	expectVet(t, "f := func() {\n\ta := 1 // vet:ignore\n}", nil)
+	expectVet(t, "f := func() {\n\t// vet:ignore unused-variable\n\ta := 1\n}", nil)
+	expectVet(t, "f := func() {\n\t// vet:ignore shadow, undefined\n\ta := 1\n}", nil,
+		"test:3:2: declared and not used: 'a' (unused-variable)")
+	expectVet(t, "// vet:ignore\n\nx := y", nil,
+		"test:3:6: undefined: 'y' (undefined)")
+}
+
+func TestSourceError(t *testing.T) {
+	_, err := vet.Source("test", []byte(`a := `), nil)
+	assert.Error(t, err)
+}
+
+func expectVet(t *testing.T, input string, config *vet.Config, expected ...string) {
+	diagnostics, err := vet.Source("test", []byte(input), config)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var actual []string
+	for _, d := range diagnostics {
+		actual = append(actual, d.String())
+	}
+
+	assert.Equal(t, strings.Join(expected, "\n"), strings.Join(actual, "\n"), input)
+}
@@ -0,0 +1,53 @@
+package compiler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/d5/tengo/compiler/source"
+)
+
+// Warning represents a problem in the source that does not prevent the
+// compilation, e.g. a variable that is never used.
+type Warning struct {
+	fileSet *source.FileSet
+	pos     source.Pos
+	message string
+}
+
+func (w *Warning) String() string {
+	return fmt.Sprintf("Compile Warning: %s\n\tat %s", w.message, w.Pos())
+}
+
+// Pos returns the position in the source where the problem was found.
+func (w *Warning) Pos() source.FilePos {
+	return w.fileSet.Position(w.pos)
+}
+
+// Message returns the warning message without the position.
+func (w *Warning) Message() string {
+	return w.message
+}
+
+// sortWarnings sorts the warnings by their positions and removes the
+// warnings with the same position and message.
+func sortWarnings(warnings []*Warning) []*Warning {
+	sort.SliceStable(warnings, func(i, j int) bool {
+		e, f := warnings[i].Pos(), warnings[j].Pos()
+		if e.Filename != f.Filename {
+			return e.Filename < f.Filename
+		}
+
+		return e.Offset < f.Offset
+	})
+
+	var res []*Warning
+	for i, w := range warnings {
+		if i > 0 && w.pos == warnings[i-1].pos && w.message == warnings[i-1].message {
+			continue
+		}
+		res = append(res, w)
+	}
+
+	return res
+}
@@ -0,0 +1,124 @@
+package lsp
+
+import (
+	"net/url"
+	"path/filepath"
+	"sort"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/d5/tengo/compiler/source"
+)
+
+// content represents a version of the document text.
+type content struct {
+	text  string
+	lines []int // offsets of the line starts
+}
+
+func newContent(text string) *content {
+	lines := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
+	return &content{text: text, lines: lines}
+}
+
+// position converts the byte offset into the protocol position, whose
+// character offset is counted in UTF-16 code units.
+func (c *content) position(offset int) position {
+	if offset < 0 {
+		offset = 0
+	} else if offset > len(c.text) {
+		offset = len(c.text)
+	}
+
+	line := sort.Search(len(c.lines), func(i int) bool {
+		return c.lines[i] > offset
+	}) - 1
+	start := c.lines[line]
+
+	return position{
+		Line:      line,
+		Character: len(utf16.Encode([]rune(c.text[start:offset]))),
+	}
+}
+
+// offset converts the protocol position into the byte offset.
+func (c *content) offset(p position) int {
+	if p.Line < 0 {
+		return 0
+	} else if p.Line >= len(c.lines) {
+		return len(c.text)
+	}
+
+	start := c.lines[p.Line]
+	units := 0
+	for i, r := range c.text[start:] {
+		if units >= p.Character || r == '\n' {
+			return start + i
+		}
+
+		if r >= 0x10000 && r <= utf8.MaxRune {
+			units += 2
+		} else {
+			units++
+		}
+	}
+
+	return len(c.text)
+}
+
+// rangeOf returns the range between the byte offsets.
+func (c *content) rangeOf(start, end int) textRange {
+	return textRange{Start: c.position(start), End: c.position(end)}
+}
+
+// parsed represents the last version of the document that could be parsed.
+type parsed struct {
+	*content
+	index *index
+}
+
+// offsetOf converts the file set position into the byte offset.
+func (p *parsed) offsetOf(pos source.Pos) int {
+	return int(pos) - p.index.file.InputFile.Base
+}
+
+// rangeOfNode returns the range between the file set positions.
+func (p *parsed) rangeOfNode(start, end source.Pos) textRange {
+	return p.rangeOf(p.offsetOf(start), p.offsetOf(end))
+}
+
+// pos converts the protocol position into the file set position.
+func (p *parsed) pos(position position) source.Pos {
+	return p.index.file.InputFile.FileSetPos(p.offset(position))
+}
+
+type document struct {
+	uri     string
+	path    string
+	version int
+	*content
+	parsed *parsed // nil if the document was never parsed
+}
+
+// uriToPath converts the file URI into a file path. Other URIs are used as
+// they are.
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+
+	path := u.Path
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		// Windows drive letter, e.g. "/C:/path"
+		path = path[1:]
+	}
+
+	return filepath.FromSlash(path)
+}
@@ -0,0 +1,395 @@
+package lsp
+
+import (
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/compiler/token"
+	"github.com/d5/tengo/objects"
+)
+
+type definitionKind int
+
+const (
+	kindVariable definitionKind = iota
+	kindFunction
+	kindImport
+	kindParameter
+	kindBuiltin
+)
+
+// definition represents a variable defined in the source, or a builtin
+// function.
+type definition struct {
+	name     string
+	kind     definitionKind
+	ident    *ast.Ident // nil for builtin functions
+	stmt     ast.Stmt   // defining statement; nil for parameters
+	value    ast.Expr   // assigned value; or nil
+	scope    compiler.SymbolScope
+	end      source.Pos // end of the scope where the variable is visible
+	children []*definition
+}
+
+// visible returns true if the variable can be referred at the position.
+func (d *definition) visible(pos source.Pos) bool {
+	if d.kind == kindBuiltin {
+		return true
+	}
+
+	return d.ident.Pos() <= pos && pos <= d.end
+}
+
+// moduleName returns the name of the imported module.
+func (d *definition) moduleName() string {
+	if x, ok := d.value.(*ast.ImportExpr); ok {
+		return x.ModuleName
+	}
+
+	return ""
+}
+
+var builtinDefinitions = func() map[string]*definition {
+	defs := make(map[string]*definition)
+	for _, fn := range objects.Builtins {
+		defs[fn.Name] = &definition{
+			name:  fn.Name,
+			kind:  kindBuiltin,
+			scope: compiler.ScopeBuiltin,
+		}
+	}
+
+	return defs
+}()
+
+// index is the result of resolving the identifiers of a file.
+type index struct {
+	file      *ast.File
+	defs      []*definition // all definitions in the order of the source
+	symbols   []*definition // definitions not inside functions
+	refs      map[*ast.Ident]*definition
+	selectors []*ast.SelectorExpr
+}
+
+// identAt returns the identifier at the position and the definition it
+// refers to.
+func (idx *index) identAt(pos source.Pos) (*ast.Ident, *definition) {
+	for ident, def := range idx.refs {
+		if ident.Pos() <= pos && pos <= ident.End() {
+			return ident, def
+		}
+	}
+
+	return nil, nil
+}
+
+// selectorAt returns the selector whose selected name is at the position.
+func (idx *index) selectorAt(pos source.Pos) *ast.SelectorExpr {
+	for _, x := range idx.selectors {
+		if x.Sel.Pos() <= pos && pos <= x.Sel.End() {
+			return x
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the definition of the name visible at the position.
+func (idx *index) lookup(name string, pos source.Pos) *definition {
+	var res *definition
+	for _, def := range idx.defs {
+		if def.name == name && def.visible(pos) {
+			// inner definitions come after the outer ones
+			res = def
+		}
+	}
+
+	if res == nil {
+		res = builtinDefinitions[name]
+	}
+
+	return res
+}
+
+// visibleAt returns the definitions visible at the position. Inner
+// definitions hide the outer ones of the same name.
+func (idx *index) visibleAt(pos source.Pos) []*definition {
+	byName := make(map[string]*definition)
+	var names []string
+	for _, def := range idx.defs {
+		if !def.visible(pos) {
+			continue
+		}
+
+		if _, ok := byName[def.name]; !ok {
+			names = append(names, def.name)
+		}
+		byName[def.name] = def
+	}
+
+	var res []*definition
+	for _, name := range names {
+		res = append(res, byName[name])
+	}
+
+	return res
+}
+
+type indexer struct {
+	idx         *index
+	symbolTable *compiler.SymbolTable
+	funcTables  []*compiler.SymbolTable
+	scopeEnds   []source.Pos
+	owners      []*definition
+	symbols     map[*compiler.Symbol]*definition
+}
+
+// newIndex resolves the identifiers of the file in the same way as the
+// compiler does.
+func newIndex(file *ast.File) *index {
+	symbolTable := compiler.NewSymbolTable()
+	for idx, fn := range objects.Builtins {
+		symbolTable.DefineBuiltin(idx, fn.Name)
+	}
+
+	i := &indexer{
+		idx: &index{
+			file: file,
+			refs: make(map[*ast.Ident]*definition),
+		},
+		symbolTable: symbolTable,
+		funcTables:  []*compiler.SymbolTable{symbolTable},
+		scopeEnds:   []source.Pos{file.InputFile.FileSetPos(file.InputFile.Size)},
+		symbols:     make(map[*compiler.Symbol]*definition),
+	}
+	i.stmts(file.Stmts)
+
+	return i.idx
+}
+
+func (i *indexer) openScope(block bool, end source.Pos) {
+	i.symbolTable = i.symbolTable.Fork(block)
+	if !block {
+		i.funcTables = append(i.funcTables, i.symbolTable)
+	}
+	i.scopeEnds = append(i.scopeEnds, end)
+}
+
+func (i *indexer) closeScope(block bool) {
+	i.symbolTable = i.symbolTable.Parent(false)
+	if !block {
+		i.funcTables = i.funcTables[:len(i.funcTables)-1]
+	}
+	i.scopeEnds = i.scopeEnds[:len(i.scopeEnds)-1]
+}
+
+func (i *indexer) define(ident *ast.Ident, kind definitionKind, stmt ast.Stmt, value ast.Expr) *definition {
+	symbol := i.symbolTable.Define(ident.Name)
+	def := &definition{
+		name:  ident.Name,
+		kind:  kind,
+		ident: ident,
+		stmt:  stmt,
+		value: value,
+		scope: symbol.Scope,
+		end:   i.scopeEnds[len(i.scopeEnds)-1],
+	}
+
+	i.symbols[symbol] = def
+	i.idx.defs = append(i.idx.defs, def)
+	i.idx.refs[ident] = def
+
+	if kind != kindParameter {
+		if len(i.owners) > 0 {
+			owner := i.owners[len(i.owners)-1]
+			owner.children = append(owner.children, def)
+		} else {
+			i.idx.symbols = append(i.idx.symbols, def)
+		}
+	}
+
+	return def
+}
+
+func (i *indexer) use(ident *ast.Ident) {
+	symbol, _, ok := i.symbolTable.Resolve(ident.Name)
+	if !ok {
+		return
+	}
+
+	if symbol.Scope == compiler.ScopeBuiltin {
+		i.idx.refs[ident] = builtinDefinitions[ident.Name]
+		return
+	}
+
+	// free variables refer to the symbols of the enclosing functions
+	for f := len(i.funcTables) - 1; symbol.Scope == compiler.ScopeFree && f >= 0; f-- {
+		symbol = i.funcTables[f].FreeSymbols()[symbol.Index]
+	}
+
+	if def, ok := i.symbols[symbol]; ok {
+		i.idx.refs[ident] = def
+	}
+}
+
+func (i *indexer) stmts(list []ast.Stmt) {
+	for _, s := range list {
+		i.stmt(s)
+	}
+}
+
+func (i *indexer) stmt(s ast.Stmt) {
+	switch s := s.(type) {
+	case *ast.ExprStmt:
+		i.expr(s.Expr)
+	case *ast.AssignStmt:
+		i.assign(s)
+	case *ast.IncDecStmt:
+		i.expr(s.Expr)
+	case *ast.ReturnStmt:
+		if s.Result != nil {
+			i.expr(s.Result)
+		}
+	case *ast.ExportStmt:
+		i.expr(s.Result)
+	case *ast.BlockStmt:
+		i.openScope(true, s.End())
+		i.stmts(s.Stmts)
+		i.closeScope(true)
+	case *ast.IfStmt:
+		i.openScope(true, s.End())
+		if s.Init != nil {
+			i.stmt(s.Init)
+		}
+		i.expr(s.Cond)
+		i.stmt(s.Body)
+		if s.Else != nil {
+			i.stmt(s.Else)
+		}
+		i.closeScope(true)
+	case *ast.ForStmt:
+		i.openScope(true, s.End())
+		if s.Init != nil {
+			i.stmt(s.Init)
+		}
+		if s.Cond != nil {
+			i.expr(s.Cond)
+		}
+		i.stmt(s.Body)
+		if s.Post != nil {
+			i.stmt(s.Post)
+		}
+		i.closeScope(true)
+	case *ast.ForInStmt:
+		i.openScope(true, s.End())
+		i.expr(s.Iterable)
+		if s.Key.Name != "_" {
+			i.define(s.Key, kindVariable, s, nil)
+		}
+		if s.Value.Name != "_" {
+			i.define(s.Value, kindVariable, s, nil)
+		}
+		i.stmt(s.Body)
+		i.closeScope(true)
+	}
+}
+
+func (i *indexer) assign(s *ast.AssignStmt) {
+	var value ast.Expr
+	if len(s.RHS) == 1 {
+		value = s.RHS[0]
+	}
+
+	var owner *definition
+	for _, lhs := range s.LHS {
+		ident, ok := lhs.(*ast.Ident)
+		if !ok || s.Token != token.Define {
+			i.expr(lhs)
+			continue
+		}
+
+		kind := kindVariable
+		switch value.(type) {
+		case *ast.FuncLit:
+			kind = kindFunction
+		case *ast.ImportExpr:
+			kind = kindImport
+		}
+
+		// the variable is defined before the right-hand side is compiled,
+		// so that the functions can refer to themselves.
+		owner = i.define(ident, kind, s, value)
+	}
+
+	if owner != nil {
+		i.owners = append(i.owners, owner)
+		defer func() {
+			i.owners = i.owners[:len(i.owners)-1]
+		}()
+	}
+
+	for _, x := range s.RHS {
+		i.expr(x)
+	}
+}
+
+func (i *indexer) expr(x ast.Expr) {
+	switch x := x.(type) {
+	case *ast.Ident:
+		i.use(x)
+	case *ast.BinaryExpr:
+		i.expr(x.LHS)
+		i.expr(x.RHS)
+	case *ast.UnaryExpr:
+		i.expr(x.Expr)
+	case *ast.ParenExpr:
+		i.expr(x.Expr)
+	case *ast.CondExpr:
+		i.expr(x.Cond)
+		i.expr(x.True)
+		i.expr(x.False)
+	case *ast.CallExpr:
+		i.expr(x.Func)
+		for _, arg := range x.Args {
+			i.expr(arg)
+		}
+	case *ast.SelectorExpr:
+		i.expr(x.Expr)
+		i.expr(x.Sel)
+		if _, ok := x.Expr.(*ast.Ident); ok {
+			i.idx.selectors = append(i.idx.selectors, x)
+		}
+	case *ast.IndexExpr:
+		i.expr(x.Expr)
+		if x.Index != nil {
+			i.expr(x.Index)
+		}
+	case *ast.SliceExpr:
+		i.expr(x.Expr)
+		if x.Low != nil {
+			i.expr(x.Low)
+		}
+		if x.High != nil {
+			i.expr(x.High)
+		}
+	case *ast.ArrayLit:
+		for _, e := range x.Elements {
+			i.expr(e)
+		}
+	case *ast.MapLit:
+		for _, e := range x.Elements {
+			i.expr(e.Value)
+		}
+	case *ast.ErrorExpr:
+		i.expr(x.Expr)
+	case *ast.ImmutableExpr:
+		i.expr(x.Expr)
+	case *ast.FuncLit:
+		i.openScope(false, x.End())
+		for _, param := range x.Type.Params.List {
+			i.define(param, kindParameter, nil, nil)
+		}
+		i.stmt(x.Body)
+		i.closeScope(false)
+	}
+}
@@ -0,0 +1,589 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/objects"
+)
+
+// ErrExitWithoutShutdown is returned by Serve if the client sent the exit
+// notification without the shutdown request.
+var ErrExitWithoutShutdown = errors.New("exit without shutdown")
+
+const diagnosticSource = "tengo"
+
+var (
+	memberPrefixRE = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_]*)\.([A-Za-z_][A-Za-z0-9_]*)?$`)
+	identPrefixRE  = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Server is a language server for Tengo that communicates with the client
+// using JSON-RPC. It provides diagnostics, hover, go-to-definition,
+// completion and document symbols for the open documents.
+type Server struct {
+	modules     *objects.ModuleMap
+	documents   map[string]*document
+	out         io.Writer
+	initialized bool
+	shutdown    bool
+}
+
+// NewServer creates a Server. Modules are used to compile the documents
+// and to complete the members of the builtin modules.
+func NewServer(modules *objects.ModuleMap) *Server {
+	if modules == nil {
+		modules = objects.NewModuleMap()
+	}
+
+	return &Server{
+		modules:   modules,
+		documents: make(map[string]*document),
+	}
+}
+
+// Serve reads the messages from in and writes the responses into out until
+// the client sends the exit notification or in is closed.
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	r := bufio.NewReader(in)
+	s.out = out
+
+	for {
+		content, err := readMessage(r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(content, &msg); err != nil {
+			if err := s.reply(nil, nil, errorf(codeParseError, "invalid message: %s", err.Error())); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		result, err := s.handle(&msg)
+		if msg.ID == nil {
+			// no responses to notifications
+			continue
+		}
+
+		if err := s.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handler{
+	"initialize":                  (*Server).initialize,
+	"initialized":                 (*Server).ignore,
+	"shutdown":                    (*Server).shutdownServer,
+	"textDocument/didOpen":        (*Server).didOpen,
+	"textDocument/didChange":      (*Server).didChange,
+	"textDocument/didClose":       (*Server).didClose,
+	"textDocument/didSave":        (*Server).ignore,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/completion":     (*Server).completion,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+}
+
+func (s *Server) handle(msg *message) (interface{}, error) {
+	if !s.initialized && msg.Method != "initialize" {
+		return nil, errorf(codeServerNotInitialized, "server not initialized")
+	}
+
+	if s.shutdown {
+		return nil, errorf(codeInvalidRequest, "server is shut down")
+	}
+
+	h, ok := handlers[msg.Method]
+	if !ok {
+		return nil, errorf(codeMethodNotFound, "method not found: %s", msg.Method)
+	}
+
+	return h(s, msg.Params)
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, err error) error {
+	res := &response{JSONRPC: "2.0", ID: id}
+	if err != nil {
+		rpcErr, ok := err.(*rpcError)
+		if !ok {
+			rpcErr = errorf(codeInternalError, "%s", err.Error())
+		}
+		res.Error = rpcErr
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		res.Result = data
+	}
+
+	return writeMessage(s.out, res)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return writeMessage(s.out, &notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func decode(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return errorf(codeInvalidParams, "invalid params: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (s *Server) ignore(params json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	s.initialized = true
+
+	return &initializeResult{
+		Capabilities: serverCapabilities{
+			TextDocumentSync: textDocumentSyncOptions{
+				OpenClose: true,
+				Change:    syncFull,
+			},
+			HoverProvider:      true,
+			DefinitionProvider: true,
+			CompletionProvider: completionOptions{
+				TriggerCharacters: []string{"."},
+			},
+			DocumentSymbolProvider: true,
+		},
+	}, nil
+}
+
+func (s *Server) shutdownServer(params json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+
+	return nil, nil
+}
+
+func (s *Server) didOpen(params json.RawMessage) (interface{}, error) {
+	var p didOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc := &document{
+		uri:     p.TextDocument.URI,
+		path:    uriToPath(p.TextDocument.URI),
+		version: p.TextDocument.Version,
+	}
+	s.documents[doc.uri] = doc
+
+	return nil, s.update(doc, p.TextDocument.Text)
+}
+
+func (s *Server) didChange(params json.RawMessage) (interface{}, error) {
+	var p didChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+
+	text := doc.text
+	for _, change := range p.ContentChanges {
+		if change.Range == nil {
+			text = change.Text
+			continue
+		}
+
+		// incremental changes are applied in order
+		c := newContent(text)
+		text = text[:c.offset(change.Range.Start)] + change.Text + text[c.offset(change.Range.End):]
+	}
+	doc.version = p.TextDocument.Version
+
+	return nil, s.update(doc, text)
+}
+
+func (s *Server) didClose(params json.RawMessage) (interface{}, error) {
+	var p didCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	delete(s.documents, p.TextDocument.URI)
+
+	return nil, s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []diagnostic{},
+	})
+}
+
+// update parses and compiles the new text of the document, and publishes
+// the errors as the diagnostics.
+func (s *Server) update(doc *document, text string) error {
+	doc.content = newContent(text)
+	diagnostics := []diagnostic{}
+
+	fileSet := source.NewFileSet()
+	srcFile := fileSet.AddFile(doc.path, -1, len(text))
+	p := parser.NewParserWithMode(srcFile, []byte(text), nil, parser.ParseComments)
+	file, err := p.ParseFile()
+	if err != nil {
+		if list, ok := err.(parser.ErrorList); ok {
+			for _, e := range list {
+				diagnostics = append(diagnostics, doc.diagnosticAt(e.Pos.Offset, e.Msg))
+			}
+		} else {
+			diagnostics = append(diagnostics, doc.diagnosticAt(0, err.Error()))
+		}
+	} else {
+		doc.parsed = &parsed{content: doc.content, index: newIndex(file)}
+
+		c := compiler.NewCompiler(srcFile, nil, nil, s.modules, nil)
+		c.EnableFileImport(true)
+		if err := c.Compile(file); err != nil {
+			list, ok := err.(compiler.ErrorList)
+			if !ok {
+				diagnostics = append(diagnostics, doc.diagnosticAt(0, err.Error()))
+			}
+
+			for _, e := range list {
+				if e.Pos().Filename == doc.path {
+					diagnostics = append(diagnostics, doc.diagnosticAt(e.Pos().Offset, e.Message()))
+				} else {
+					// errors in the imported modules
+					diagnostics = append(diagnostics, doc.diagnosticAt(0, e.Error()))
+				}
+			}
+		}
+	}
+
+	return s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+		URI:         doc.uri,
+		Version:     doc.version,
+		Diagnostics: diagnostics,
+	})
+}
+
+// diagnosticAt creates an error diagnostic that covers the word at the
+// offset.
+func (doc *document) diagnosticAt(offset int, msg string) diagnostic {
+	end := offset
+	for end < len(doc.text) && isWordChar(doc.text[end]) {
+		end++
+	}
+	if end == offset && end < len(doc.text) && doc.text[end] != '\n' {
+		end++
+	}
+
+	return diagnostic{
+		Range:    doc.rangeOf(offset, end),
+		Severity: severityError,
+		Source:   diagnosticSource,
+		Message:  msg,
+	}
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parsedDocument returns the last parsed version of the document at the
+// position, or nil if it is not available.
+func (s *Server) parsedDocument(params json.RawMessage) (*document, *textDocumentPositionParams, error) {
+	var p textDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok || doc.parsed == nil {
+		return nil, nil, nil
+	}
+
+	return doc, &p, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	doc, p, err := s.parsedDocument(params)
+	if doc == nil {
+		return nil, err
+	}
+
+	parsed := doc.parsed
+	pos := parsed.pos(p.Position)
+	if ident, def := parsed.index.identAt(pos); ident != nil {
+		r := parsed.rangeOfNode(ident.Pos(), ident.End())
+		return &hover{Contents: markdown(describe(def)), Range: &r}, nil
+	}
+
+	if x := parsed.index.selectorAt(pos); x != nil {
+		def := parsed.index.refs[x.Expr.(*ast.Ident)]
+		name, _ := x.Sel.(*ast.StringLit)
+		if def == nil || def.kind != kindImport || name == nil {
+			return nil, nil
+		}
+
+		member, ok := s.moduleAttrs(def.moduleName())[name.Value]
+		if !ok {
+			return nil, nil
+		}
+
+		r := parsed.rangeOfNode(x.Sel.Pos(), x.Sel.End())
+		return &hover{
+			Contents: markdown(describeMember(def.moduleName(), name.Value, member)),
+			Range:    &r,
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func markdown(code string) markupContent {
+	return markupContent{Kind: "markdown", Value: code}
+}
+
+// describe returns the description of the definition in markdown.
+func describe(def *definition) string {
+	var code string
+	switch def.kind {
+	case kindBuiltin:
+		code = "builtin function " + def.name
+	case kindParameter:
+		code = "parameter " + def.name
+	case kindFunction:
+		code = def.name + " := " + def.value.(*ast.FuncLit).Type.String()
+	case kindImport:
+		code = def.name + " := " + def.value.String()
+	default:
+		code = def.name
+		if def.value != nil {
+			if value := def.value.String(); len(value) <= 60 && !strings.Contains(value, "\n") {
+				code += " := " + value
+			}
+		}
+	}
+
+	res := "```tengo\n" + code + "\n```"
+	if doc := docComment(def); doc != "" {
+		res += "\n\n" + doc
+	}
+
+	return res
+}
+
+// docComment returns the text of the comments attached to the defining
+// statement.
+func docComment(def *definition) string {
+	if s, ok := def.stmt.(*ast.AssignStmt); ok {
+		if s.Doc != nil {
+			return strings.TrimSpace(s.Doc.Text())
+		}
+		if s.Comment != nil {
+			return strings.TrimSpace(s.Comment.Text())
+		}
+	}
+
+	return ""
+}
+
+func describeMember(module, name string, member objects.Object) string {
+	code := module + "." + name
+	switch member.(type) {
+	case objects.Callable:
+		code += " (" + member.TypeName() + ")"
+	default:
+		code += " = " + member.String()
+	}
+
+	return "```tengo\n" + code + "\n```"
+}
+
+// moduleAttrs returns the attributes of the builtin module.
+func (s *Server) moduleAttrs(name string) map[string]objects.Object {
+	mod := s.modules.GetBuiltinModule(name)
+	if mod == nil {
+		return nil
+	}
+
+	return mod.Attrs
+}
+
+func (s *Server) definition(params json.RawMessage) (interface{}, error) {
+	doc, p, err := s.parsedDocument(params)
+	if doc == nil {
+		return nil, err
+	}
+
+	parsed := doc.parsed
+	_, def := parsed.index.identAt(parsed.pos(p.Position))
+	if def == nil || def.ident == nil {
+		return nil, nil
+	}
+
+	return &location{
+		URI:   doc.uri,
+		Range: parsed.rangeOfNode(def.ident.Pos(), def.ident.End()),
+	}, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+
+	offset := doc.offset(p.Position)
+	line := doc.text[doc.lines[doc.position(offset).Line]:offset]
+
+	res := &completionList{Items: []completionItem{}}
+
+	var pos source.Pos
+	var idx *index
+	if doc.parsed != nil {
+		pos = doc.parsed.pos(p.Position)
+		idx = doc.parsed.index
+	}
+
+	if m := memberPrefixRE.FindStringSubmatch(line); m != nil {
+		if idx == nil {
+			return res, nil
+		}
+
+		def := idx.lookup(m[1], pos)
+		if def == nil || def.kind != kindImport {
+			return res, nil
+		}
+
+		for name, member := range s.moduleAttrs(def.moduleName()) {
+			if !strings.HasPrefix(name, m[2]) {
+				continue
+			}
+
+			item := completionItem{Label: name, Detail: member.TypeName()}
+			switch member.(type) {
+			case objects.Callable:
+				item.Kind = completionFunction
+			case *objects.Int, *objects.Float, *objects.String, *objects.Bool:
+				item.Kind = completionConstant
+			default:
+				item.Kind = completionField
+			}
+			res.Items = append(res.Items, item)
+		}
+	} else {
+		prefix := identPrefixRE.FindString(line)
+		names := make(map[string]bool)
+
+		if idx != nil {
+			for _, def := range idx.visibleAt(pos) {
+				if !strings.HasPrefix(def.name, prefix) {
+					continue
+				}
+
+				item := completionItem{Label: def.name, Kind: completionVariable}
+				switch def.kind {
+				case kindFunction:
+					item.Kind = completionFunction
+				case kindImport:
+					item.Kind = completionModule
+					item.Detail = def.moduleName()
+				}
+				res.Items = append(res.Items, item)
+				names[def.name] = true
+			}
+		}
+
+		for _, fn := range objects.Builtins {
+			if strings.HasPrefix(fn.Name, prefix) && !names[fn.Name] {
+				res.Items = append(res.Items, completionItem{
+					Label:  fn.Name,
+					Kind:   completionFunction,
+					Detail: "builtin function",
+				})
+			}
+		}
+	}
+
+	sort.Slice(res.Items, func(i, j int) bool {
+		return res.Items[i].Label < res.Items[j].Label
+	})
+
+	return res, nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (interface{}, error) {
+	var p documentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	doc, ok := s.documents[p.TextDocument.URI]
+	if !ok || doc.parsed == nil {
+		return nil, nil
+	}
+
+	return documentSymbols(doc.parsed, doc.parsed.index.symbols), nil
+}
+
+func documentSymbols(parsed *parsed, defs []*definition) []documentSymbol {
+	res := []documentSymbol{}
+	for _, def := range defs {
+		sym := documentSymbol{
+			Name:           def.name,
+			Kind:           symbolVariable,
+			Range:          parsed.rangeOfNode(def.stmt.Pos(), def.stmt.End()),
+			SelectionRange: parsed.rangeOfNode(def.ident.Pos(), def.ident.End()),
+		}
+
+		switch def.kind {
+		case kindFunction:
+			sym.Kind = symbolFunction
+			sym.Detail = def.value.(*ast.FuncLit).Type.String()
+		case kindImport:
+			sym.Kind = symbolModule
+			sym.Detail = def.moduleName()
+		}
+
+		if len(def.children) > 0 {
+			sym.Children = documentSymbols(parsed, def.children)
+		}
+
+		res = append(res, sym)
+	}
+
+	return res
+}
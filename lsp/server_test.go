@@ -0,0 +1,297 @@
+package lsp_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/lsp"
+	"github.com/d5/tengo/stdlib"
+)
+
+const testURI = "file:///tmp/test.tengo"
+
+const testSource = `fmt := import("fmt")
+
+// add returns the sum.
+add := func(a, b) {
+	return a + b
+}
+x := add(1, 2)
+fmt.println(x)
+`
+
+func TestServer(t *testing.T) {
+	c := newClient(t)
+
+	// requests before initialization
+	_, rpcErr := c.request("textDocument/hover", nil)
+	assert.Equal(t, -32002.0, rpcErr["code"])
+
+	res, rpcErr := c.request("initialize", map[string]interface{}{})
+	assert.Nil(t, rpcErr)
+	capabilities := res.(map[string]interface{})["capabilities"].(map[string]interface{})
+	assert.Equal(t, true, capabilities["hoverProvider"])
+	assert.Equal(t, true, capabilities["definitionProvider"])
+	assert.Equal(t, true, capabilities["documentSymbolProvider"])
+	c.notify("initialized", map[string]interface{}{})
+
+	_, rpcErr = c.request("unknown/method", nil)
+	assert.Equal(t, -32601.0, rpcErr["code"])
+
+	// diagnostics
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri":        testURI,
+			"languageId": "tengo",
+			"version":    1,
+			"text":       testSource,
+		},
+	})
+	assert.Equal(t, []string{}, c.diagnostics())
+
+	c.change(2, testSource+"y := z\nw = 1\n")
+	assert.Equal(t, []string{"8:5-8:6: unresolved reference 'z'", "9:0-9:1: unresolved reference 'w'"}, c.diagnostics())
+
+	c.change(3, testSource+"y := \n")
+	assert.Equal(t, []string{"9:0-9:0: expected operand, found 'EOF'"}, c.diagnostics())
+
+	c.change(4, testSource)
+	assert.Equal(t, []string{}, c.diagnostics())
+
+	// hover
+	res, _ = c.request("textDocument/hover", position(6, 6))
+	assert.Equal(t, "```tengo\nadd := func(a, b)\n```\n\nadd returns the sum.", hoverText(res))
+	res, _ = c.request("textDocument/hover", position(4, 9))
+	assert.Equal(t, "```tengo\nparameter a\n```", hoverText(res))
+	res, _ = c.request("textDocument/hover", position(7, 6))
+	assert.Equal(t, "```tengo\nfmt.println (user-function:println)\n```", hoverText(res))
+	res, _ = c.request("textDocument/hover", position(1, 0))
+	assert.Nil(t, res)
+
+	// definition
+	res, _ = c.request("textDocument/definition", position(7, 12))
+	assert.Equal(t, "6:0-6:1", rangeText(res.(map[string]interface{})["range"]))
+	res, _ = c.request("textDocument/definition", position(4, 13))
+	assert.Equal(t, "3:15-3:16", rangeText(res.(map[string]interface{})["range"]))
+	res, _ = c.request("textDocument/definition", position(6, 0))
+	assert.Equal(t, "6:0-6:1", rangeText(res.(map[string]interface{})["range"]))
+
+	// completion of the module members uses the last parsed version
+	c.change(5, testSource+"fmt.pr")
+	c.diagnostics()
+	res, _ = c.request("textDocument/completion", position(8, 6))
+	assert.Equal(t, []string{"print", "printf", "println"}, completionLabels(res))
+
+	c.change(6, testSource+"ad")
+	c.diagnostics()
+	res, _ = c.request("textDocument/completion", position(8, 2))
+	assert.Equal(t, []string{"add"}, completionLabels(res))
+	res, _ = c.request("textDocument/completion", position(4, 9))
+	assert.Equal(t, []string{"a", "add", "append"}, completionLabels(res))
+
+	// document symbols
+	res, _ = c.request("textDocument/documentSymbol", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": testURI},
+	})
+	var symbols []string
+	for _, s := range res.([]interface{}) {
+		s := s.(map[string]interface{})
+		symbols = append(symbols, fmt.Sprintf("%s %v %s", s["name"], s["kind"], rangeText(s["range"])))
+	}
+	assert.Equal(t, []string{"fmt 2 0:0-0:20", "add 12 3:0-5:1", "x 13 6:0-6:14"}, symbols)
+
+	c.notify("textDocument/didClose", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": testURI},
+	})
+	assert.Equal(t, []string{}, c.diagnostics())
+
+	res, rpcErr = c.request("shutdown", nil)
+	assert.Nil(t, res)
+	assert.Nil(t, rpcErr)
+	c.notify("exit", nil)
+	assert.NoError(t, c.wait())
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	c := newClient(t)
+	_, _ = c.request("initialize", map[string]interface{}{})
+	c.notify("exit", nil)
+	assert.Equal(t, lsp.ErrExitWithoutShutdown, c.wait())
+}
+
+type client struct {
+	t             *testing.T
+	in            *io.PipeWriter
+	out           *bufio.Reader
+	nextID        int
+	notifications []map[string]interface{}
+	done          chan error
+}
+
+// newClient starts the server and returns a client connected to its
+// standard input and output.
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	c := &client{
+		t:    t,
+		in:   inW,
+		out:  bufio.NewReader(outR),
+		done: make(chan error, 1),
+	}
+
+	go func() {
+		err := lsp.NewServer(stdlib.GetModuleMap(stdlib.AllModuleNames()...)).Serve(inR, outW)
+		_ = outW.Close()
+		c.done <- err
+	}()
+
+	return c
+}
+
+func (c *client) send(msg map[string]interface{}) {
+	msg["jsonrpc"] = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if _, err := fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) read() map[string]interface{} {
+	length := 0
+	for {
+		line, err := c.out.ReadString('\n')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+
+		if strings.HasPrefix(line, "Content-Length:") {
+			length, _ = strconv.Atoi(strings.TrimSpace(line[len("Content-Length:"):]))
+		}
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(c.out, data); err != nil {
+		c.t.Fatal(err)
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+
+	return msg
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"method": method, "params": params})
+}
+
+// request sends a request and returns the result and the error of the
+// response. Notifications received before the response are queued.
+func (c *client) request(method string, params interface{}) (interface{}, map[string]interface{}) {
+	c.nextID++
+	id := c.nextID
+	c.send(map[string]interface{}{"id": id, "method": method, "params": params})
+
+	for {
+		msg := c.read()
+		if _, ok := msg["method"]; ok {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+
+		assert.Equal(c.t, float64(id), msg["id"])
+		if rpcErr, ok := msg["error"]; ok {
+			return nil, rpcErr.(map[string]interface{})
+		}
+
+		if _, ok := msg["result"]; !ok {
+			c.t.Fatal("response without result")
+		}
+
+		return msg["result"], nil
+	}
+}
+
+func (c *client) change(version int, text string) {
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": testURI, "version": version},
+		"contentChanges": []interface{}{map[string]interface{}{"text": text}},
+	})
+}
+
+// diagnostics returns the next published diagnostics.
+func (c *client) diagnostics() []string {
+	var msg map[string]interface{}
+	if len(c.notifications) > 0 {
+		msg, c.notifications = c.notifications[0], c.notifications[1:]
+	} else {
+		msg = c.read()
+	}
+
+	assert.Equal(c.t, "textDocument/publishDiagnostics", msg["method"])
+	params := msg["params"].(map[string]interface{})
+	assert.Equal(c.t, testURI, params["uri"])
+
+	res := []string{}
+	for _, d := range params["diagnostics"].([]interface{}) {
+		d := d.(map[string]interface{})
+		res = append(res, rangeText(d["range"])+": "+d["message"].(string))
+	}
+
+	return res
+}
+
+func (c *client) wait() error {
+	_ = c.in.Close()
+	return <-c.done
+}
+
+func position(line, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": testURI},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+
+func rangeText(v interface{}) string {
+	r := v.(map[string]interface{})
+	start := r["start"].(map[string]interface{})
+	end := r["end"].(map[string]interface{})
+
+	return fmt.Sprintf("%v:%v-%v:%v", start["line"], start["character"], end["line"], end["character"])
+}
+
+func hoverText(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	return v.(map[string]interface{})["contents"].(map[string]interface{})["value"].(string)
+}
+
+func completionLabels(v interface{}) []string {
+	var labels []string
+	for _, item := range v.(map[string]interface{})["items"].([]interface{}) {
+		labels = append(labels, item.(map[string]interface{})["label"].(string))
+	}
+
+	return labels
+}
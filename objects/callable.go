@@ -1,5 +1,7 @@
 package objects
 
+import "context"
+
 // Callable represents an object that can be called like a function.
 type Callable interface {
 	// Call should take an arbitrary number of arguments
@@ -7,3 +9,16 @@ type Callable interface {
 	// which the VM will consider as a run-time error.
 	Call(args ...Object) (ret Object, err error)
 }
+
+// ContextCallable represents a callable object that takes the context of
+// the run, so that a blocking call can return early when the context is
+// done. The VM prefers CallContext over Call when it invokes a
+// ContextCallable.
+type ContextCallable interface {
+	Callable
+
+	// CallContext should take the context of the run and an arbitrary
+	// number of arguments and returns a return value and/or an error,
+	// which the VM will consider as a run-time error.
+	CallContext(ctx context.Context, args ...Object) (ret Object, err error)
+}
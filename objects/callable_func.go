@@ -1,4 +1,10 @@
 package objects
 
+import "context"
+
 // CallableFunc is a function signature for the callable functions.
 type CallableFunc = func(args ...Object) (ret Object, err error)
+
+// ContextCallableFunc is a function signature for the callable functions
+// that take the context of the run.
+type ContextCallableFunc = func(ctx context.Context, args ...Object) (ret Object, err error)
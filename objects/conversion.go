@@ -2,7 +2,7 @@ package objects
 
 import (
 	"errors"
-	"fmt"
+	"reflect"
 	"strconv"
 	"time"
 
@@ -200,6 +200,8 @@ func ToInterface(o Object) (res interface{}) {
 		res = errors.New(o.String())
 	case *Undefined:
 		res = nil
+	case *GoStruct:
+		res = o.Interface()
 	case Object:
 		return o
 	}
@@ -270,7 +272,10 @@ func FromInterface(v interface{}) (Object, error) {
 		return v, nil
 	case CallableFunc:
 		return &UserFunction{Value: v}, nil
+	case ContextCallableFunc:
+		return &UserFunction{ContextValue: v}, nil
 	}
 
-	return nil, fmt.Errorf("cannot convert to object: %T", v)
+	// structs, pointers, functions and the other Go types
+	return fromReflect(reflect.ValueOf(v))
 }
@@ -0,0 +1,331 @@
+package objects
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var objectType = reflect.TypeOf((*Object)(nil)).Elem()
+
+// Decode stores the object into the Go value pointed to by out. Maps are
+// decoded into structs and maps with string keys, arrays into slices and Go
+// arrays, and the other objects into the values of the matching kinds:
+//
+//	Int            int, uint and their sized variants, float32, float64
+//	Float          float32, float64
+//	String, Char   string
+//	Bool           bool
+//	Bytes, String  []byte
+//	Time           time.Time
+//
+// The struct fields are named by their "tengo" struct tags, the same way
+// they are exposed by GoStruct, and the map keys without fields are
+// ignored. The fields and the map entries not in the object keep their
+// values. Undefined sets the value to its zero value. The fields of type
+// Object (or its implementations) take the objects as they are, and the
+// fields of type interface{} take the values of ToInterface.
+//
+// If an object does not match the type of the Go value, ErrDecode is
+// returned with the path of the object, e.g. "servers[2].port".
+func Decode(o Object, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("decode: out must be a non-nil pointer")
+	}
+
+	return decodeValue(o, v.Elem(), "")
+}
+
+func decodeValue(o Object, v reflect.Value, path string) error {
+	t := v.Type()
+
+	if o == UndefinedValue {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+
+	// the values of Object types take the objects as they are
+	if t.Kind() == reflect.Interface && t.NumMethod() > 0 || t.Implements(objectType) {
+		if reflect.TypeOf(o).AssignableTo(t) {
+			v.Set(reflect.ValueOf(o))
+			return nil
+		}
+
+		return decodeError(path, t, o)
+	}
+
+	if s, ok := o.(*GoStruct); ok {
+		switch {
+		case s.value.Type().AssignableTo(t):
+			v.Set(s.value)
+			return nil
+		case s.value.Kind() == reflect.Ptr && s.value.Type().Elem().AssignableTo(t):
+			v.Set(s.value.Elem())
+			return nil
+		}
+	}
+
+	if t == timeType {
+		tm, ok := o.(*Time)
+		if !ok {
+			return decodeError(path, t, o)
+		}
+
+		v.Set(reflect.ValueOf(tm.Value))
+
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if res := ToInterface(o); res != nil {
+			v.Set(reflect.ValueOf(res))
+		} else {
+			v.Set(reflect.Zero(t))
+		}
+
+		return nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+
+		return decodeValue(o, v.Elem(), path)
+	case reflect.Bool:
+		b, ok := o.(*Bool)
+		if !ok {
+			return decodeError(path, t, o)
+		}
+
+		v.SetBool(!b.IsFalsy())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := decodeInt(o)
+		if !ok {
+			return decodeError(path, t, o)
+		}
+		if v.OverflowInt(i) {
+			return ErrDecode{Path: path, Expected: t.String(), Found: strconv.FormatInt(i, 10)}
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := decodeInt(o)
+		if !ok {
+			return decodeError(path, t, o)
+		}
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return ErrDecode{Path: path, Expected: t.String(), Found: strconv.FormatInt(i, 10)}
+		}
+
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		switch o := o.(type) {
+		case *Float:
+			v.SetFloat(o.Value)
+		case *Int:
+			v.SetFloat(float64(o.Value))
+		default:
+			return decodeError(path, t, o)
+		}
+	case reflect.String:
+		switch o := o.(type) {
+		case *String:
+			v.SetString(o.Value)
+		case *Char:
+			v.SetString(string(o.Value))
+		default:
+			return decodeError(path, t, o)
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			switch o := o.(type) {
+			case *Bytes:
+				v.SetBytes(append([]byte{}, o.Value...))
+			case *String:
+				v.SetBytes([]byte(o.Value))
+			default:
+				return decodeError(path, t, o)
+			}
+
+			return nil
+		}
+
+		arr, ok := decodeArray(o)
+		if !ok {
+			return decodeError(path, t, o)
+		}
+
+		s := reflect.MakeSlice(t, len(arr), len(arr))
+		for i, e := range arr {
+			if err := decodeValue(e, s.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+
+		v.Set(s)
+	case reflect.Array:
+		arr, ok := decodeArray(o)
+		if !ok || len(arr) != t.Len() {
+			return decodeError(path, t, o)
+		}
+
+		for i, e := range arr {
+			if err := decodeValue(e, v.Index(i), indexPath(path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("%s: cannot decode into %s", pathName(path), t)
+		}
+
+		m, ok := decodeMap(o)
+		if !ok {
+			return decodeError(path, t, o)
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, len(m)))
+		}
+
+		for k, e := range m {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := decodeValue(e, elem, keyPath(path, k)); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+		}
+	case reflect.Struct:
+		m, ok := decodeMap(o)
+		if !ok {
+			return decodeError(path, t, o)
+		}
+
+		fields := structFields(v)
+		for k, e := range m {
+			field, ok := fields[k]
+			if !ok {
+				continue
+			}
+
+			if err := decodeValue(e, v.FieldByIndex(field.index), keyPath(path, k)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s: cannot decode into %s", pathName(path), t)
+	}
+
+	return nil
+}
+
+func decodeInt(o Object) (int64, bool) {
+	switch o := o.(type) {
+	case *Int:
+		return o.Value, true
+	case *Char:
+		return int64(o.Value), true
+	}
+
+	return 0, false
+}
+
+func decodeArray(o Object) ([]Object, bool) {
+	switch o := o.(type) {
+	case *Array:
+		return o.Value, true
+	case *ImmutableArray:
+		return o.Value, true
+	}
+
+	return nil, false
+}
+
+func decodeMap(o Object) (map[string]Object, bool) {
+	switch o := o.(type) {
+	case *Map:
+		return o.Value, true
+	case *ImmutableMap:
+		return o.Value, true
+	}
+
+	return nil, false
+}
+
+func decodeError(path string, t reflect.Type, o Object) error {
+	return ErrDecode{Path: path, Expected: decodeTypeName(t), Found: o.TypeName()}
+}
+
+// decodeTypeName returns the name of the object type that the Go type is
+// decoded from.
+func decodeTypeName(t reflect.Type) string {
+	if t == timeType {
+		return "time"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytes"
+		}
+
+		return "array"
+	case reflect.Array:
+		return fmt.Sprintf("array of %d elements", t.Len())
+	case reflect.Map, reflect.Struct:
+		return "map"
+	}
+
+	return t.String()
+}
+
+func indexPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+func keyPath(path, key string) string {
+	if !isIdentifier(key) {
+		return path + "[" + strconv.Quote(key) + "]"
+	}
+
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+func pathName(path string) string {
+	if path == "" {
+		return "value"
+	}
+
+	return path
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, c := range s {
+		if c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9' {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,171 @@
+package objects_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+)
+
+type testServer struct {
+	Host    string   `tengo:"host"`
+	Port    uint16   `tengo:"port"`
+	Enabled bool     `tengo:"enabled"`
+	Weight  float64  `tengo:"weight"`
+	Aliases []string `tengo:"aliases"`
+}
+
+type testConfig struct {
+	Name     string                 `tengo:"name"`
+	Servers  []testServer           `tengo:"servers"`
+	Primary  *testServer            `tengo:"primary"`
+	Labels   map[string]string      `tengo:"labels"`
+	Started  time.Time              `tengo:"started"`
+	Key      []byte                 `tengo:"key"`
+	Extra    interface{}            `tengo:"extra"`
+	Raw      objects.Object         `tengo:"raw"`
+	Pair     [2]int                 `tengo:"pair"`
+	Defaults map[string]interface{} `tengo:"defaults"`
+	Timeout  int
+}
+
+func TestDecode(t *testing.T) {
+	now := time.Now()
+
+	var cfg testConfig
+	cfg.Timeout = 30
+	err := objects.Decode(decodeMap(map[string]objects.Object{
+		"name": &objects.String{Value: "prod"},
+		"servers": &objects.Array{Value: []objects.Object{
+			decodeMap(map[string]objects.Object{
+				"host":    &objects.String{Value: "a"},
+				"port":    &objects.Int{Value: 80},
+				"enabled": objects.TrueValue,
+				"weight":  &objects.Int{Value: 2},
+				"aliases": &objects.ImmutableArray{Value: []objects.Object{&objects.String{Value: "x"}, &objects.Char{Value: 'y'}}},
+				"unknown": &objects.Int{Value: 1},
+			}),
+			decodeMap(map[string]objects.Object{
+				"host":   &objects.String{Value: "b"},
+				"weight": &objects.Float{Value: 0.5},
+			}),
+		}},
+		"primary": &objects.ImmutableMap{Value: map[string]objects.Object{"host": &objects.String{Value: "p"}}},
+		"labels":  decodeMap(map[string]objects.Object{"env": &objects.String{Value: "prod"}}),
+		"started": &objects.Time{Value: now},
+		"key":     &objects.Bytes{Value: []byte("secret")},
+		"extra":   &objects.Array{Value: []objects.Object{&objects.Int{Value: 1}}},
+		"raw":     &objects.Int{Value: 7},
+		"pair":    &objects.Array{Value: []objects.Object{&objects.Int{Value: 1}, &objects.Int{Value: 2}}},
+	}), &cfg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "prod", cfg.Name)
+	assert.Equal(t, 2, len(cfg.Servers))
+	assert.Equal(t, "a", cfg.Servers[0].Host)
+	assert.Equal(t, 80, int(cfg.Servers[0].Port))
+	assert.True(t, cfg.Servers[0].Enabled)
+	assert.Equal(t, 2.0, cfg.Servers[0].Weight)
+	assert.Equal(t, 2, len(cfg.Servers[0].Aliases))
+	assert.Equal(t, "y", cfg.Servers[0].Aliases[1])
+	assert.Equal(t, "b", cfg.Servers[1].Host)
+	assert.Equal(t, 0.5, cfg.Servers[1].Weight)
+	assert.Equal(t, "p", cfg.Primary.Host)
+	assert.Equal(t, "prod", cfg.Labels["env"])
+	assert.True(t, now.Equal(cfg.Started))
+	assert.Equal(t, []byte("secret"), cfg.Key)
+	assert.Equal(t, 1, len(cfg.Extra.([]interface{})))
+	assert.Equal(t, &objects.Int{Value: 7}, cfg.Raw)
+	assert.Equal(t, 2, cfg.Pair[1])
+	assert.Equal(t, 30, cfg.Timeout) // not in the map
+
+	// undefined sets the zero value
+	err = objects.Decode(decodeMap(map[string]objects.Object{
+		"primary": objects.UndefinedValue,
+		"name":    objects.UndefinedValue,
+	}), &cfg)
+	assert.NoError(t, err)
+	assert.True(t, cfg.Primary == nil)
+	assert.Equal(t, "", cfg.Name)
+
+	// scalars
+	var i int
+	assert.NoError(t, objects.Decode(&objects.Int{Value: 5}, &i))
+	assert.Equal(t, 5, i)
+	var s string
+	assert.NoError(t, objects.Decode(&objects.String{Value: "foo"}, &s))
+	assert.Equal(t, "foo", s)
+	var p *int
+	assert.NoError(t, objects.Decode(&objects.Int{Value: 3}, &p))
+	assert.Equal(t, 3, *p)
+	var m map[string]int
+	assert.NoError(t, objects.Decode(decodeMap(map[string]objects.Object{"a": &objects.Int{Value: 1}}), &m))
+	assert.Equal(t, 1, m["a"])
+}
+
+func TestDecode_Errors(t *testing.T) {
+	expectDecodeError(t, decodeMap(map[string]objects.Object{
+		"servers": &objects.Array{Value: []objects.Object{
+			decodeMap(nil),
+			decodeMap(nil),
+			decodeMap(map[string]objects.Object{"port": &objects.String{Value: "80"}}),
+		}},
+	}), &testConfig{}, "servers[2].port: expected int, found string")
+
+	expectDecodeError(t, decodeMap(map[string]objects.Object{
+		"servers": &objects.Array{Value: []objects.Object{
+			decodeMap(map[string]objects.Object{"port": &objects.Int{Value: 70000}}),
+		}},
+	}), &testConfig{}, "servers[0].port: expected uint16, found 70000")
+
+	expectDecodeError(t, decodeMap(map[string]objects.Object{
+		"labels": decodeMap(map[string]objects.Object{"a b": &objects.Int{Value: 1}}),
+	}), &testConfig{}, `labels["a b"]: expected string, found int`)
+
+	expectDecodeError(t, decodeMap(map[string]objects.Object{
+		"primary": decodeMap(map[string]objects.Object{"aliases": &objects.String{Value: "x"}}),
+	}), &testConfig{}, "primary.aliases: expected array, found string")
+
+	expectDecodeError(t, decodeMap(map[string]objects.Object{
+		"started": &objects.Int{Value: 1},
+	}), &testConfig{}, "started: expected time, found int")
+
+	expectDecodeError(t, decodeMap(map[string]objects.Object{
+		"pair": &objects.Array{},
+	}), &testConfig{}, "pair: expected array of 2 elements, found array")
+
+	expectDecodeError(t, decodeMap(map[string]objects.Object{
+		"servers": &objects.Array{Value: []objects.Object{
+			decodeMap(map[string]objects.Object{"enabled": &objects.Int{Value: 1}}),
+		}},
+	}), &testConfig{}, "servers[0].enabled: expected bool, found int")
+
+	expectDecodeError(t, &objects.Int{Value: 1}, &testConfig{}, "expected map, found int")
+	expectDecodeError(t, &objects.Float{Value: 1}, new(int), "expected int, found float")
+	expectDecodeError(t, &objects.Int{Value: -1}, new(uint), "expected uint, found -1")
+	expectDecodeError(t, &objects.Int{Value: 1}, new(chan int), "value: cannot decode into chan int")
+	expectDecodeError(t, decodeMap(nil), new(map[int]int), "value: cannot decode into map[int]int")
+
+	err := objects.Decode(&objects.Int{Value: 1}, 1)
+	assert.Error(t, err)
+	err = objects.Decode(&objects.Int{Value: 1}, (*int)(nil))
+	assert.Error(t, err)
+}
+
+func decodeMap(m map[string]objects.Object) *objects.Map {
+	if m == nil {
+		m = make(map[string]objects.Object)
+	}
+
+	return &objects.Map{Value: m}
+}
+
+func expectDecodeError(t *testing.T, o objects.Object, out interface{}, expected string) {
+	err := objects.Decode(o, out)
+	if !assert.Error(t, err) {
+		return
+	}
+
+	assert.Equal(t, expected, err.Error())
+}
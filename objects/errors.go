@@ -36,3 +36,20 @@ type ErrInvalidArgumentType struct {
 func (e ErrInvalidArgumentType) Error() string {
 	return fmt.Sprintf("invalid type for argument '%s': expected %s, found %s", e.Name, e.Expected, e.Found)
 }
+
+// ErrDecode represents an error where an object cannot be decoded into a Go
+// value. Path is the location of the object in the decoded value, e.g.
+// "servers[2].port".
+type ErrDecode struct {
+	Path     string
+	Expected string
+	Found    string
+}
+
+func (e ErrDecode) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("expected %s, found %s", e.Expected, e.Found)
+	}
+
+	return fmt.Sprintf("%s: expected %s, found %s", e.Path, e.Expected, e.Found)
+}
@@ -0,0 +1,211 @@
+package objects
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/d5/tengo/compiler/token"
+)
+
+// GoStruct represents a Go struct, or a pointer to a Go struct, exposed to
+// the scripts by reflection. The exported fields are accessed with the
+// index operator (or selector), and the exported methods are called like
+// functions with the arguments and the return values converted.
+//
+// The fields are named by their "tengo" struct tags, or by the field names
+// if not tagged. The tag "-" hides a field, and the "readonly" option makes
+// it read-only:
+//
+//	type User struct {
+//		Name  string `tengo:"name"`
+//		ID    int64  `tengo:"id,readonly"`
+//		Token string `tengo:"-"`
+//	}
+//
+// The fields can be assigned only if the value is a pointer to the struct.
+// Use FromInterface to create a GoStruct.
+type GoStruct struct {
+	value reflect.Value
+}
+
+// goStructField is an exported field of a struct type.
+type goStructField struct {
+	index    []int
+	readOnly bool
+}
+
+// goStructFields caches the fields of the struct types by their names.
+var goStructFields sync.Map // reflect.Type => map[string]*goStructField
+
+// TypeName returns the name of the type.
+func (o *GoStruct) TypeName() string {
+	return o.structValue().Type().Name()
+}
+
+func (o *GoStruct) String() string {
+	return fmt.Sprintf("%+v", o.structValue().Interface())
+}
+
+// BinaryOp returns another object that is the result of
+// a given binary operator and a right-hand side object.
+func (o *GoStruct) BinaryOp(op token.Token, rhs Object) (Object, error) {
+	return nil, ErrInvalidOperator
+}
+
+// Copy returns a copy of the type. The copy of a pointer points to a
+// shallow copy of the struct.
+func (o *GoStruct) Copy() Object {
+	if o.value.Kind() != reflect.Ptr {
+		return &GoStruct{value: o.value}
+	}
+
+	c := reflect.New(o.value.Type().Elem())
+	c.Elem().Set(o.value.Elem())
+
+	return &GoStruct{value: c}
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (o *GoStruct) IsFalsy() bool {
+	return false
+}
+
+// Equals returns true if the value of the type
+// is equal to the value of another object.
+func (o *GoStruct) Equals(x Object) bool {
+	t, ok := x.(*GoStruct)
+	if !ok {
+		return false
+	}
+
+	if o.value.Kind() == reflect.Ptr && t.value.Kind() == reflect.Ptr {
+		return o.value.Pointer() == t.value.Pointer()
+	}
+
+	return reflect.DeepEqual(o.structValue().Interface(), t.structValue().Interface())
+}
+
+// Interface returns the Go value: the struct or the pointer to the struct.
+func (o *GoStruct) Interface() interface{} {
+	return o.value.Interface()
+}
+
+// IndexGet returns the value of the field, or the method of the given name.
+func (o *GoStruct) IndexGet(index Object) (Object, error) {
+	name, ok := index.(*String)
+	if !ok {
+		return nil, ErrInvalidIndexType
+	}
+
+	if field := structFields(o.value)[name.Value]; field != nil {
+		return fromReflect(o.structValue().FieldByIndex(field.index))
+	}
+
+	if method := o.value.MethodByName(name.Value); method.IsValid() {
+		return &UserFunction{Name: name.Value, Value: reflectFunc(method)}, nil
+	}
+
+	return UndefinedValue, nil
+}
+
+// IndexSet assigns the value to the field of the given name.
+func (o *GoStruct) IndexSet(index, value Object) error {
+	name, ok := index.(*String)
+	if !ok {
+		return ErrInvalidIndexType
+	}
+
+	field := structFields(o.value)[name.Value]
+	if field == nil {
+		return fmt.Errorf("field '%s' not found", name.Value)
+	}
+
+	if field.readOnly || o.value.Kind() != reflect.Ptr {
+		return fmt.Errorf("field '%s' is read-only", name.Value)
+	}
+
+	fv := o.structValue().FieldByIndex(field.index)
+	v, ok := toReflect(value, fv.Type())
+	if !ok {
+		return fmt.Errorf("invalid type for field '%s': expected %s, found %s", name.Value, fv.Type(), value.TypeName())
+	}
+	fv.Set(v)
+
+	return nil
+}
+
+func (o *GoStruct) structValue() reflect.Value {
+	if o.value.Kind() == reflect.Ptr {
+		return o.value.Elem()
+	}
+
+	return o.value
+}
+
+// structFields returns the exported fields of the struct (or the pointer to
+// the struct) keyed by their names. The fields of the embedded structs are
+// promoted unless the outer struct has the fields of the same names.
+func structFields(v reflect.Value) map[string]*goStructField {
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if fields, ok := goStructFields.Load(t); ok {
+		return fields.(map[string]*goStructField)
+	}
+
+	fields := make(map[string]*goStructField)
+	collectStructFields(t, nil, fields)
+	goStructFields.Store(t, fields)
+
+	return fields
+}
+
+func collectStructFields(t reflect.Type, index []int, fields map[string]*goStructField) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("tengo")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if n := strings.IndexByte(tag, ','); n >= 0 {
+			name, opts = tag[:n], tag[n+1:]
+		}
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, f)
+			continue
+		}
+
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fields[name] = &goStructField{
+			index:    append(append([]int{}, index...), i),
+			readOnly: opts == "readonly",
+		}
+	}
+
+	for _, f := range embedded {
+		promoted := make(map[string]*goStructField)
+		collectStructFields(f.Type, append(append([]int{}, index...), f.Index...), promoted)
+
+		for name, field := range promoted {
+			if _, exists := fields[name]; !exists {
+				fields[name] = field
+			}
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package objects_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+)
+
+type testAddress struct {
+	City string `tengo:"city"`
+}
+
+type testUser struct {
+	testAddress
+	Name    string         `tengo:"name"`
+	ID      int64          `tengo:"id,readonly"`
+	Tags    []string       `tengo:"tags"`
+	Attrs   map[string]int `tengo:"attrs"`
+	Manager *testUser      `tengo:"manager"`
+	Secret  string         `tengo:"-"`
+	Age     uint8
+	hidden  int
+}
+
+func (u *testUser) Greet(greeting string, names ...string) string {
+	return greeting + " " + strings.Join(append([]string{u.Name}, names...), ", ")
+}
+
+func (u *testUser) Rename(name string) error {
+	if name == "" {
+		return errors.New("empty name")
+	}
+	u.Name = name
+	return nil
+}
+
+func (u testUser) Split() (string, int64) {
+	return u.Name, u.ID
+}
+
+func TestGoStruct(t *testing.T) {
+	u := &testUser{
+		testAddress: testAddress{City: "Seoul"},
+		Name:        "kim",
+		ID:          7,
+		Tags:        []string{"a", "b"},
+		Secret:      "s",
+		hidden:      1,
+	}
+
+	o, err := objects.FromInterface(u)
+	assert.NoError(t, err)
+	s, ok := o.(*objects.GoStruct)
+	assert.True(t, ok)
+	assert.Equal(t, "testUser", s.TypeName())
+	assert.True(t, s.Interface() == u)
+	assert.True(t, objects.ToInterface(s) == u)
+
+	// fields
+	expectIndexGet(t, s, "name", &objects.String{Value: "kim"})
+	expectIndexGet(t, s, "id", &objects.Int{Value: 7})
+	expectIndexGet(t, s, "city", &objects.String{Value: "Seoul"})
+	expectIndexGet(t, s, "Age", &objects.Int{Value: 0})
+	expectIndexGet(t, s, "tags", &objects.Array{Value: []objects.Object{
+		&objects.String{Value: "a"}, &objects.String{Value: "b"}}})
+	expectIndexGet(t, s, "manager", objects.UndefinedValue)
+	expectIndexGet(t, s, "Secret", objects.UndefinedValue)
+	expectIndexGet(t, s, "hidden", objects.UndefinedValue)
+
+	assert.NoError(t, s.IndexSet(&objects.String{Value: "name"}, &objects.String{Value: "lee"}))
+	assert.Equal(t, "lee", u.Name)
+	assert.NoError(t, s.IndexSet(&objects.String{Value: "Age"}, &objects.Int{Value: 30}))
+	assert.Equal(t, 30, int(u.Age))
+	assert.NoError(t, s.IndexSet(&objects.String{Value: "attrs"}, &objects.Map{Value: map[string]objects.Object{
+		"x": &objects.Int{Value: 1}}}))
+	assert.Equal(t, 1, u.Attrs["x"])
+	assert.NoError(t, s.IndexSet(&objects.String{Value: "manager"}, s))
+	assert.True(t, u.Manager == u)
+
+	err = s.IndexSet(&objects.String{Value: "id"}, &objects.Int{Value: 8})
+	assert.Equal(t, "field 'id' is read-only", err.Error())
+	err = s.IndexSet(&objects.String{Value: "Age"}, &objects.Int{Value: 256})
+	assert.Equal(t, "invalid type for field 'Age': expected uint8, found int", err.Error())
+	err = s.IndexSet(&objects.String{Value: "tags"}, &objects.Int{Value: 1})
+	assert.Equal(t, "invalid type for field 'tags': expected []string, found int", err.Error())
+	err = s.IndexSet(&objects.String{Value: "Secret"}, &objects.String{Value: "x"})
+	assert.Equal(t, "field 'Secret' not found", err.Error())
+
+	// methods
+	res := callMethod(t, s, "Greet", &objects.String{Value: "hi"})
+	assert.Equal(t, &objects.String{Value: "hi lee"}, res)
+	res = callMethod(t, s, "Greet", &objects.String{Value: "hi"}, &objects.String{Value: "park"}, &objects.String{Value: "choi"})
+	assert.Equal(t, &objects.String{Value: "hi lee, park, choi"}, res)
+	res = callMethod(t, s, "Rename", &objects.String{Value: "choi"})
+	assert.Equal(t, objects.UndefinedValue, res)
+	assert.Equal(t, "choi", u.Name)
+	res = callMethod(t, s, "Rename", &objects.String{Value: ""})
+	assert.Equal(t, &objects.Error{Value: &objects.String{Value: "empty name"}}, res)
+	res = callMethod(t, s, "Split")
+	assert.Equal(t, &objects.Array{Value: []objects.Object{
+		&objects.String{Value: "choi"}, &objects.Int{Value: 7}}}, res)
+
+	fn, err := s.IndexGet(&objects.String{Value: "Greet"})
+	assert.NoError(t, err)
+	_, err = fn.(objects.Callable).Call()
+	assert.Equal(t, objects.ErrWrongNumArguments, err)
+	_, err = fn.(objects.Callable).Call(objects.UndefinedValue)
+	assert.Equal(t, "invalid type for argument 'first': expected string, found undefined", err.Error())
+
+	// struct values are read-only
+	o, err = objects.FromInterface(testUser{Name: "kim"})
+	assert.NoError(t, err)
+	expectIndexGet(t, o.(*objects.GoStruct), "name", &objects.String{Value: "kim"})
+	err = o.(*objects.GoStruct).IndexSet(&objects.String{Value: "name"}, &objects.String{Value: "lee"})
+	assert.Equal(t, "field 'name' is read-only", err.Error())
+	assert.True(t, o.Equals(o.Copy()))
+
+	// copies of the pointers point to different structs
+	c := s.Copy().(*objects.GoStruct)
+	assert.False(t, s.Equals(c))
+	assert.NoError(t, c.IndexSet(&objects.String{Value: "name"}, &objects.String{Value: "yoon"}))
+	assert.Equal(t, "choi", u.Name)
+
+	var nilUser *testUser
+	o, err = objects.FromInterface(nilUser)
+	assert.NoError(t, err)
+	assert.Equal(t, objects.UndefinedValue, o)
+}
+
+func TestFromInterface_Reflect(t *testing.T) {
+	o, err := objects.FromInterface(func(a, b int) int { return a + b })
+	assert.NoError(t, err)
+	res, err := o.(objects.Callable).Call(&objects.Int{Value: 1}, &objects.Int{Value: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.Int{Value: 3}, res)
+
+	o, err = objects.FromInterface([]int32{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.Array{Value: []objects.Object{&objects.Int{Value: 1}, &objects.Int{Value: 2}}}, o)
+
+	o, err = objects.FromInterface(map[string]float32{"a": 0.5})
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.Map{Value: map[string]objects.Object{"a": &objects.Float{Value: 0.5}}}, o)
+
+	_, err = objects.FromInterface(map[int]string{})
+	assert.Equal(t, "cannot convert to object: map[int]string", err.Error())
+}
+
+func expectIndexGet(t *testing.T, s *objects.GoStruct, name string, expected objects.Object) {
+	res, err := s.IndexGet(&objects.String{Value: name})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, res)
+}
+
+func callMethod(t *testing.T, s *objects.GoStruct, name string, args ...objects.Object) objects.Object {
+	fn, err := s.IndexGet(&objects.String{Value: name})
+	assert.NoError(t, err)
+
+	res, err := fn.(objects.Callable).Call(args...)
+	assert.NoError(t, err)
+
+	return res
+}
@@ -0,0 +1,57 @@
+package objects
+
+import (
+	"fmt"
+)
+
+// IteratorState is the state of an iterator of the builtin types. It is used
+// to save and restore the iterators, e.g. by the VM snapshots.
+type IteratorState struct {
+	// Values is the iterated values: an *Array, *ImmutableArray, *Map,
+	// *ImmutableMap, *String or *Bytes.
+	Values Object
+
+	// Keys is the keys of the map in the iteration order.
+	Keys []string
+
+	// Pos is the number of the iterated elements.
+	Pos int
+}
+
+// IteratorStateOf returns the state of the iterator, or false if the
+// iterator is not an iterator of the builtin types.
+func IteratorStateOf(it Iterator) (*IteratorState, bool) {
+	switch it := it.(type) {
+	case *ArrayIterator:
+		return &IteratorState{Values: &Array{Value: it.v}, Pos: it.i}, true
+	case *MapIterator:
+		return &IteratorState{Values: &Map{Value: it.v}, Keys: it.k, Pos: it.i}, true
+	case *StringIterator:
+		return &IteratorState{Values: &String{Value: string(it.v)}, Pos: it.i}, true
+	case *BytesIterator:
+		return &IteratorState{Values: &Bytes{Value: it.v}, Pos: it.i}, true
+	}
+
+	return nil, false
+}
+
+// Iterator creates an iterator with the state.
+func (s *IteratorState) Iterator() (Iterator, error) {
+	switch v := s.Values.(type) {
+	case *Array:
+		return &ArrayIterator{v: v.Value, i: s.Pos, l: len(v.Value)}, nil
+	case *ImmutableArray:
+		return &ArrayIterator{v: v.Value, i: s.Pos, l: len(v.Value)}, nil
+	case *Map:
+		return &MapIterator{v: v.Value, k: s.Keys, i: s.Pos, l: len(s.Keys)}, nil
+	case *ImmutableMap:
+		return &MapIterator{v: v.Value, k: s.Keys, i: s.Pos, l: len(s.Keys)}, nil
+	case *String:
+		runes := []rune(v.Value)
+		return &StringIterator{v: runes, i: s.Pos, l: len(runes)}, nil
+	case *Bytes:
+		return &BytesIterator{v: v.Value, i: s.Pos, l: len(v.Value)}, nil
+	}
+
+	return nil, fmt.Errorf("invalid iterator values: %s", s.Values.TypeName())
+}
@@ -0,0 +1,299 @@
+package objects
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	timeType  = reflect.TypeOf(time.Time{})
+)
+
+// argumentNames are the names of the arguments used in the errors.
+var argumentNames = []string{"first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth", "tenth"}
+
+// fromReflect converts the Go value that FromInterface does not handle
+// directly into an object. The structs and the pointers to the structs are
+// converted into GoStruct, and the functions into UserFunction.
+func fromReflect(v reflect.Value) (Object, error) {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return UndefinedValue, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return UndefinedValue, nil
+		}
+
+		if v.Elem().Kind() == reflect.Struct && v.Type().Elem() != timeType {
+			return &GoStruct{value: v}, nil
+		}
+
+		return FromInterface(v.Elem().Interface())
+	case reflect.Interface:
+		if v.IsNil() {
+			return UndefinedValue, nil
+		}
+
+		return FromInterface(v.Elem().Interface())
+	case reflect.Bool:
+		return FromInterface(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Int{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Int{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.String:
+		return FromInterface(v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return FromInterface(b)
+		}
+
+		arr := make([]Object, v.Len())
+		for i := range arr {
+			o, err := fromReflect(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = o
+		}
+
+		return &Array{Value: arr}, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+
+		m := make(map[string]Object, v.Len())
+		for _, key := range v.MapKeys() {
+			o, err := fromReflect(v.MapIndex(key))
+			if err != nil {
+				return nil, err
+			}
+			m[key.String()] = o
+		}
+
+		return &Map{Value: m}, nil
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return &Time{Value: v.Interface().(time.Time)}, nil
+		}
+
+		return &GoStruct{value: v}, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return UndefinedValue, nil
+		}
+
+		return &UserFunction{Value: reflectFunc(v)}, nil
+	}
+
+	return nil, fmt.Errorf("cannot convert to object: %s", v.Type())
+}
+
+// toReflect converts the object into a Go value of the given type. It
+// returns false if the object cannot be converted.
+func toReflect(o Object, t reflect.Type) (reflect.Value, bool) {
+	// the parameters of Object types take the objects as they are
+	if t.Kind() != reflect.Interface || t.NumMethod() > 0 {
+		if reflect.TypeOf(o).AssignableTo(t) {
+			return reflect.ValueOf(o), true
+		}
+	}
+
+	if s, ok := o.(*GoStruct); ok {
+		switch {
+		case s.value.Type().AssignableTo(t):
+			return s.value, true
+		case s.value.Kind() == reflect.Ptr && s.value.Type().Elem().AssignableTo(t):
+			return s.value.Elem(), true
+		}
+
+		return reflect.Value{}, false
+	}
+
+	if t == timeType {
+		v, ok := ToTime(o)
+		return reflect.ValueOf(v), ok
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		if o == UndefinedValue {
+			return reflect.Zero(t), true
+		}
+
+		v := reflect.ValueOf(ToInterface(o))
+		if !v.Type().AssignableTo(t) {
+			return reflect.Value{}, false
+		}
+
+		return v, true
+	case reflect.Ptr:
+		if o == UndefinedValue {
+			return reflect.Zero(t), true
+		}
+
+		v, ok := toReflect(o, t.Elem())
+		if !ok {
+			return reflect.Value{}, false
+		}
+
+		p := reflect.New(t.Elem())
+		p.Elem().Set(v)
+
+		return p, true
+	case reflect.Bool:
+		v, ok := ToBool(o)
+		return reflect.ValueOf(v).Convert(t), ok
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, ok := ToInt64(o)
+		if !ok || reflect.Zero(t).OverflowInt(v) {
+			return reflect.Value{}, false
+		}
+
+		return reflect.ValueOf(v).Convert(t), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v, ok := ToInt64(o)
+		if !ok || v < 0 || reflect.Zero(t).OverflowUint(uint64(v)) {
+			return reflect.Value{}, false
+		}
+
+		return reflect.ValueOf(uint64(v)).Convert(t), true
+	case reflect.Float32, reflect.Float64:
+		v, ok := ToFloat64(o)
+		return reflect.ValueOf(v).Convert(t), ok
+	case reflect.String:
+		v, ok := ToString(o)
+		return reflect.ValueOf(v).Convert(t), ok
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			v, ok := ToByteSlice(o)
+			return reflect.ValueOf(v).Convert(t), ok
+		}
+
+		var arr []Object
+		switch o := o.(type) {
+		case *Array:
+			arr = o.Value
+		case *ImmutableArray:
+			arr = o.Value
+		default:
+			return reflect.Value{}, false
+		}
+
+		s := reflect.MakeSlice(t, len(arr), len(arr))
+		for i, e := range arr {
+			v, ok := toReflect(e, t.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			s.Index(i).Set(v)
+		}
+
+		return s, true
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+
+		var m map[string]Object
+		switch o := o.(type) {
+		case *Map:
+			m = o.Value
+		case *ImmutableMap:
+			m = o.Value
+		default:
+			return reflect.Value{}, false
+		}
+
+		res := reflect.MakeMapWithSize(t, len(m))
+		for k, e := range m {
+			v, ok := toReflect(e, t.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			res.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), v)
+		}
+
+		return res, true
+	}
+
+	return reflect.Value{}, false
+}
+
+// reflectFunc returns the callable function that calls the Go function with
+// the arguments converted into the parameter types. If the last result of
+// the function is an error, a non-nil error is returned as an Error object.
+// The other results are converted into an object, or an array of objects if
+// there are more than one.
+func reflectFunc(fn reflect.Value) CallableFunc {
+	t := fn.Type()
+
+	return func(args ...Object) (Object, error) {
+		numIn := t.NumIn()
+		if t.IsVariadic() && len(args) < numIn-1 || !t.IsVariadic() && len(args) != numIn {
+			return nil, ErrWrongNumArguments
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var paramType reflect.Type
+			if t.IsVariadic() && i >= numIn-1 {
+				paramType = t.In(numIn - 1).Elem()
+			} else {
+				paramType = t.In(i)
+			}
+
+			v, ok := toReflect(arg, paramType)
+			if !ok {
+				return nil, ErrInvalidArgumentType{
+					Name:     argumentName(i),
+					Expected: paramType.String(),
+					Found:    arg.TypeName(),
+				}
+			}
+			in[i] = v
+		}
+
+		out := fn.Call(in)
+
+		if n := len(out); n > 0 && t.Out(n-1) == errorType {
+			if err := out[n-1]; !err.IsNil() {
+				return &Error{Value: &String{Value: err.Interface().(error).Error()}}, nil
+			}
+			out = out[:n-1]
+		}
+
+		switch len(out) {
+		case 0:
+			return UndefinedValue, nil
+		case 1:
+			return fromReflect(out[0])
+		}
+
+		arr := make([]Object, len(out))
+		for i, v := range out {
+			o, err := fromReflect(v)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = o
+		}
+
+		return &Array{Value: arr}, nil
+	}
+}
+
+func argumentName(i int) string {
+	if i < len(argumentNames) {
+		return argumentNames[i]
+	}
+
+	return fmt.Sprintf("#%d", i+1)
+}
@@ -2,6 +2,7 @@ package objects
 
 import (
 	"strconv"
+	"sync/atomic"
 
 	"github.com/d5/tengo"
 	"github.com/d5/tengo/compiler/token"
@@ -10,7 +11,7 @@ import (
 // String represents a string value.
 type String struct {
 	Value   string
-	runeStr []rune
+	runeStr atomic.Value // []rune, set on the first use
 }
 
 // TypeName returns the name of the type.
@@ -76,28 +77,37 @@ func (o *String) IndexGet(index Object) (res Object, err error) {
 
 	idxVal := int(intIdx.Value)
 
-	if o.runeStr == nil {
-		o.runeStr = []rune(o.Value)
-	}
-
-	if idxVal < 0 || idxVal >= len(o.runeStr) {
+	runes := o.runes()
+	if idxVal < 0 || idxVal >= len(runes) {
 		res = UndefinedValue
 		return
 	}
 
-	res = &Char{Value: o.runeStr[idxVal]}
+	res = &Char{Value: runes[idxVal]}
 
 	return
 }
 
 // Iterate creates a string iterator.
 func (o *String) Iterate() Iterator {
-	if o.runeStr == nil {
-		o.runeStr = []rune(o.Value)
-	}
+	runes := o.runes()
 
 	return &StringIterator{
-		v: o.runeStr,
-		l: len(o.runeStr),
+		v: runes,
+		l: len(runes),
+	}
+}
+
+// runes returns the characters of the string. They are cached, and the
+// cache is safe for concurrent use, so that the strings shared by the
+// concurrent runs, e.g. the constants, are not changed by them.
+func (o *String) runes() []rune {
+	if runes, ok := o.runeStr.Load().([]rune); ok {
+		return runes
 	}
+
+	runes := []rune(o.Value)
+	o.runeStr.Store(runes)
+
+	return runes
 }
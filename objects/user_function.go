@@ -1,14 +1,18 @@
 package objects
 
 import (
+	"context"
+
 	"github.com/d5/tengo/compiler/token"
 )
 
-// UserFunction represents a user function.
+// UserFunction represents a user function. If ContextValue is set, the VM
+// calls it with the context of the run instead of Value.
 type UserFunction struct {
-	Name       string
-	Value      CallableFunc
-	EncodingID string
+	Name         string
+	Value        CallableFunc
+	ContextValue ContextCallableFunc
+	EncodingID   string
 }
 
 // TypeName returns the name of the type.
@@ -28,7 +32,7 @@ func (o *UserFunction) BinaryOp(op token.Token, rhs Object) (Object, error) {
 
 // Copy returns a copy of the type.
 func (o *UserFunction) Copy() Object {
-	return &UserFunction{Value: o.Value}
+	return &UserFunction{Value: o.Value, ContextValue: o.ContextValue}
 }
 
 // IsFalsy returns true if the value of the type is falsy.
@@ -44,5 +48,18 @@ func (o *UserFunction) Equals(x Object) bool {
 
 // Call invokes a user function.
 func (o *UserFunction) Call(args ...Object) (Object, error) {
+	if o.Value == nil && o.ContextValue != nil {
+		return o.ContextValue(context.Background(), args...)
+	}
+
+	return o.Value(args...)
+}
+
+// CallContext invokes a user function with the context.
+func (o *UserFunction) CallContext(ctx context.Context, args ...Object) (Object, error) {
+	if o.ContextValue != nil {
+		return o.ContextValue(ctx, args...)
+	}
+
 	return o.Value(args...)
 }
@@ -0,0 +1,57 @@
+package objects_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+)
+
+func TestUserFunction_CallContext(t *testing.T) {
+	type ctxKey struct{}
+
+	fn := &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			return &objects.String{Value: "value"}, nil
+		},
+	}
+
+	ret, err := fn.CallContext(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.String{Value: "value"}, ret)
+
+	fn.ContextValue = func(ctx context.Context, args ...objects.Object) (objects.Object, error) {
+		if ctx.Value(ctxKey{}) == nil {
+			return &objects.String{Value: "background"}, nil
+		}
+
+		return &objects.String{Value: "context"}, nil
+	}
+
+	ret, err = fn.CallContext(context.WithValue(context.Background(), ctxKey{}, true))
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.String{Value: "context"}, ret)
+
+	// Call uses Value if set, and ContextValue with the background context
+	// if not
+	ret, err = fn.Call()
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.String{Value: "value"}, ret)
+
+	fn.Value = nil
+	ret, err = fn.Call()
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.String{Value: "background"}, ret)
+
+	ret, err = fn.Copy().(*objects.UserFunction).CallContext(context.WithValue(context.Background(), ctxKey{}, true))
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.String{Value: "context"}, ret)
+
+	// FromInterface converts the context functions
+	o, err := objects.FromInterface(fn.ContextValue)
+	assert.NoError(t, err)
+	ret, err = o.(objects.ContextCallable).CallContext(context.WithValue(context.Background(), ctxKey{}, true))
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.String{Value: "context"}, ret)
+}
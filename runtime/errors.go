@@ -9,3 +9,29 @@ var ErrStackOverflow = errors.New("stack overflow")
 
 // ErrObjectAllocLimit is an objects allocation limit error.
 var ErrObjectAllocLimit = errors.New("object allocation limit exceeded")
+
+// ErrVMRequired is an error where a function that calls back into the VM is
+// called from outside of the VM.
+var ErrVMRequired = errors.New("function must be called from the VM")
+
+// ErrAborted is an error where a function called back by the host is
+// stopped because the execution of the VM is aborted.
+var ErrAborted = errors.New("execution aborted")
+
+// ErrSuspend is returned by a host function to suspend the execution of the
+// VM. The result of the function is the value yielded to the host.
+var ErrSuspend = errors.New("suspend")
+
+// ErrCannotSuspend is an error where a host function returns ErrSuspend in
+// a VM that is not run with RunUntilYield, or in a function called back by
+// the host.
+var ErrCannotSuspend = errors.New("cannot suspend the execution")
+
+// ErrNotSuspended is an error where a VM that is not suspended is resumed.
+var ErrNotSuspended = errors.New("not suspended")
+
+// ErrSnapshotMismatch is an error where a snapshot is restored in a VM with
+// a different bytecode.
+var ErrSnapshotMismatch = errors.New("snapshot does not match the bytecode")
+
+var errInvalidSnapshot = errors.New("invalid snapshot")
@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
 	"sync/atomic"
 
@@ -23,6 +24,7 @@ const (
 
 // VM is a virtual machine that executes the bytecode compiled by Compiler.
 type VM struct {
+	bytecode    *compiler.Bytecode
 	constants   []objects.Object
 	stack       [StackSize]objects.Object
 	sp          int
@@ -34,9 +36,14 @@ type VM struct {
 	curInsts    []byte
 	ip          int
 	aborting    int64
+	abort       *int64 // the aborting flag of the VM that runs the script
 	maxAllocs   int64
 	allocs      int64
 	err         error
+	ctx         context.Context
+	yieldable   bool
+	suspended   bool
+	yielded     objects.Object
 }
 
 // NewVM creates a VM.
@@ -46,6 +53,7 @@ func NewVM(bytecode *compiler.Bytecode, globals []objects.Object, maxAllocs int6
 	}
 
 	v := &VM{
+		bytecode:    bytecode,
 		constants:   bytecode.Constants,
 		sp:          0,
 		globals:     globals,
@@ -54,6 +62,7 @@ func NewVM(bytecode *compiler.Bytecode, globals []objects.Object, maxAllocs int6
 		ip:          -1,
 		maxAllocs:   maxAllocs,
 	}
+	v.abort = &v.aborting
 
 	v.frames[0].fn = bytecode.MainFunction
 	v.frames[0].ip = -1
@@ -70,33 +79,224 @@ func (v *VM) Abort() {
 
 // Run starts the execution.
 func (v *VM) Run() (err error) {
-	// reset VM states
+	v.reset(false)
+
+	return v.resume()
+}
+
+// RunContext is like Run but includes a context. The context is passed to
+// the ContextCallable functions, and the execution is aborted when the
+// context is done, in which case the context error is returned.
+func (v *VM) RunContext(ctx context.Context) (err error) {
+	return v.runContext(ctx, v.Run)
+}
+
+// RunUntilYield is like RunContext but the host functions can suspend the
+// execution by returning ErrSuspend. It returns when the execution is
+// completed or suspended: use Suspended to tell them apart. The suspended
+// VM keeps its stack and frames, and can be resumed by Resume.
+func (v *VM) RunUntilYield(ctx context.Context) error {
+	return v.runContext(ctx, func() error {
+		v.reset(true)
+
+		return v.resume()
+	})
+}
+
+// Resume resumes the suspended VM with the value as the result of the call
+// to the function that suspended it. Like RunUntilYield, it returns when
+// the execution is completed or suspended again.
+func (v *VM) Resume(ctx context.Context, value objects.Object) error {
+	if !v.suspended {
+		return ErrNotSuspended
+	}
+
+	if value == nil {
+		value = objects.UndefinedValue
+	}
+
+	return v.runContext(ctx, func() error {
+		v.suspended = false
+		v.yielded = nil
+		v.stack[v.sp] = value
+		v.sp++
+
+		return v.resume()
+	})
+}
+
+// Suspended returns true if the execution is suspended by a host function.
+func (v *VM) Suspended() bool {
+	return v.suspended
+}
+
+// Yielded returns the value yielded by the host function that suspended the
+// execution, or nil if the VM is not suspended.
+func (v *VM) Yielded() objects.Object {
+	return v.yielded
+}
+
+// reset resets the VM states for a new execution.
+func (v *VM) reset(yieldable bool) {
 	v.sp = 0
 	v.curFrame = &(v.frames[0])
 	v.curInsts = v.curFrame.fn.Instructions
 	v.framesIndex = 1
 	v.ip = -1
 	v.allocs = v.maxAllocs + 1
+	v.err = nil
+	v.yieldable = yieldable
+	v.suspended = false
+	v.yielded = nil
+}
+
+// resume runs the VM from its current states.
+func (v *VM) resume() error {
+	v.err = nil
 
 	v.run()
 
 	atomic.StoreInt64(&v.aborting, 0)
 
-	err = v.err
-	if err != nil {
-		filePos := v.fileSet.Position(v.curFrame.fn.SourcePos(v.ip - 1))
-		err = fmt.Errorf("Runtime Error: %s\n\tat %s", err.Error(), filePos)
-		for v.framesIndex > 1 {
-			v.framesIndex--
-			v.curFrame = &v.frames[v.framesIndex-1]
+	if v.err != nil {
+		return fmt.Errorf("Runtime Error: %s", v.traceError(1))
+	}
+
+	return nil
+}
+
+// runContext calls run with the context.
+func (v *VM) runContext(ctx context.Context, run func() error) (err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	v.ctx = ctx
+	defer func() { v.ctx = nil }()
 
-			filePos = v.fileSet.Position(v.curFrame.fn.SourcePos(v.curFrame.ip - 1))
-			err = fmt.Errorf("%s\n\tat %s", err.Error(), filePos)
+	// the context is never done
+	if ctx.Done() == nil {
+		return run()
+	}
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- run()
+	}()
+
+	select {
+	case <-ctx.Done():
+		v.Abort()
+		<-ch
+
+		// the run may have finished before Abort: clear the flag so that
+		// the VM can be run again
+		atomic.StoreInt64(&v.aborting, 0)
+		err = ctx.Err()
+	case err = <-ch:
+		// the functions may have returned the context error
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
 		}
-		return err
 	}
 
-	return nil
+	return
+}
+
+// Context returns the context of the run, or context.Background() if the
+// VM is not run with RunContext.
+func (v *VM) Context() context.Context {
+	if v.ctx == nil {
+		return context.Background()
+	}
+
+	return v.ctx
+}
+
+// Call invokes a function object with the given arguments and returns its
+// result. Compiled functions and closures are executed in a child VM that
+// shares the constants and globals of v, so Call can be used by the host
+// functions to call back into the script functions passed to them. The
+// child VM stops when v is aborted, e.g. when the context of RunContext is
+// done, and Call returns ErrAborted.
+func (v *VM) Call(fn objects.Object, args ...objects.Object) (ret objects.Object, err error) {
+	switch fn := fn.(type) {
+	case *objects.CompiledFunction, *objects.Closure:
+		// run below
+	case VMCallable:
+		return fn.CallVM(v, args...)
+	case objects.ContextCallable:
+		return fn.CallContext(v.Context(), args...)
+	case objects.Callable:
+		return fn.Call(args...)
+	default:
+		return nil, fmt.Errorf("not callable: %s", fn.TypeName())
+	}
+
+	if len(args)+1 >= StackSize {
+		return nil, ErrStackOverflow
+	}
+
+	child := &VM{
+		constants:   v.constants,
+		globals:     v.globals,
+		fileSet:     v.fileSet,
+		framesIndex: 1,
+		ip:          -1,
+		maxAllocs:   v.maxAllocs,
+		allocs:      v.allocs,
+		ctx:         v.ctx,
+		abort:       v.abort,
+	}
+
+	// the main function of the child VM only calls the function
+	// that is placed at the bottom of the stack.
+	child.frames[0].fn = &objects.CompiledFunction{
+		Instructions: compiler.MakeInstruction(compiler.OpCall, len(args)),
+	}
+	child.frames[0].ip = -1
+	child.curFrame = &child.frames[0]
+	child.curInsts = child.curFrame.fn.Instructions
+
+	child.stack[0] = fn
+	copy(child.stack[1:], args)
+	child.sp = len(args) + 1
+
+	child.run()
+
+	v.allocs = child.allocs
+
+	if atomic.LoadInt64(v.abort) != 0 {
+		return nil, ErrAborted
+	}
+
+	if child.err != nil {
+		// skip the frame of the synthetic main function
+		return nil, child.traceError(2)
+	}
+
+	ret = child.stack[0]
+	if ret == nil {
+		ret = objects.UndefinedValue
+	}
+
+	return ret, nil
+}
+
+// traceError decorates the VM error with the source positions of
+// the call frames down to the frame minFrame.
+func (v *VM) traceError(minFrame int) error {
+	filePos := v.fileSet.Position(v.curFrame.fn.SourcePos(v.ip - 1))
+	err := fmt.Errorf("%s\n\tat %s", v.err.Error(), filePos)
+	for v.framesIndex > minFrame {
+		v.framesIndex--
+		v.curFrame = &v.frames[v.framesIndex-1]
+
+		filePos = v.fileSet.Position(v.curFrame.fn.SourcePos(v.curFrame.ip - 1))
+		err = fmt.Errorf("%s\n\tat %s", err.Error(), filePos)
+	}
+
+	return err
 }
 
 func (v *VM) run() {
@@ -117,7 +317,7 @@ func (v *VM) run() {
 		}
 	}()
 
-	for atomic.LoadInt64(&v.aborting) == 0 {
+	for atomic.LoadInt64(v.abort) == 0 {
 		v.ip++
 
 		switch v.curInsts[v.ip] {
@@ -753,9 +953,33 @@ func (v *VM) run() {
 				var args []objects.Object
 				args = append(args, v.stack[v.sp-numArgs:v.sp]...)
 
-				ret, e := callee.Call(args...)
+				var ret objects.Object
+				var e error
+				switch callee := callee.(type) {
+				case VMCallable:
+					ret, e = callee.CallVM(v, args...)
+				case objects.ContextCallable:
+					ret, e = callee.CallContext(v.Context(), args...)
+				default:
+					ret, e = callee.Call(args...)
+				}
 				v.sp -= numArgs + 1
 
+				if e == ErrSuspend {
+					if !v.yieldable {
+						v.err = ErrCannotSuspend
+						return
+					}
+
+					// the result of the call is pushed by Resume
+					if ret == nil {
+						ret = objects.UndefinedValue
+					}
+					v.suspended = true
+					v.yielded = ret
+					return
+				}
+
 				// runtime error
 				if e != nil {
 					if e == objects.ErrWrongNumArguments {
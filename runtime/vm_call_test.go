@@ -1,6 +1,14 @@
 package runtime_test
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+)
 
 func TestCall(t *testing.T) {
 	expect(t, `a := { b: func(x) { return x + 2 } }; out = a.b(5)`, nil, 7)
@@ -17,3 +25,70 @@ c := func(a) {
 b(a, c)
 `, nil, "Runtime Error: not callable: int\n\tat test:7:4\n\tat test:3:4\n\tat test:9:1")
 }
+
+func TestVMCallback(t *testing.T) {
+	callback := &runtime.VMFunction{
+		Name: "callback",
+		Value: func(v *runtime.VM, args ...objects.Object) (objects.Object, error) {
+			return v.Call(args[0], args[1:]...)
+		},
+	}
+
+	expect(t, `out = callback(func(x) { return x * 2 }, 5)`, Opts().Symbol("callback", callback).Skip2ndPass(), 10)
+	expect(t, `out = callback(func(...x) { return len(x) }, 1, 2, 3)`, Opts().Symbol("callback", callback).Skip2ndPass(), 3)
+	expect(t, `out = callback(len, [1, 2])`, Opts().Symbol("callback", callback).Skip2ndPass(), 2)
+	expect(t, `a := 3; out = callback(func(x) { a += x; return a }, 4)`, Opts().Symbol("callback", callback).Skip2ndPass(), 7)
+	expect(t, `f := func() { a := 3; return func(x) { return a + x } }; out = callback(f(), 4)`, Opts().Symbol("callback", callback).Skip2ndPass(), 7)
+	expect(t, `out = callback(func(x) { return callback(func(y) { return x + y }, 2) }, 1)`, Opts().Symbol("callback", callback).Skip2ndPass(), 3)
+	expectError(t, `callback(func(x) { return x + "a" }, 1)`, Opts().Symbol("callback", callback).Skip2ndPass(),
+		"Runtime Error: invalid operation: int + string\n\tat test:1:27\n\tat test:1:1")
+	expectError(t, `callback(5)`, Opts().Symbol("callback", callback).Skip2ndPass(), "not callable: int")
+}
+
+func TestVMCallback_Abort(t *testing.T) {
+	callback := &runtime.VMFunction{
+		Name: "callback",
+		Value: func(v *runtime.VM, args ...objects.Object) (objects.Object, error) {
+			return v.Call(args[0], args[1:]...)
+		},
+	}
+
+	// the callbacks stop when the context of the parent VM is done
+	for _, input := range []string{
+		`callback(func() { for {} })`,
+		`callback(func() { callback(func() { for {} }) })`,
+		`for { callback(func() {}) }`,
+	} {
+		v, _ := suspendVM(t, input, callback)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		done := make(chan error, 1)
+		go func() {
+			done <- v.RunContext(ctx)
+		}()
+
+		select {
+		case err := <-done:
+			assert.Equal(t, context.DeadlineExceeded, err)
+		case <-time.After(time.Second):
+			t.Fatalf("not stopped: %s", input)
+		}
+		cancel()
+	}
+
+	// Abort
+	v, _ := suspendVM(t, `callback(func() { for {} })`, callback)
+	done := make(chan error, 1)
+	go func() {
+		done <- v.Run()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	v.Abort()
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("not aborted")
+	}
+}
@@ -0,0 +1,17 @@
+package runtime
+
+import (
+	"github.com/d5/tengo/objects"
+)
+
+// VMCallable represents a callable object that needs to access the VM it's
+// called from, e.g. to call back the script functions passed as arguments.
+// The VM prefers CallVM over Call when it invokes a VMCallable.
+type VMCallable interface {
+	objects.Callable
+
+	// CallVM should take the calling VM and an arbitrary number of arguments
+	// and returns a return value and/or an error,
+	// which the VM will consider as a run-time error.
+	CallVM(v *VM, args ...objects.Object) (ret objects.Object, err error)
+}
@@ -0,0 +1,60 @@
+package runtime
+
+import (
+	"encoding/gob"
+
+	"github.com/d5/tengo/compiler/token"
+	"github.com/d5/tengo/objects"
+)
+
+// VMFunction represents a user function that can call back into the VM.
+type VMFunction struct {
+	Name  string
+	Value func(v *VM, args ...objects.Object) (ret objects.Object, err error)
+}
+
+// TypeName returns the name of the type.
+func (o *VMFunction) TypeName() string {
+	return "user-function:" + o.Name
+}
+
+func (o *VMFunction) String() string {
+	return "<user-function>"
+}
+
+// BinaryOp returns another object that is the result of
+// a given binary operator and a right-hand side object.
+func (o *VMFunction) BinaryOp(op token.Token, rhs objects.Object) (objects.Object, error) {
+	return nil, objects.ErrInvalidOperator
+}
+
+// Copy returns a copy of the type.
+func (o *VMFunction) Copy() objects.Object {
+	return &VMFunction{Name: o.Name, Value: o.Value}
+}
+
+// IsFalsy returns true if the value of the type is falsy.
+func (o *VMFunction) IsFalsy() bool {
+	return false
+}
+
+// Equals returns true if the value of the type
+// is equal to the value of another object.
+func (o *VMFunction) Equals(x objects.Object) bool {
+	return false
+}
+
+// Call invokes the function outside of the VM.
+// It returns ErrVMRequired as the function needs the calling VM.
+func (o *VMFunction) Call(args ...objects.Object) (objects.Object, error) {
+	return nil, ErrVMRequired
+}
+
+// CallVM invokes the function with the calling VM.
+func (o *VMFunction) CallVM(v *VM, args ...objects.Object) (objects.Object, error) {
+	return o.Value(v, args...)
+}
+
+func init() {
+	gob.Register(&VMFunction{})
+}
@@ -0,0 +1,719 @@
+package runtime
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/d5/tengo/objects"
+)
+
+// snapshotVersion is the version of the snapshot format.
+const snapshotVersion = 1
+
+// snapshot is the encoded state of a VM. The objects are stored in a table
+// and referenced by their indexes plus one, so that 0 is nil.
+type snapshot struct {
+	Version   int
+	Bytecode  string // fingerprint of the bytecode
+	Objects   []snapshotObject
+	Globals   []int
+	Suspended bool
+	Stack     []int
+	Frames    []snapshotFrame
+	IP        int
+	Allocs    int64
+	Yielded   int
+}
+
+type snapshotFrame struct {
+	Fn          int // constant index, or -1 for the main function
+	FreeVars    []int
+	IP          int
+	BasePointer int
+}
+
+type snapshotKind byte
+
+const (
+	snapshotUndefined snapshotKind = iota
+	snapshotTrue
+	snapshotFalse
+	snapshotInt
+	snapshotFloat
+	snapshotChar
+	snapshotString
+	snapshotBytes
+	snapshotTime
+	snapshotArray
+	snapshotImmutableArray
+	snapshotMap
+	snapshotImmutableMap
+	snapshotError
+	snapshotObjectPtr
+	snapshotClosure
+	snapshotIterator
+	snapshotConstant       // Ref: constant index
+	snapshotConstantMember // Ref: constant index, String: key
+	snapshotBuiltin        // Ref: builtin function index
+	snapshotGlobal         // Ref: index of the global holding the host object
+)
+
+type snapshotObject struct {
+	Kind   snapshotKind
+	Int    int64
+	Float  float64
+	String string
+	Bytes  []byte
+	Time   time.Time
+	Elems  []int
+	Keys   []string
+	Ref    int
+}
+
+type constantMember struct {
+	constant int
+	key      string
+}
+
+// collection identifies the values of an array or a map, which an iterator
+// shares with the collection it iterates.
+type collection struct {
+	ptr uintptr
+	len int
+}
+
+type pendingIterator struct {
+	index    int
+	iterator objects.Iterator
+}
+
+// Snapshot writes the state of the VM to the writer: the global variables
+// and, if the VM is suspended, its stack and frames. The compiled functions
+// and the members of the builtin modules are referenced by the constants of
+// the bytecode, and the host objects that can't be encoded, e.g. the user
+// functions, by the global variables holding them. Snapshot must not be
+// called while the VM is running.
+func (v *VM) Snapshot(w io.Writer) error {
+	e := &snapshotEncoder{
+		v:           v,
+		indexes:     make(map[objects.Object]int),
+		constants:   make(map[objects.Object]int),
+		members:     make(map[objects.Object]constantMember),
+		hosts:       make(map[objects.Object]int),
+		collections: make(map[collection]int),
+	}
+
+	for i, c := range v.constants {
+		if !isComparable(c) {
+			continue
+		}
+		if _, ok := e.constants[c]; !ok {
+			e.constants[c] = i
+		}
+
+		if m, ok := c.(*objects.ImmutableMap); ok {
+			for k, o := range m.Value {
+				if !isComparable(o) {
+					continue
+				}
+				if _, ok := e.members[o]; !ok {
+					e.members[o] = constantMember{constant: i, key: k}
+				}
+			}
+		}
+	}
+	for i, g := range v.globals {
+		if g == nil || !isComparable(g) {
+			continue
+		}
+		if _, ok := e.hosts[g]; !ok {
+			e.hosts[g] = i
+		}
+	}
+
+	s := &snapshot{
+		Version:   snapshotVersion,
+		Bytecode:  v.bytecode.Fingerprint(),
+		Suspended: v.suspended,
+	}
+
+	var err error
+	if s.Globals, err = e.encodeAll(v.globals); err != nil {
+		return err
+	}
+
+	if v.suspended {
+		if s.Stack, err = e.encodeAll(v.stack[:v.sp]); err != nil {
+			return err
+		}
+
+		for i := 0; i < v.framesIndex; i++ {
+			f := &v.frames[i]
+
+			fn := -1
+			if i > 0 {
+				idx, ok := e.constants[f.fn]
+				if !ok {
+					return fmt.Errorf("snapshot: function not in the constants")
+				}
+				fn = idx
+			}
+
+			freeVars := make([]objects.Object, len(f.freeVars))
+			for i, p := range f.freeVars {
+				freeVars[i] = p
+			}
+			frame := snapshotFrame{Fn: fn, IP: f.ip, BasePointer: f.basePointer}
+			if frame.FreeVars, err = e.encodeAll(freeVars); err != nil {
+				return err
+			}
+
+			s.Frames = append(s.Frames, frame)
+		}
+
+		s.IP = v.ip
+		s.Allocs = v.allocs
+		if s.Yielded, err = e.encode(v.yielded); err != nil {
+			return err
+		}
+	}
+
+	if err := e.encodeIterators(); err != nil {
+		return err
+	}
+	s.Objects = e.objects
+
+	return gob.NewEncoder(w).Encode(s)
+}
+
+// Restore reads the state of the VM written by Snapshot from the reader. The
+// VM must be created with the same bytecode: ErrSnapshotMismatch is returned
+// otherwise. The host objects are restored from the global variables of
+// the VM, which must be set before Restore. If the VM was suspended, it can
+// be resumed by Resume after Restore.
+func (v *VM) Restore(r io.Reader) error {
+	var s snapshot
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return err
+	}
+
+	if s.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %d", s.Version)
+	}
+	if s.Bytecode != v.bytecode.Fingerprint() {
+		return ErrSnapshotMismatch
+	}
+	if len(s.Globals) > len(v.globals) || len(s.Stack) > StackSize || len(s.Frames) > MaxFrames {
+		return errInvalidSnapshot
+	}
+
+	hosts := make([]objects.Object, len(v.globals))
+	copy(hosts, v.globals)
+	d := &snapshotDecoder{
+		v:       v,
+		s:       &s,
+		hosts:   hosts,
+		decoded: make([]objects.Object, len(s.Objects)),
+	}
+
+	globals, err := d.decodeAll(s.Globals)
+	if err != nil {
+		return err
+	}
+
+	var stack []objects.Object
+	var frames []Frame
+	var yielded objects.Object
+	if s.Suspended {
+		if len(s.Frames) == 0 {
+			return errInvalidSnapshot
+		}
+
+		if stack, err = d.decodeAll(s.Stack); err != nil {
+			return err
+		}
+
+		for i, sf := range s.Frames {
+			fn := v.frames[0].fn
+			if i > 0 {
+				if sf.Fn < 0 || sf.Fn >= len(v.constants) {
+					return errInvalidSnapshot
+				}
+				var ok bool
+				if fn, ok = v.constants[sf.Fn].(*objects.CompiledFunction); !ok {
+					return errInvalidSnapshot
+				}
+			}
+
+			freeVars, err := d.decodeAll(sf.FreeVars)
+			if err != nil {
+				return err
+			}
+			f := Frame{fn: fn, ip: sf.IP, basePointer: sf.BasePointer}
+			for _, p := range freeVars {
+				ptr, ok := p.(*objects.ObjectPtr)
+				if !ok {
+					return errInvalidSnapshot
+				}
+				f.freeVars = append(f.freeVars, ptr)
+			}
+
+			frames = append(frames, f)
+		}
+
+		if yielded, err = d.decode(s.Yielded); err != nil {
+			return err
+		}
+	}
+
+	// the snapshot is valid: update the VM
+	v.reset(s.Suspended)
+	for i := range v.globals {
+		v.globals[i] = nil
+	}
+	copy(v.globals, globals)
+
+	if s.Suspended {
+		for i := range v.stack {
+			v.stack[i] = nil
+		}
+		copy(v.stack[:], stack)
+		v.sp = len(stack)
+		copy(v.frames[:], frames)
+		v.framesIndex = len(frames)
+		v.curFrame = &v.frames[v.framesIndex-1]
+		v.curInsts = v.curFrame.fn.Instructions
+		v.ip = s.IP
+		v.allocs = s.Allocs
+		v.suspended = true
+		v.yielded = yielded
+	}
+
+	return nil
+}
+
+type snapshotEncoder struct {
+	v           *VM
+	objects     []snapshotObject
+	indexes     map[objects.Object]int
+	constants   map[objects.Object]int
+	members     map[objects.Object]constantMember
+	hosts       map[objects.Object]int
+	collections map[collection]int
+	iterators   []pendingIterator
+}
+
+func (e *snapshotEncoder) encodeAll(objs []objects.Object) ([]int, error) {
+	res := make([]int, len(objs))
+	for i, o := range objs {
+		idx, err := e.encode(o)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = idx
+	}
+
+	return res, nil
+}
+
+// encode adds the object to the table, and returns its index plus one.
+func (e *snapshotEncoder) encode(o objects.Object) (int, error) {
+	if o == nil {
+		return 0, nil
+	}
+
+	if !isComparable(o) {
+		return 0, fmt.Errorf("snapshot: cannot encode %s", o.TypeName())
+	}
+	if idx, ok := e.indexes[o]; ok {
+		return idx, nil
+	}
+
+	// add the object before its elements, which can refer to it
+	e.objects = append(e.objects, snapshotObject{})
+	idx := len(e.objects)
+	e.indexes[o] = idx
+
+	so, err := e.encodeObject(o)
+	if err != nil {
+		return 0, err
+	}
+	e.objects[idx-1] = so
+
+	if c, ok := collectionOf(o); ok {
+		if _, ok := e.collections[c]; !ok {
+			e.collections[c] = idx
+		}
+	}
+	if so.Kind == snapshotIterator {
+		e.iterators = append(e.iterators, pendingIterator{index: idx, iterator: o.(objects.Iterator)})
+	}
+
+	return idx, nil
+}
+
+// encodeIterators encodes the iterated values of the iterators. An iterator
+// refers to the array or the map it iterates if the collection is encoded,
+// so that the restored iterator sees the changes of the collection.
+func (e *snapshotEncoder) encodeIterators() error {
+	// encoding the values can add iterators
+	for i := 0; i < len(e.iterators); i++ {
+		it := e.iterators[i]
+		state, _ := objects.IteratorStateOf(it.iterator)
+
+		c, ok := collectionOf(state.Values)
+		values, found := e.collections[c]
+		if !ok || !found {
+			var err error
+			if values, err = e.encode(state.Values); err != nil {
+				return err
+			}
+		}
+
+		e.objects[it.index-1].Elems = []int{values}
+	}
+
+	return nil
+}
+
+func (e *snapshotEncoder) encodeObject(o objects.Object) (so snapshotObject, err error) {
+	if idx, ok := e.constants[o]; ok {
+		return snapshotObject{Kind: snapshotConstant, Ref: idx}, nil
+	}
+
+	switch o := o.(type) {
+	case *objects.Undefined:
+		so.Kind = snapshotUndefined
+	case *objects.Bool:
+		so.Kind = snapshotFalse
+		if !o.IsFalsy() {
+			so.Kind = snapshotTrue
+		}
+	case *objects.Int:
+		so.Kind, so.Int = snapshotInt, o.Value
+	case *objects.Float:
+		so.Kind, so.Float = snapshotFloat, o.Value
+	case *objects.Char:
+		so.Kind, so.Int = snapshotChar, int64(o.Value)
+	case *objects.String:
+		so.Kind, so.String = snapshotString, o.Value
+	case *objects.Bytes:
+		so.Kind, so.Bytes = snapshotBytes, o.Value
+	case *objects.Time:
+		so.Kind, so.Time = snapshotTime, o.Value
+	case *objects.Array:
+		so.Kind = snapshotArray
+		so.Elems, err = e.encodeAll(o.Value)
+	case *objects.ImmutableArray:
+		so.Kind = snapshotImmutableArray
+		so.Elems, err = e.encodeAll(o.Value)
+	case *objects.Map:
+		so.Kind = snapshotMap
+		so.Keys, so.Elems, err = e.encodeMap(o.Value)
+	case *objects.ImmutableMap:
+		so.Kind = snapshotImmutableMap
+		so.Keys, so.Elems, err = e.encodeMap(o.Value)
+	case *objects.Error:
+		so.Kind = snapshotError
+		so.Elems, err = e.encodeAll([]objects.Object{o.Value})
+	case *objects.ObjectPtr:
+		so.Kind = snapshotObjectPtr
+		if o.Value != nil {
+			so.Elems, err = e.encodeAll([]objects.Object{*o.Value})
+		}
+	case *objects.Closure:
+		idx, ok := e.constants[o.Fn]
+		if !ok {
+			return so, fmt.Errorf("snapshot: function not in the constants")
+		}
+
+		free := make([]objects.Object, len(o.Free))
+		for i, p := range o.Free {
+			free[i] = p
+		}
+
+		so.Kind, so.Ref = snapshotClosure, idx
+		so.Elems, err = e.encodeAll(free)
+	case *objects.BuiltinFunction:
+		for idx, fn := range objects.Builtins {
+			if fn == o {
+				return snapshotObject{Kind: snapshotBuiltin, Ref: idx}, nil
+			}
+		}
+
+		return e.encodeHost(o)
+	case objects.Iterator:
+		state, ok := objects.IteratorStateOf(o)
+		if !ok {
+			return e.encodeHost(o)
+		}
+
+		// the values are encoded by encodeIterators
+		so.Kind, so.Keys, so.Int = snapshotIterator, state.Keys, int64(state.Pos)
+	default:
+		return e.encodeHost(o)
+	}
+
+	return so, err
+}
+
+// encodeHost encodes the object that can't be encoded by its value as the
+// reference to the constant or the global variable holding it.
+func (e *snapshotEncoder) encodeHost(o objects.Object) (snapshotObject, error) {
+	if m, ok := e.members[o]; ok {
+		return snapshotObject{Kind: snapshotConstantMember, Ref: m.constant, String: m.key}, nil
+	}
+
+	if idx, ok := e.hosts[o]; ok {
+		return snapshotObject{Kind: snapshotGlobal, Ref: idx}, nil
+	}
+
+	return snapshotObject{}, fmt.Errorf("snapshot: cannot encode %s", o.TypeName())
+}
+
+func (e *snapshotEncoder) encodeMap(m map[string]objects.Object) ([]string, []int, error) {
+	keys := make([]string, 0, len(m))
+	values := make([]objects.Object, 0, len(m))
+	for k, v := range m {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+
+	elems, err := e.encodeAll(values)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return keys, elems, nil
+}
+
+type snapshotDecoder struct {
+	v       *VM
+	s       *snapshot
+	hosts   []objects.Object
+	decoded []objects.Object
+}
+
+func (d *snapshotDecoder) decodeAll(idxs []int) ([]objects.Object, error) {
+	res := make([]objects.Object, len(idxs))
+	for i, idx := range idxs {
+		o, err := d.decode(idx)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = o
+	}
+
+	return res, nil
+}
+
+// decode returns the object at the index minus one in the table.
+func (d *snapshotDecoder) decode(idx int) (objects.Object, error) {
+	if idx == 0 {
+		return nil, nil
+	}
+	if idx < 0 || idx > len(d.s.Objects) {
+		return nil, errInvalidSnapshot
+	}
+	if o := d.decoded[idx-1]; o != nil {
+		return o, nil
+	}
+
+	so := &d.s.Objects[idx-1]
+
+	// the containers are added before their elements, which can refer to them
+	switch so.Kind {
+	case snapshotUndefined:
+		return d.set(idx, objects.UndefinedValue)
+	case snapshotTrue:
+		return d.set(idx, objects.TrueValue)
+	case snapshotFalse:
+		return d.set(idx, objects.FalseValue)
+	case snapshotInt:
+		return d.set(idx, &objects.Int{Value: so.Int})
+	case snapshotFloat:
+		return d.set(idx, &objects.Float{Value: so.Float})
+	case snapshotChar:
+		return d.set(idx, &objects.Char{Value: rune(so.Int)})
+	case snapshotString:
+		return d.set(idx, &objects.String{Value: so.String})
+	case snapshotBytes:
+		return d.set(idx, &objects.Bytes{Value: so.Bytes})
+	case snapshotTime:
+		return d.set(idx, &objects.Time{Value: so.Time})
+	case snapshotArray:
+		o := &objects.Array{}
+		d.decoded[idx-1] = o
+		elems, err := d.decodeAll(so.Elems)
+		o.Value = elems
+		return o, err
+	case snapshotImmutableArray:
+		o := &objects.ImmutableArray{}
+		d.decoded[idx-1] = o
+		elems, err := d.decodeAll(so.Elems)
+		o.Value = elems
+		return o, err
+	case snapshotMap:
+		o := &objects.Map{}
+		d.decoded[idx-1] = o
+		m, err := d.decodeMap(so)
+		o.Value = m
+		return o, err
+	case snapshotImmutableMap:
+		o := &objects.ImmutableMap{}
+		d.decoded[idx-1] = o
+		m, err := d.decodeMap(so)
+		o.Value = m
+		return o, err
+	case snapshotError:
+		o := &objects.Error{}
+		d.decoded[idx-1] = o
+		elems, err := d.decodeAll(so.Elems)
+		if err != nil {
+			return nil, err
+		}
+		if len(elems) == 1 {
+			o.Value = elems[0]
+		}
+		return o, nil
+	case snapshotObjectPtr:
+		o := &objects.ObjectPtr{}
+		d.decoded[idx-1] = o
+		elems, err := d.decodeAll(so.Elems)
+		if err != nil {
+			return nil, err
+		}
+		if len(elems) == 1 {
+			value := elems[0]
+			o.Value = &value
+		}
+		return o, nil
+	case snapshotClosure:
+		fn, err := d.constant(so.Ref)
+		if err != nil {
+			return nil, err
+		}
+		o := &objects.Closure{}
+		if o.Fn, _ = fn.(*objects.CompiledFunction); o.Fn == nil {
+			return nil, errInvalidSnapshot
+		}
+		d.decoded[idx-1] = o
+		free, err := d.decodeAll(so.Elems)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range free {
+			ptr, ok := p.(*objects.ObjectPtr)
+			if !ok {
+				return nil, errInvalidSnapshot
+			}
+			o.Free = append(o.Free, ptr)
+		}
+		return o, nil
+	case snapshotIterator:
+		elems, err := d.decodeAll(so.Elems)
+		if err != nil {
+			return nil, err
+		}
+		if len(elems) != 1 || elems[0] == nil {
+			return nil, errInvalidSnapshot
+		}
+		state := &objects.IteratorState{Values: elems[0], Keys: so.Keys, Pos: int(so.Int)}
+		o, err := state.Iterator()
+		if err != nil {
+			return nil, err
+		}
+		return d.set(idx, o)
+	case snapshotConstant:
+		o, err := d.constant(so.Ref)
+		if err != nil {
+			return nil, err
+		}
+		return d.set(idx, o)
+	case snapshotConstantMember:
+		c, err := d.constant(so.Ref)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := c.(*objects.ImmutableMap)
+		if !ok || m.Value[so.String] == nil {
+			return nil, errInvalidSnapshot
+		}
+		return d.set(idx, m.Value[so.String])
+	case snapshotBuiltin:
+		if so.Ref < 0 || so.Ref >= len(objects.Builtins) {
+			return nil, errInvalidSnapshot
+		}
+		return d.set(idx, objects.Builtins[so.Ref])
+	case snapshotGlobal:
+		if so.Ref < 0 || so.Ref >= len(d.hosts) || d.hosts[so.Ref] == nil {
+			return nil, fmt.Errorf("restore: host object in global %d not found", so.Ref)
+		}
+		return d.set(idx, d.hosts[so.Ref])
+	}
+
+	return nil, errInvalidSnapshot
+}
+
+func (d *snapshotDecoder) set(idx int, o objects.Object) (objects.Object, error) {
+	d.decoded[idx-1] = o
+
+	return o, nil
+}
+
+func (d *snapshotDecoder) constant(idx int) (objects.Object, error) {
+	if idx < 0 || idx >= len(d.v.constants) {
+		return nil, errInvalidSnapshot
+	}
+
+	return d.v.constants[idx], nil
+}
+
+func (d *snapshotDecoder) decodeMap(so *snapshotObject) (map[string]objects.Object, error) {
+	if len(so.Keys) != len(so.Elems) {
+		return nil, errInvalidSnapshot
+	}
+
+	values, err := d.decodeAll(so.Elems)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]objects.Object, len(values))
+	for i, k := range so.Keys {
+		m[k] = values[i]
+	}
+
+	return m, nil
+}
+
+func isComparable(o objects.Object) bool {
+	return reflect.TypeOf(o).Comparable()
+}
+
+// collectionOf returns the identity of the values of an array or a map.
+func collectionOf(o objects.Object) (collection, bool) {
+	var values reflect.Value
+	switch o := o.(type) {
+	case *objects.Array:
+		values = reflect.ValueOf(o.Value)
+	case *objects.ImmutableArray:
+		values = reflect.ValueOf(o.Value)
+	case *objects.Map:
+		values = reflect.ValueOf(o.Value)
+	case *objects.ImmutableMap:
+		values = reflect.ValueOf(o.Value)
+	default:
+		return collection{}, false
+	}
+
+	// the empty collections can share the pointer
+	if values.Len() == 0 {
+		return collection{}, false
+	}
+
+	return collection{ptr: values.Pointer(), len: values.Len()}, true
+}
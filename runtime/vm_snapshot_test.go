@@ -0,0 +1,116 @@
+package runtime_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+)
+
+func TestVMSnapshot(t *testing.T) {
+	await := &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			return args[0], runtime.ErrSuspend
+		},
+	}
+
+	input := `
+m := {a: 1, b: 2, c: 3}
+s := ""
+for k, v in m {
+	for i, c in "xy" {
+		s += k + string(c) + string(await(v * 10 + i))
+	}
+}
+out = s
+`
+	v, vGlobals := suspendVM(t, input, await)
+	assert.NoError(t, v.RunUntilYield(context.Background()))
+
+	for v.Suspended() {
+		// continue the execution in a new VM, and compare it with the old one
+		var buf bytes.Buffer
+		assert.NoError(t, v.Snapshot(&buf))
+
+		restored, globals := suspendVM(t, input, await)
+		assert.NoError(t, restored.Restore(&buf))
+		assert.True(t, restored.Suspended())
+		assert.True(t, v.Yielded().Equals(restored.Yielded()))
+
+		assert.NoError(t, restored.Resume(context.Background(), restored.Yielded()))
+		assert.NoError(t, v.Resume(context.Background(), v.Yielded()))
+		assert.Equal(t, v.Suspended(), restored.Suspended())
+		if !v.Suspended() {
+			assert.Equal(t, vGlobals[0], globals[0])
+		}
+
+		v, vGlobals = restored, globals
+	}
+	assert.Equal(t, 3*2*4, len(vGlobals[0].(*objects.String).Value))
+
+	// different bytecode
+	var buf bytes.Buffer
+	assert.NoError(t, v.Snapshot(&buf))
+	other, _ := suspendVM(t, `out = 1`, await)
+	assert.Equal(t, runtime.ErrSnapshotMismatch, other.Restore(&buf))
+}
+
+func TestVMSnapshot_Iterator(t *testing.T) {
+	await := &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			return args[0], runtime.ErrSuspend
+		},
+	}
+
+	// the restored iterators see the changes of the iterated collections
+	input := `
+a := [1, 2, 3]
+s := 0
+for i, x in a {
+	if i < 2 {
+		a[i+1] = await(x) * 10
+	}
+	s += x
+}
+m := {x: 1, y: 2}
+t := 0
+for k, x in m {
+	t += x
+	await(0)
+	m.x = 100
+	m.y = 100
+}
+out = [s, t]
+`
+	v, globals := suspendVM(t, input, await)
+	assert.NoError(t, v.RunUntilYield(context.Background()))
+	for v.Suspended() {
+		var buf bytes.Buffer
+		assert.NoError(t, v.Snapshot(&buf))
+
+		v, globals = suspendVM(t, input, await)
+		assert.NoError(t, v.Restore(&buf))
+		assert.NoError(t, v.Resume(context.Background(), v.Yielded()))
+	}
+
+	out := globals[0].(*objects.Array).Value
+	assert.Equal(t, int64(111), out[0].(*objects.Int).Value)
+	assert.True(t, out[1].(*objects.Int).Value > 100)
+}
+
+// hostValues is a host object of a type that is not comparable.
+type hostValues struct {
+	*objects.Undefined
+	values []int
+}
+
+func TestVMSnapshot_NotComparable(t *testing.T) {
+	v, _ := suspendVM(t, `out = 1`, hostValues{Undefined: objects.UndefinedValue.(*objects.Undefined)})
+	assert.NoError(t, v.Run())
+
+	var buf bytes.Buffer
+	assert.Error(t, v.Snapshot(&buf))
+}
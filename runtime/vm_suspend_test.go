@@ -0,0 +1,83 @@
+package runtime_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+)
+
+func TestVMSuspend(t *testing.T) {
+	await := &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			return args[0], runtime.ErrSuspend
+		},
+	}
+
+	v, globals := suspendVM(t, `
+sum := 0
+f := func(n) {
+	for i := 0; i < n; i++ {
+		sum += await(i)
+	}
+	return sum
+}
+out = f(3) + await("last")
+`, await)
+
+	assert.NoError(t, v.RunUntilYield(context.Background()))
+	for i := 0; i < 3; i++ {
+		assert.True(t, v.Suspended())
+		assert.Equal(t, &objects.Int{Value: int64(i)}, v.Yielded())
+		assert.NoError(t, v.Resume(context.Background(), &objects.Int{Value: int64(i * 10)}))
+	}
+	assert.True(t, v.Suspended())
+	assert.Equal(t, &objects.String{Value: "last"}, v.Yielded())
+	assert.NoError(t, v.Resume(context.Background(), &objects.Int{Value: 100}))
+	assert.False(t, v.Suspended())
+	assert.True(t, v.Yielded() == nil)
+	assert.Equal(t, &objects.Int{Value: 130}, globals[0])
+	assert.True(t, v.IsStackEmpty())
+
+	assert.Equal(t, runtime.ErrNotSuspended, v.Resume(context.Background(), nil))
+
+	// Run cannot be suspended
+	err := v.Run()
+	assert.Error(t, err)
+	assert.Equal(t, "Runtime Error: cannot suspend the execution\n\tat test:5:10\n\tat test:9:7", err.Error())
+
+	// neither can the functions called back by the host
+	callback := &runtime.VMFunction{
+		Name: "callback",
+		Value: func(v *runtime.VM, args ...objects.Object) (objects.Object, error) {
+			return v.Call(args[0])
+		},
+	}
+	v, _ = suspendVM(t, `out = callback(func() { return await(1) })`, await, callback)
+	err = v.RunUntilYield(context.Background())
+	assert.Error(t, err)
+	assert.False(t, v.Suspended())
+}
+
+func suspendVM(t *testing.T, input string, fns ...objects.Object) (*runtime.VM, []objects.Object) {
+	globals := make([]objects.Object, runtime.GlobalsSize)
+
+	symTable := compiler.NewSymbolTable()
+	symTable.Define("out")
+	for _, fn := range fns {
+		name := "await"
+		if f, ok := fn.(*runtime.VMFunction); ok {
+			name = f.Name
+		}
+		globals[symTable.Define(name).Index] = fn
+	}
+
+	file := parse(t, input)
+	c := compiler.NewCompiler(file.InputFile, symTable, nil, nil, nil)
+	assert.NoError(t, c.Compile(file))
+
+	return runtime.NewVM(c.Bytecode(), globals, -1), globals
+}
@@ -3,6 +3,7 @@ package script
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 
 	"github.com/d5/tengo/compiler"
@@ -17,6 +18,9 @@ type Compiled struct {
 	bytecode      *compiler.Bytecode
 	globals       []objects.Object
 	maxAllocs     int64
+	warnings      []*compiler.Warning
+	isolated      bool
+	suspended     *runtime.VM
 	lock          sync.RWMutex
 }
 
@@ -25,37 +29,140 @@ func (c *Compiled) Run() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	c.suspended = nil
 	v := runtime.NewVM(c.bytecode, c.globals, c.maxAllocs)
 
 	return v.Run()
 }
 
-// RunContext is like Run but includes a context.
+// RunContext is like Run but includes a context. The context is passed to
+// the functions that implement objects.ContextCallable, e.g. times.sleep.
 func (c *Compiled) RunContext(ctx context.Context) (err error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	c.suspended = nil
 	v := runtime.NewVM(c.bytecode, c.globals, c.maxAllocs)
 
-	ch := make(chan error, 1)
+	return v.RunContext(ctx)
+}
+
+// RunUntilYield is like RunContext but the host functions can suspend the
+// execution by returning runtime.ErrSuspend, e.g. to wait for an event
+// without blocking the goroutine. It returns true if the execution is
+// completed, or false if it's suspended, in which case Yielded returns the
+// result of the function that suspended it, and Resume continues it.
+func (c *Compiled) RunUntilYield(ctx context.Context) (done bool, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.suspended = nil
+	v := runtime.NewVM(c.bytecode, c.globals, c.maxAllocs)
+
+	return c.yield(v, v.RunUntilYield(ctx))
+}
+
+// Resume resumes the suspended execution with the value as the result of
+// the call to the function that suspended it. Like RunUntilYield, it
+// returns true if the execution is completed, or false if it's suspended
+// again. Running the compiled script by Run, RunContext or RunUntilYield
+// discards the suspended execution.
+func (c *Compiled) Resume(ctx context.Context, value interface{}) (done bool, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.suspended == nil {
+		return false, runtime.ErrNotSuspended
+	}
+
+	obj, err := objects.FromInterface(value)
+	if err != nil {
+		return false, err
+	}
+
+	v := c.suspended
+	c.suspended = nil
+
+	return c.yield(v, v.Resume(ctx, obj))
+}
+
+func (c *Compiled) yield(v *runtime.VM, err error) (done bool, _ error) {
+	if err != nil {
+		return false, err
+	}
+
+	if v.Suspended() {
+		c.suspended = v
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Snapshot writes the global variables and, if the execution is suspended,
+// the stack and frames of the execution to the writer. See VM.Snapshot for
+// how the objects are encoded.
+func (c *Compiled) Snapshot(w io.Writer) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	v := c.suspended
+	if v == nil {
+		v = runtime.NewVM(c.bytecode, c.globals, c.maxAllocs)
+	}
+
+	return v.Snapshot(w)
+}
 
-	go func() {
-		ch <- v.Run()
-	}()
+// Restore reads the state written by Snapshot from the reader, e.g. in
+// another process. The script must be compiled from the same source and
+// modules, and the host values, e.g. the user functions, must be set before
+// Restore. If the execution was suspended, it can be continued by Resume.
+func (c *Compiled) Restore(r io.Reader) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
-	select {
-	case <-ctx.Done():
-		v.Abort()
-		<-ch
-		err = ctx.Err()
-	case err = <-ch:
+	v := runtime.NewVM(c.bytecode, c.globals, c.maxAllocs)
+	if err := v.Restore(r); err != nil {
+		return err
 	}
 
-	return
+	c.suspended = nil
+	if v.Suspended() {
+		c.suspended = v
+	}
+
+	return nil
+}
+
+// Suspended returns true if the execution is suspended.
+func (c *Compiled) Suspended() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.suspended != nil
+}
+
+// Yielded returns the value yielded by the function that suspended the
+// execution. The value is undefined if the execution is not suspended.
+func (c *Compiled) Yielded() *Variable {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	value := objects.UndefinedValue
+	if c.suspended != nil {
+		value = c.suspended.Yielded()
+	}
+
+	return &Variable{
+		value: value,
+	}
 }
 
 // Clone creates a new copy of Compiled.
 // Cloned copies are safe for concurrent use by multiple goroutines.
+// The clones share the global objects with the original unless the
+// isolation is enabled by SetIsolated.
 func (c *Compiled) Clone() *Compiled {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -63,11 +170,18 @@ func (c *Compiled) Clone() *Compiled {
 	clone := &Compiled{
 		globalIndexes: c.globalIndexes,
 		bytecode:      c.bytecode,
-		globals:       make([]objects.Object, len(c.globals)),
 		maxAllocs:     c.maxAllocs,
+		warnings:      c.warnings,
+		isolated:      c.isolated,
+	}
+
+	if c.isolated {
+		clone.globals = isolateGlobals(c.globals)
+		return clone
 	}
 
 	// copy global objects
+	clone.globals = make([]objects.Object, len(c.globals))
 	for idx, g := range c.globals {
 		if g != nil {
 			clone.globals[idx] = g
@@ -77,6 +191,27 @@ func (c *Compiled) Clone() *Compiled {
 	return clone
 }
 
+// SetIsolated sets whether the clones of the compiled script are isolated.
+// The isolated clones, and the runs of a Pool created from an isolated
+// Compiled, deep copy the mutable global objects (maps, arrays, bytes and
+// the variables captured by closures) instead of sharing them, so that a
+// run mutating a global map does not race with another run. The clones
+// inherit the isolation.
+func (c *Compiled) SetIsolated(isolated bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.isolated = isolated
+}
+
+// Warnings returns the compiler warnings of the script.
+func (c *Compiled) Warnings() []*compiler.Warning {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.warnings
+}
+
 // IsDefined returns true if the variable name is defined (has value) before or after the execution.
 func (c *Compiled) IsDefined(name string) bool {
 	c.lock.RLock()
@@ -6,7 +6,10 @@ import (
 	"time"
 
 	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
 	"github.com/d5/tengo/script"
+	"github.com/d5/tengo/stdlib"
 )
 
 type M map[string]interface{}
@@ -92,6 +95,57 @@ func TestCompiled_RunContext(t *testing.T) {
 	assert.Equal(t, context.DeadlineExceeded, err)
 }
 
+func TestCompiled_RunContext_ContextCallable(t *testing.T) {
+	type ctxKey struct{}
+
+	wait := objects.ContextCallableFunc(func(ctx context.Context, args ...objects.Object) (objects.Object, error) {
+		if ctx.Value(ctxKey{}) != nil {
+			return ctx.Value(ctxKey{}).(objects.Object), nil
+		}
+
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	// the context is passed to the function
+	c := compile(t, `a := wait()`, M{"wait": wait})
+	ctx := context.WithValue(context.Background(), ctxKey{}, &objects.String{Value: "foo"})
+	assert.NoError(t, c.RunContext(ctx))
+	compiledGet(t, c, "a", "foo")
+
+	// the blocking function returns when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := c.RunContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	// the function called back from a host function takes the context too
+	callback := &runtime.VMFunction{
+		Name: "callback",
+		Value: func(v *runtime.VM, args ...objects.Object) (objects.Object, error) {
+			return v.Call(args[0], args[1:]...)
+		},
+	}
+	c = compile(t, `a := callback(func() { return wait() })`, M{"wait": wait, "callback": callback})
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, c.RunContext(ctx))
+
+	// times.sleep returns when the context is done
+	s := script.New([]byte(`times := import("times"); times.sleep(60 * times.second)`))
+	s.SetImports(stdlib.GetModuleMap("times"))
+	c, err = s.Compile()
+	assert.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, c.RunContext(ctx))
+	assert.True(t, time.Since(start) < 10*time.Second)
+}
+
 func compile(t *testing.T, input string, vars M) *script.Compiled {
 	s := script.New([]byte(input))
 	for vn, vv := range vars {
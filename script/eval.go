@@ -0,0 +1,301 @@
+package script
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/d5/tengo/compiler"
+	"github.com/d5/tengo/compiler/ast"
+	"github.com/d5/tengo/compiler/parser"
+	"github.com/d5/tengo/compiler/source"
+	"github.com/d5/tengo/compiler/token"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+)
+
+// DefaultEvalCacheSize is the default maximum number of the compiled
+// expressions cached by an Evaluator.
+const DefaultEvalCacheSize = 1024
+
+const evalResultName = "__eval_result__"
+
+var defaultEvaluator = NewEvaluator(nil)
+
+// Eval evaluates the expression with the variables, and returns the result
+// value. The compiled expressions are cached, so evaluating the same
+// expression again only binds the variables and runs it. The expression
+// cannot import modules: use an Evaluator with the modules for that.
+//
+//	res, err := script.Eval(ctx, `price > 100 && tier == "gold"`, map[string]interface{}{
+//		"price": 120,
+//		"tier":  "gold",
+//	})
+func Eval(ctx context.Context, expr string, vars map[string]interface{}) (interface{}, error) {
+	return defaultEvaluator.Eval(ctx, expr, vars)
+}
+
+// Evaluator evaluates single expressions, caching the compiled expressions
+// by their sources and reusing the VMs that run them. It is safe for
+// concurrent use by multiple goroutines.
+type Evaluator struct {
+	modules   *objects.ModuleMap
+	maxAllocs int64
+	lock      sync.Mutex
+	cacheSize int
+	cache     map[string]*list.Element
+	lru       *list.List // most recently used first
+}
+
+// evalExpr is a compiled expression.
+type evalExpr struct {
+	src       string
+	bytecode  *compiler.Bytecode
+	names     map[string]int // variable name to global index
+	result    int            // global index of the result
+	numGlobal int
+	maxAllocs int64
+	machines  sync.Pool // *evalMachine
+}
+
+// evalMachine is a VM with its globals.
+type evalMachine struct {
+	vm      *runtime.VM
+	globals []objects.Object
+}
+
+// NewEvaluator creates an Evaluator. The expressions can import the modules
+// in the module map, which can be nil.
+func NewEvaluator(modules *objects.ModuleMap) *Evaluator {
+	return &Evaluator{
+		modules:   modules,
+		maxAllocs: -1,
+		cacheSize: DefaultEvalCacheSize,
+		cache:     make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// SetMaxAllocs sets the maximum number of objects allocations while
+// evaluating an expression.
+func (e *Evaluator) SetMaxAllocs(n int64) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.maxAllocs = n
+	e.clear()
+}
+
+// SetCacheSize sets the maximum number of the cached expressions. The least
+// recently used expressions are removed from the cache if it is full.
+func (e *Evaluator) SetCacheSize(n int) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.cacheSize = n
+	e.evict()
+}
+
+// Len returns the number of the cached expressions.
+func (e *Evaluator) Len() int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.lru.Len()
+}
+
+// Eval evaluates the expression with the variables, and returns the result
+// value converted by objects.ToInterface. The identifiers in the expression
+// other than the builtin functions are the variables, which are undefined
+// if they are not in vars. The context is used the same way as
+// Compiled.RunContext.
+func (e *Evaluator) Eval(ctx context.Context, expr string, vars map[string]interface{}) (interface{}, error) {
+	res, err := e.EvalObject(ctx, expr, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return objects.ToInterface(res), nil
+}
+
+// EvalObject is like Eval but returns the result object.
+func (e *Evaluator) EvalObject(ctx context.Context, expr string, vars map[string]interface{}) (objects.Object, error) {
+	compiled, err := e.compiled(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	m, _ := compiled.machines.Get().(*evalMachine)
+	if m == nil {
+		globals := make([]objects.Object, compiled.numGlobal)
+		m = &evalMachine{
+			vm:      runtime.NewVM(compiled.bytecode, globals, compiled.maxAllocs),
+			globals: globals,
+		}
+	}
+
+	for i := range m.globals {
+		m.globals[i] = objects.UndefinedValue
+	}
+	for name, value := range vars {
+		idx, ok := compiled.names[name]
+		if !ok {
+			continue
+		}
+
+		obj, err := objects.FromInterface(value)
+		if err != nil {
+			return nil, err
+		}
+		m.globals[idx] = obj
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	err = m.vm.RunContext(ctx)
+	res := m.globals[compiled.result]
+
+	// release the values for the garbage collector
+	for i := range m.globals {
+		m.globals[i] = nil
+	}
+
+	// the VM aborted by the context is not reused
+	if err == nil || ctx.Err() == nil {
+		compiled.machines.Put(m)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// compiled returns the compiled expression from the cache, or compiles and
+// caches the expression if it's not in the cache.
+func (e *Evaluator) compiled(expr string) (*evalExpr, error) {
+	e.lock.Lock()
+	if elem, ok := e.cache[expr]; ok {
+		e.lru.MoveToFront(elem)
+		e.lock.Unlock()
+		return elem.Value.(*evalExpr), nil
+	}
+	maxAllocs := e.maxAllocs
+	e.lock.Unlock()
+
+	compiled, err := compileExpr(expr, e.modules)
+	if err != nil {
+		return nil, err
+	}
+	compiled.maxAllocs = maxAllocs
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	// compiled by another goroutine
+	if elem, ok := e.cache[expr]; ok {
+		e.lru.MoveToFront(elem)
+		return elem.Value.(*evalExpr), nil
+	}
+
+	// the max allocs changed while compiling
+	if maxAllocs != e.maxAllocs {
+		return compiled, nil
+	}
+
+	e.cache[expr] = e.lru.PushFront(compiled)
+	e.evict()
+
+	return compiled, nil
+}
+
+func (e *Evaluator) evict() {
+	for e.lru.Len() > 0 && e.lru.Len() > e.cacheSize {
+		elem := e.lru.Back()
+		e.lru.Remove(elem)
+		delete(e.cache, elem.Value.(*evalExpr).src)
+	}
+}
+
+func (e *Evaluator) clear() {
+	e.cache = make(map[string]*list.Element)
+	e.lru.Init()
+}
+
+// compileExpr compiles the expression into the assignment of the result
+// to a global variable, and defines the identifiers in the expression as
+// the global variables.
+func compileExpr(expr string, modules *objects.ModuleMap) (*evalExpr, error) {
+	fileSet := source.NewFileSet()
+	srcFile := fileSet.AddFile("(eval)", -1, len(expr))
+
+	p := parser.NewParser(srcFile, []byte(expr), nil)
+	file, err := p.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(file.Stmts) != 1 {
+		return nil, errors.New("not a single expression")
+	}
+	stmt, ok := file.Stmts[0].(*ast.ExprStmt)
+	if !ok {
+		return nil, errors.New("not a single expression")
+	}
+
+	symbolTable := compiler.NewSymbolTable()
+	builtins := make(map[string]bool, len(objects.Builtins))
+	for idx, fn := range objects.Builtins {
+		symbolTable.DefineBuiltin(idx, fn.Name)
+		builtins[fn.Name] = true
+	}
+
+	var idents []string
+	seen := make(map[string]bool)
+	ast.Inspect(stmt.Expr, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok {
+			name := ident.Name
+			if !builtins[name] && !seen[name] && name != evalResultName && name != "_" {
+				seen[name] = true
+				idents = append(idents, name)
+			}
+		}
+
+		return true
+	})
+	sort.Strings(idents)
+
+	names := make(map[string]int, len(idents))
+	for _, name := range idents {
+		names[name] = symbolTable.Define(name).Index
+	}
+	result := symbolTable.Define(evalResultName)
+
+	// __eval_result__ = expr
+	file.Stmts[0] = &ast.AssignStmt{
+		LHS:      []ast.Expr{&ast.Ident{Name: evalResultName, NamePos: stmt.Pos()}},
+		RHS:      []ast.Expr{stmt.Expr},
+		Token:    token.Assign,
+		TokenPos: stmt.Pos(),
+	}
+
+	c := compiler.NewCompiler(srcFile, symbolTable, nil, modules, nil)
+	if err := c.Compile(file); err != nil {
+		return nil, err
+	}
+
+	bytecode := c.Bytecode()
+	bytecode.RemoveDuplicates()
+
+	return &evalExpr{
+		src:       expr,
+		bytecode:  bytecode,
+		names:     names,
+		result:    result.Index,
+		numGlobal: symbolTable.MaxSymbols() + 1,
+	}, nil
+}
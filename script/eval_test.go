@@ -0,0 +1,129 @@
+package script_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/script"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestEval(t *testing.T) {
+	ctx := context.Background()
+
+	expectEval(t, `price > 100 && tier == "gold"`, M{"price": 120, "tier": "gold"}, true)
+	expectEval(t, `price > 100 && tier == "gold"`, M{"price": 80, "tier": "gold"}, false)
+	expectEval(t, `price > 100 && tier == "gold"`, M{"price": 120, "tier": "silver"}, false)
+	expectEval(t, `a * 2 + b`, M{"a": 3, "b": 4}, int64(10))
+	expectEval(t, `len(items) > 0 ? items[0].name : "none"`, M{"items": []interface{}{map[string]interface{}{"name": "foo"}}}, "foo")
+	expectEval(t, `len(items) > 0 ? items[0].name : "none"`, M{"items": []interface{}{}}, "none")
+	expectEval(t, `func(x) { return x + y }(1)`, M{"y": 2}, int64(3))
+	expectEval(t, `string(a) + "!"`, M{"a": 1, "unused": 2}, "1!")
+
+	// undefined variables
+	expectEval(t, `is_undefined(a)`, nil, true)
+	expectEval(t, `a`, nil, nil)
+
+	// errors
+	_, err := script.Eval(ctx, `a := 1`, nil)
+	assert.Error(t, err)
+	_, err = script.Eval(ctx, `a; b`, nil)
+	assert.Error(t, err)
+	_, err = script.Eval(ctx, `a +`, nil)
+	assert.Error(t, err)
+	_, err = script.Eval(ctx, `import("fmt")`, nil)
+	assert.Error(t, err)
+	_, err = script.Eval(ctx, `a + 1`, M{"a": "x"})
+	assert.NoError(t, err)
+	_, err = script.Eval(ctx, `a + 1`, M{"a": true})
+	assert.Error(t, err)
+
+	// the VM is reusable after an error
+	expectEval(t, `a + 1`, M{"a": 1}, int64(2))
+}
+
+func TestEvaluator(t *testing.T) {
+	e := script.NewEvaluator(stdlib.GetModuleMap("math", "times"))
+
+	res, err := e.Eval(context.Background(), `import("math").abs(x)`, M{"x": -1.5})
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, res)
+	assert.Equal(t, 1, e.Len())
+
+	// cache
+	e.SetCacheSize(2)
+	for _, expr := range []string{`a + 1`, `a + 2`, `a + 1`, `a + 3`} {
+		_, err := e.Eval(context.Background(), expr, M{"a": 1})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, e.Len())
+	e.SetCacheSize(1)
+	assert.Equal(t, 1, e.Len())
+
+	// objects
+	obj, err := e.EvalObject(context.Background(), `[a, b]`, M{"a": 1, "b": "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(obj.(*objects.Array).Value))
+
+	// allocation limit
+	e.SetMaxAllocs(2)
+	assert.Equal(t, 0, e.Len())
+	_, err = e.Eval(context.Background(), `[1, 2, 3] + [4]`, nil)
+	assert.Error(t, err)
+
+	// context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = e.Eval(ctx, `import("times").sleep(60 * import("times").second)`, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestEval_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				res, err := script.Eval(context.Background(), `a * b + c`, M{"a": i, "b": j, "c": 1})
+				assert.NoError(t, err)
+				assert.Equal(t, int64(i*j+1), res)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func expectEval(t *testing.T, expr string, vars M, expected interface{}) {
+	res, err := script.Eval(context.Background(), expr, vars)
+	if !assert.NoError(t, err, expr) {
+		return
+	}
+
+	assert.Equal(t, expected, res, expr)
+}
+
+func TestEval_ContextDone(t *testing.T) {
+	// the VMs aborted by the contexts must not be reused
+	e := script.NewEvaluator(nil)
+	expr := `func() { s := 0; for i := 0; i < n; i++ { s += i }; return s }()`
+	for i := 0; i < 500; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(i%50)*time.Microsecond)
+		res, err := e.Eval(ctx, expr, map[string]interface{}{"n": 1000})
+		cancel()
+		if err == nil {
+			assert.Equal(t, int64(499500), res)
+		}
+
+		res, err = e.Eval(context.Background(), expr, map[string]interface{}{"n": 100})
+		assert.NoError(t, err)
+		if !assert.Equal(t, int64(4950), res) {
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package script
+
+import (
+	"github.com/d5/tengo/objects"
+)
+
+// isolateGlobals returns a deep copy of the globals. The objects referenced
+// more than once, e.g. a map stored in two globals or the variable shared
+// by two closures, are copied once and stay shared in the copy.
+func isolateGlobals(globals []objects.Object) []objects.Object {
+	copied := make(map[objects.Object]objects.Object)
+
+	res := make([]objects.Object, len(globals))
+	for idx, g := range globals {
+		if g != nil {
+			res[idx] = isolate(g, copied)
+		}
+	}
+
+	return res
+}
+
+// isolate deep copies the mutable object. The immutable objects are not
+// copied, and the unknown types are copied by their Copy methods.
+func isolate(o objects.Object, copied map[objects.Object]objects.Object) objects.Object {
+	switch o.(type) {
+	case *objects.Int, *objects.Float, *objects.String, *objects.Char,
+		*objects.Bool, *objects.Undefined, *objects.Time,
+		*objects.CompiledFunction, *objects.BuiltinFunction, *objects.UserFunction:
+		return o
+	}
+
+	if c, ok := copied[o]; ok {
+		return c
+	}
+
+	switch o := o.(type) {
+	case *objects.Array:
+		c := &objects.Array{Value: make([]objects.Object, len(o.Value))}
+		copied[o] = c
+		for i, e := range o.Value {
+			c.Value[i] = isolate(e, copied)
+		}
+
+		return c
+	case *objects.ImmutableArray:
+		c := &objects.ImmutableArray{Value: make([]objects.Object, len(o.Value))}
+		copied[o] = c
+		for i, e := range o.Value {
+			c.Value[i] = isolate(e, copied)
+		}
+
+		return c
+	case *objects.Map:
+		c := &objects.Map{Value: make(map[string]objects.Object, len(o.Value))}
+		copied[o] = c
+		for k, e := range o.Value {
+			c.Value[k] = isolate(e, copied)
+		}
+
+		return c
+	case *objects.ImmutableMap:
+		c := &objects.ImmutableMap{Value: make(map[string]objects.Object, len(o.Value))}
+		copied[o] = c
+		for k, e := range o.Value {
+			c.Value[k] = isolate(e, copied)
+		}
+
+		return c
+	case *objects.Error:
+		c := &objects.Error{}
+		copied[o] = c
+		if o.Value != nil {
+			c.Value = isolate(o.Value, copied)
+		}
+
+		return c
+	case *objects.ObjectPtr:
+		c := &objects.ObjectPtr{}
+		copied[o] = c
+		if o.Value != nil {
+			v := isolate(*o.Value, copied)
+			c.Value = &v
+		}
+
+		return c
+	case *objects.Closure:
+		c := &objects.Closure{Fn: o.Fn, Free: make([]*objects.ObjectPtr, len(o.Free))}
+		copied[o] = c
+		for i, p := range o.Free {
+			c.Free[i] = isolate(p, copied).(*objects.ObjectPtr)
+		}
+
+		return c
+	}
+
+	c := o.Copy()
+	copied[o] = c
+
+	return c
+}
@@ -0,0 +1,150 @@
+package script_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/script"
+)
+
+func TestCompiled_SetIsolated(t *testing.T) {
+	s := script.New([]byte(`
+counts.n += 1
+items = append(items, counts.n)
+nested.inner.v = n
+inc()
+out := get()
+`))
+	shared := &objects.Map{Value: map[string]objects.Object{"n": &objects.Int{Value: 0}}}
+	assert.NoError(t, s.Add("counts", shared))
+	assert.NoError(t, s.Add("alias", shared))
+	assert.NoError(t, s.Add("items", []interface{}{}))
+	assert.NoError(t, s.Add("nested", &objects.ImmutableMap{Value: map[string]objects.Object{
+		"inner": &objects.Map{Value: map[string]objects.Object{}},
+	}}))
+	assert.NoError(t, s.Add("n", 0))
+	assert.NoError(t, s.Add("inc", nil))
+	assert.NoError(t, s.Add("get", nil))
+
+	// closures sharing a free variable
+	setup := script.New([]byte(`
+f := func() {
+	x := 0
+	return [func() { x += 1 }, func() { return x }]
+}()
+inc := f[0]
+get := f[1]
+`))
+	sc, err := setup.Run()
+	assert.NoError(t, err)
+
+	c, err := s.Compile()
+	assert.NoError(t, err)
+	assert.NoError(t, c.Set("inc", sc.Get("inc").Object()))
+	assert.NoError(t, c.Set("get", sc.Get("get").Object()))
+	c.SetIsolated(true)
+
+	var wg sync.WaitGroup
+	clones := make([]*script.Compiled, 20)
+	for i := range clones {
+		clones[i] = c.Clone()
+		assert.NoError(t, clones[i].Set("n", i))
+
+		wg.Add(1)
+		go func(clone *script.Compiled) {
+			defer wg.Done()
+			assert.NoError(t, clone.Run())
+		}(clones[i])
+	}
+	wg.Wait()
+
+	for i, clone := range clones {
+		assert.Equal(t, int64(1), clone.Get("counts").Map()["n"])
+		assert.Equal(t, 1, len(clone.Get("items").Array()))
+		assert.Equal(t, 1, clone.Get("out").Int())
+		inner := clone.Get("nested").Object().(*objects.ImmutableMap).Value["inner"].(*objects.Map)
+		assert.Equal(t, &objects.Int{Value: int64(i)}, inner.Value["v"])
+
+		// the aliases of a copied map are kept
+		assert.True(t, clone.Get("counts").Object() == clone.Get("alias").Object())
+	}
+
+	// the original objects are not changed
+	assert.Equal(t, &objects.Int{Value: 0}, shared.Value["n"])
+	assert.Equal(t, 0, len(c.Get("items").Array()))
+
+	// clones of an isolated clone are isolated
+	clone := clones[0].Clone()
+	assert.NoError(t, clone.Run())
+	assert.Equal(t, int64(2), clone.Get("counts").Map()["n"])
+	assert.Equal(t, int64(1), clones[0].Get("counts").Map()["n"])
+}
+
+func TestPool_Isolated(t *testing.T) {
+	s := script.New([]byte(`
+for i := 0; i < 100; i++ {
+	m[string(i)] = i
+}
+out := len(m)
+`))
+	assert.NoError(t, s.Add("m", map[string]interface{}{}))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+	c.SetIsolated(true)
+
+	pool := script.NewPool(c, 4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			res, err := pool.Run(context.Background(), nil)
+			if assert.NoError(t, err) {
+				assert.Equal(t, 100, res.Get("out").Int())
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 0, len(c.Get("m").Map()))
+}
+
+func TestCompiled_SetIsolated_String(t *testing.T) {
+	// the global and the constant strings are shared, and the concurrent
+	// runs index and iterate them
+	s := script.New([]byte(`
+out := s[1]
+n := 0
+for c in s { n++ }
+for c in "héllo" { n++ }
+x := "héllo"[1]
+`))
+	assert.NoError(t, s.Add("s", "héllo"))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+	c.SetIsolated(true)
+
+	var wg sync.WaitGroup
+	clones := make([]*script.Compiled, 8)
+	for i := range clones {
+		clones[i] = c.Clone()
+
+		wg.Add(1)
+		go func(clone *script.Compiled) {
+			defer wg.Done()
+			assert.NoError(t, clone.Run())
+		}(clones[i])
+	}
+	wg.Wait()
+
+	for _, clone := range clones {
+		assert.Equal(t, 'é', clone.Get("out").Char())
+		assert.Equal(t, 10, clone.Get("n").Int())
+		assert.Equal(t, 'é', clone.Get("x").Char())
+	}
+}
@@ -0,0 +1,197 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	goruntime "runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+)
+
+// Pool runs a compiled script concurrently, reusing a bounded set of VMs
+// and their globals. It is safe for concurrent use by multiple goroutines.
+type Pool struct {
+	// accessed atomically: keep them first for 64-bit alignment
+	runs     int64
+	failures int64
+	waitTime int64
+
+	compiled *Compiled        // without the globals
+	globals  []objects.Object // the globals every run starts with
+	isolated bool
+	slots    chan struct{}
+	lock     sync.Mutex
+	free     []*poolMachine
+}
+
+// PoolStats is the metrics of a Pool.
+type PoolStats struct {
+	// Runs is the number of the completed runs.
+	Runs int64
+	// Failures is the number of the runs that returned an error, including
+	// the runs whose context was done while waiting for a VM.
+	Failures int64
+	// WaitTime is the total time the runs spent waiting for a VM.
+	WaitTime time.Duration
+}
+
+// poolMachine is a VM with its globals.
+type poolMachine struct {
+	vm      *runtime.VM
+	globals []objects.Object
+}
+
+// NewPool creates a Pool that runs at most size copies of the compiled
+// script at the same time. If size is less than 1, the number of CPUs is
+// used. Every run starts with the global variables of the compiled script
+// at the time the Pool is created. If the compiled script is isolated (see
+// Compiled.SetIsolated), every run starts with a deep copy of them.
+func NewPool(c *Compiled, size int) *Pool {
+	if size < 1 {
+		size = goruntime.NumCPU()
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	globals := make([]objects.Object, len(c.globals))
+	copy(globals, c.globals)
+
+	return &Pool{
+		compiled: &Compiled{
+			globalIndexes: c.globalIndexes,
+			bytecode:      c.bytecode,
+			maxAllocs:     c.maxAllocs,
+			warnings:      c.warnings,
+		},
+		globals:  globals,
+		isolated: c.isolated,
+		slots:    make(chan struct{}, size),
+	}
+}
+
+// Run runs the compiled script with the variables, which replace the
+// values of the global variables of the same names for this run. It waits
+// for a VM if the maximum number of the runs are running. The context is
+// used the same way as Compiled.RunContext, and Run returns the context
+// error if it is done while waiting.
+//
+// The returned Compiled holds the global variables after the run, and can
+// be used to Get the results.
+func (p *Pool) Run(ctx context.Context, vars map[string]interface{}) (*Compiled, error) {
+	res, err := p.run(ctx, vars)
+	atomic.AddInt64(&p.runs, 1)
+	if err != nil {
+		atomic.AddInt64(&p.failures, 1)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (p *Pool) run(ctx context.Context, vars map[string]interface{}) (*Compiled, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	// convert the variables before waiting for a VM
+	overrides := make(map[int]objects.Object, len(vars))
+	for name, value := range vars {
+		idx, ok := p.compiled.globalIndexes[name]
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not defined", name)
+		}
+
+		obj, err := objects.FromInterface(value)
+		if err != nil {
+			return nil, err
+		}
+		overrides[idx] = obj
+	}
+
+	start := time.Now()
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		atomic.AddInt64(&p.waitTime, int64(time.Since(start)))
+		return nil, ctx.Err()
+	}
+	atomic.AddInt64(&p.waitTime, int64(time.Since(start)))
+	defer func() { <-p.slots }()
+
+	m := p.get()
+	defer p.put(m)
+
+	if p.isolated {
+		copy(m.globals, isolateGlobals(p.globals))
+	} else {
+		copy(m.globals, p.globals)
+	}
+	for idx, obj := range overrides {
+		m.globals[idx] = obj
+	}
+
+	if err := m.vm.RunContext(ctx); err != nil {
+		return nil, err
+	}
+
+	res := &Compiled{
+		globalIndexes: p.compiled.globalIndexes,
+		bytecode:      p.compiled.bytecode,
+		globals:       make([]objects.Object, len(m.globals)),
+		maxAllocs:     p.compiled.maxAllocs,
+		warnings:      p.compiled.warnings,
+		isolated:      p.isolated,
+	}
+	copy(res.globals, m.globals)
+
+	return res, nil
+}
+
+// Stats returns the metrics of the Pool.
+func (p *Pool) Stats() PoolStats {
+	return PoolStats{
+		Runs:     atomic.LoadInt64(&p.runs),
+		Failures: atomic.LoadInt64(&p.failures),
+		WaitTime: time.Duration(atomic.LoadInt64(&p.waitTime)),
+	}
+}
+
+// Size returns the maximum number of the concurrent runs.
+func (p *Pool) Size() int {
+	return cap(p.slots)
+}
+
+func (p *Pool) get() *poolMachine {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if n := len(p.free); n > 0 {
+		m := p.free[n-1]
+		p.free = p.free[:n-1]
+		return m
+	}
+
+	globals := make([]objects.Object, len(p.globals))
+
+	return &poolMachine{
+		vm:      runtime.NewVM(p.compiled.bytecode, globals, p.compiled.maxAllocs),
+		globals: globals,
+	}
+}
+
+func (p *Pool) put(m *poolMachine) {
+	// release the values for the garbage collector
+	for i := range m.globals {
+		m.globals[i] = nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.free = append(p.free, m)
+}
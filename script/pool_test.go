@@ -0,0 +1,127 @@
+package script_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/script"
+)
+
+func TestPool(t *testing.T) {
+	s := script.New([]byte(`out := a * b + c`))
+	assert.NoError(t, s.Add("a", 0))
+	assert.NoError(t, s.Add("b", 0))
+	assert.NoError(t, s.Add("c", 100))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+
+	pool := script.NewPool(c, 4)
+	assert.Equal(t, 4, pool.Size())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			res, err := pool.Run(context.Background(), map[string]interface{}{"a": i, "b": 2})
+			if assert.NoError(t, err) {
+				assert.Equal(t, i*2+100, res.Get("out").Int())
+				assert.Equal(t, i, res.Get("a").Int())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// the overrides do not change the compiled script or the next runs
+	assert.Equal(t, 0, c.Get("a").Int())
+	res, err := pool.Run(context.Background(), map[string]interface{}{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 100, res.Get("out").Int())
+
+	_, err = pool.Run(context.Background(), map[string]interface{}{"x": 1})
+	assert.Error(t, err)
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(52), stats.Runs)
+	assert.Equal(t, int64(1), stats.Failures)
+}
+
+func TestPool_Context(t *testing.T) {
+	block := make(chan struct{})
+	s := script.New([]byte(`wait()`))
+	assert.NoError(t, s.Add("wait", &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			<-block
+			return objects.UndefinedValue, nil
+		},
+	}))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+
+	pool := script.NewPool(c, 1)
+
+	done := make(chan error)
+	go func() {
+		_, err := pool.Run(context.Background(), nil)
+		done <- err
+	}()
+
+	// no VM is available while the first run is blocked
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = pool.Run(ctx, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	close(block)
+	assert.NoError(t, <-done)
+
+	stats := pool.Stats()
+	assert.Equal(t, int64(2), stats.Runs)
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.True(t, stats.WaitTime >= 20*time.Millisecond)
+
+	// cancelled while running
+	s = script.New([]byte(`for {}`))
+	c, err = s.Compile()
+	assert.NoError(t, err)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = script.NewPool(c, 0).Run(ctx, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestPool_ContextDoneAtEnd(t *testing.T) {
+	// the context is done at about the same time as the run finishes, which
+	// must not leave the pooled VM aborted for the next run
+	var cancel context.CancelFunc
+	s := script.New([]byte(`out := a * 2; done()`))
+	assert.NoError(t, s.Add("a", 0))
+	assert.NoError(t, s.Add("done", &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			cancel()
+			return objects.UndefinedValue, nil
+		},
+	}))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+
+	pool := script.NewPool(c, 1)
+	for i := 0; i < 200; i++ {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		_, _ = pool.Run(ctx, map[string]interface{}{"a": i})
+
+		cancel = func() {}
+		res, err := pool.Run(context.Background(), map[string]interface{}{"a": i})
+		if !assert.NoError(t, err) || !assert.Equal(t, i*2, res.Get("out").Int()) {
+			return
+		}
+	}
+}
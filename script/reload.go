@@ -0,0 +1,151 @@
+package script
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/d5/tengo/objects"
+)
+
+// ReloadReport is the changes of the global variables by Compiled.Reload.
+type ReloadReport struct {
+	// Added is the names of the global variables that are defined only by
+	// the new script.
+	Added []string
+
+	// Removed is the names of the global variables that are not defined by
+	// the new script. Their values are discarded.
+	Removed []string
+
+	// TypeChanged is the names of the global variables whose values are
+	// replaced by the values of different types added to the new script.
+	TypeChanged []string
+
+	// Reset is the names of the global variables whose values hold the
+	// functions compiled from the current script, e.g. functions, closures
+	// or maps of them. The functions refer to the constants of the current
+	// script, so the values are replaced by the values of the new script.
+	Reset []string
+}
+
+// Reload compiles the script, and replaces the compiled script with it,
+// keeping the values of the global variables that are defined by both of
+// them, except the values that hold compiled functions, which are reported
+// as Reset. The values of the variables added to the new script
+// (Script.Add) replace the current values only if their types are
+// different, and such variables are reported as TypeChanged.
+//
+// If the script fails to compile, the compiled script is not changed. The
+// runs in progress finish on the current version, and the global variables
+// are mapped after they finish. A suspended execution cannot be reloaded.
+// The clones and the pools created before Reload are not changed.
+func (c *Compiled) Reload(s *Script) (*ReloadReport, error) {
+	next, err := s.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.suspended != nil {
+		return nil, errors.New("cannot reload a suspended execution")
+	}
+
+	report := &ReloadReport{}
+	for name, idx := range next.globalIndexes {
+		oldIdx, ok := c.globalIndexes[name]
+		if !ok {
+			report.Added = append(report.Added, name)
+			continue
+		}
+
+		old := c.globals[oldIdx]
+		if old == nil {
+			continue
+		}
+
+		if holdsCompiledFunction(old, make(map[objects.Object]bool)) {
+			report.Reset = append(report.Reset, name)
+			continue
+		}
+
+		value := next.globals[idx]
+		if value != nil && value != objects.UndefinedValue && value.TypeName() != old.TypeName() {
+			report.TypeChanged = append(report.TypeChanged, name)
+			continue
+		}
+
+		next.globals[idx] = old
+	}
+	for name := range c.globalIndexes {
+		if _, ok := next.globalIndexes[name]; !ok {
+			report.Removed = append(report.Removed, name)
+		}
+	}
+	sort.Strings(report.Added)
+	sort.Strings(report.Removed)
+	sort.Strings(report.TypeChanged)
+	sort.Strings(report.Reset)
+
+	c.globalIndexes = next.globalIndexes
+	c.bytecode = next.bytecode
+	c.globals = next.globals
+	c.maxAllocs = next.maxAllocs
+	c.warnings = next.warnings
+
+	return report, nil
+}
+
+// holdsCompiledFunction returns true if the object is a compiled function
+// or a closure, or contains one.
+func holdsCompiledFunction(o objects.Object, seen map[objects.Object]bool) bool {
+	switch o := o.(type) {
+	case *objects.CompiledFunction, *objects.Closure:
+		return true
+	case *objects.Array:
+		return elementsHoldCompiledFunction(o, o.Value, seen)
+	case *objects.ImmutableArray:
+		return elementsHoldCompiledFunction(o, o.Value, seen)
+	case *objects.Map:
+		return valuesHoldCompiledFunction(o, o.Value, seen)
+	case *objects.ImmutableMap:
+		return valuesHoldCompiledFunction(o, o.Value, seen)
+	case *objects.Error:
+		return o.Value != nil && holdsCompiledFunction(o.Value, seen)
+	case *objects.ObjectPtr:
+		return o.Value != nil && *o.Value != nil && holdsCompiledFunction(*o.Value, seen)
+	}
+
+	return false
+}
+
+func elementsHoldCompiledFunction(o objects.Object, elems []objects.Object, seen map[objects.Object]bool) bool {
+	if seen[o] {
+		return false
+	}
+	seen[o] = true
+
+	for _, e := range elems {
+		if e != nil && holdsCompiledFunction(e, seen) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func valuesHoldCompiledFunction(o objects.Object, values map[string]objects.Object, seen map[objects.Object]bool) bool {
+	if seen[o] {
+		return false
+	}
+	seen[o] = true
+
+	for _, e := range values {
+		if e != nil && holdsCompiledFunction(e, seen) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,164 @@
+package script_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+	"github.com/d5/tengo/script"
+)
+
+func TestCompiled_Reload(t *testing.T) {
+	s := script.New([]byte(`
+count = is_undefined(count) ? 1 : count + 1
+old := "old"
+m := mode
+`))
+	assert.NoError(t, s.Add("count", nil))
+	assert.NoError(t, s.Add("mode", 1))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+
+	assert.NoError(t, c.Run())
+	assert.NoError(t, c.Run())
+	assert.Equal(t, 2, c.Get("count").Int())
+	assert.NoError(t, c.Set("mode", 2))
+
+	// same type: the current value is kept
+	s = script.New([]byte(`
+count = is_undefined(count) ? 1 : count + 10
+total := count * 2
+m := mode
+`))
+	assert.NoError(t, s.Add("count", nil))
+	assert.NoError(t, s.Add("mode", 5))
+	report, err := c.Reload(s)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(report.Added))
+	assert.Equal(t, "total", report.Added[0])
+	assert.Equal(t, 1, len(report.Removed))
+	assert.Equal(t, "old", report.Removed[0])
+	assert.Equal(t, 0, len(report.TypeChanged))
+
+	assert.NoError(t, c.Run())
+	assert.Equal(t, 12, c.Get("count").Int())
+	assert.Equal(t, 24, c.Get("total").Int())
+	assert.Equal(t, 2, c.Get("mode").Int())
+	assert.Equal(t, 2, c.Get("m").Int())
+	assert.False(t, c.IsDefined("old"))
+
+	// different type: the value of the new script is used
+	s = script.New([]byte(`total := count * 2`))
+	assert.NoError(t, s.Add("count", "a"))
+	report, err = c.Reload(s)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(report.TypeChanged))
+	assert.Equal(t, "count", report.TypeChanged[0])
+	assert.Equal(t, 2, len(report.Removed))
+	assert.Equal(t, "m", report.Removed[0])
+	assert.Equal(t, "mode", report.Removed[1])
+	assert.Error(t, c.Run())
+
+	// compile error: nothing is changed
+	_, err = c.Reload(script.New([]byte(`total :=`)))
+	assert.Error(t, err)
+	assert.Equal(t, "a", c.Get("count").String())
+}
+
+func TestCompiled_Reload_InFlight(t *testing.T) {
+	started := make(chan struct{})
+	block := make(chan struct{})
+	wait := &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			close(started)
+			<-block
+			return objects.UndefinedValue, nil
+		},
+	}
+
+	s := script.New([]byte(`wait(); version := 1`))
+	assert.NoError(t, s.Add("wait", wait))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- c.Run()
+	}()
+	<-started
+
+	reloaded := make(chan error)
+	go func() {
+		s := script.New([]byte(`version = version + 1`))
+		_ = s.Add("version", 0)
+		_, err := c.Reload(s)
+		reloaded <- err
+	}()
+
+	// the reload waits for the run in progress
+	select {
+	case <-reloaded:
+		t.Fatal("reloaded while running")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(block)
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-reloaded)
+
+	assert.Equal(t, 1, c.Get("version").Int())
+	assert.NoError(t, c.Run())
+	assert.Equal(t, 2, c.Get("version").Int())
+
+	// a suspended execution cannot be reloaded
+	s = script.New([]byte(`await()`))
+	assert.NoError(t, s.Add("await", &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			return nil, runtime.ErrSuspend
+		},
+	}))
+	c, err = s.Compile()
+	assert.NoError(t, err)
+	_, err = c.RunUntilYield(context.Background())
+	assert.NoError(t, err)
+	_, err = c.Reload(script.New([]byte(`a := 1`)))
+	assert.Error(t, err)
+}
+
+func TestCompiled_Reload_Functions(t *testing.T) {
+	s := script.New([]byte(`
+version := func() { return "v1" }
+handlers := {get: func() { return "get v1" }}
+counter := func() { n := 0; return func() { n += 1; return n } }()
+data := {list: [1, 2]}
+`))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+	assert.NoError(t, c.Run())
+
+	// the functions of the old script would load the constants of the new
+	// script, e.g. "AAA"
+	s = script.New([]byte(`
+pad := "AAA"
+out := is_undefined(version) ? "reset" : version()
+get := is_undefined(handlers) ? "reset" : handlers.get()
+data.list = append(data.list, 3)
+`))
+	for _, name := range []string{"version", "handlers", "counter", "data"} {
+		assert.NoError(t, s.Add(name, nil))
+	}
+	report, err := c.Reload(s)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(report.Reset))
+	assert.Equal(t, "counter", report.Reset[0])
+	assert.Equal(t, "handlers", report.Reset[1])
+	assert.Equal(t, "version", report.Reset[2])
+
+	assert.NoError(t, c.Run())
+	assert.Equal(t, "reset", c.Get("out").String())
+	assert.Equal(t, "reset", c.Get("get").String())
+	assert.Equal(t, 3, len(c.Get("data").Map()["list"].([]interface{})))
+}
@@ -18,16 +18,20 @@ type Script struct {
 	input            []byte
 	maxAllocs        int64
 	maxConstObjects  int
+	maxCompileErrors int
+	warningsAsErrors bool
 	enableFileImport bool
+	moduleCache      *compiler.ModuleCache
 }
 
 // New creates a Script instance with an input script.
 func New(input []byte) *Script {
 	return &Script{
-		variables:       make(map[string]*Variable),
-		input:           input,
-		maxAllocs:       -1,
-		maxConstObjects: -1,
+		variables:        make(map[string]*Variable),
+		input:            input,
+		maxAllocs:        -1,
+		maxConstObjects:  -1,
+		maxCompileErrors: compiler.DefaultMaxErrors,
 	}
 }
 
@@ -74,12 +78,29 @@ func (s *Script) SetMaxConstObjects(n int) {
 	s.maxConstObjects = n
 }
 
+// SetMaxCompileErrors sets the maximum number of errors reported by Compile.
+// There is no limit if n is 0 or less.
+func (s *Script) SetMaxCompileErrors(n int) {
+	s.maxCompileErrors = n
+}
+
+// SetWarningsAsErrors makes Compile report the compiler warnings as errors.
+func (s *Script) SetWarningsAsErrors(enable bool) {
+	s.warningsAsErrors = enable
+}
+
 // EnableFileImport enables or disables module loading from local files.
 // Local file modules are disabled by default.
 func (s *Script) EnableFileImport(enable bool) {
 	s.enableFileImport = enable
 }
 
+// SetModuleCache sets the cache of the compiled modules, which can be shared
+// by multiple scripts to avoid compiling the same modules again.
+func (s *Script) SetModuleCache(cache *compiler.ModuleCache) {
+	s.moduleCache = cache
+}
+
 // Compile compiles the script with all the defined variables, and, returns Compiled object.
 func (s *Script) Compile() (*Compiled, error) {
 	symbolTable, globals, err := s.prepCompile()
@@ -98,6 +119,9 @@ func (s *Script) Compile() (*Compiled, error) {
 
 	c := compiler.NewCompiler(srcFile, symbolTable, nil, s.modules, nil)
 	c.EnableFileImport(s.enableFileImport)
+	c.SetMaxErrors(s.maxCompileErrors)
+	c.SetWarningsAsErrors(s.warningsAsErrors)
+	c.SetModuleCache(s.moduleCache)
 	if err := c.Compile(file); err != nil {
 		return nil, err
 	}
@@ -131,6 +155,7 @@ func (s *Script) Compile() (*Compiled, error) {
 		bytecode:      bytecode,
 		globals:       globals,
 		maxAllocs:     s.maxAllocs,
+		warnings:      c.Warnings(),
 	}, nil
 }
 
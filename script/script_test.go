@@ -1,9 +1,11 @@
 package script_test
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/compiler"
 	"github.com/d5/tengo/objects"
 	"github.com/d5/tengo/script"
 	"github.com/d5/tengo/stdlib"
@@ -32,6 +34,37 @@ func TestScript_Add(t *testing.T) {
 	assert.Equal(t, int64(6), c.Get("d").Value())
 }
 
+type testPoint struct {
+	X int `tengo:"x"`
+	Y int `tengo:"y,readonly"`
+}
+
+func (p *testPoint) Move(dx, dy int) (int, int) {
+	p.X += dx
+	p.Y += dy
+	return p.X, p.Y
+}
+
+func TestScript_AddStruct(t *testing.T) {
+	p := &testPoint{X: 1, Y: 2}
+
+	s := script.New([]byte(`
+p.x += 10
+a := [p.x, p.y, p.Move(1, 1)]
+`))
+	assert.NoError(t, s.Add("p", p))
+	c, err := s.Run()
+	assert.NoError(t, err)
+	assert.Equal(t, `[11, 2, [12, 3]]`, c.Get("a").Object().String())
+	assert.Equal(t, 12, p.X)
+	assert.True(t, c.Get("p").Value() == p)
+
+	s = script.New([]byte(`p.y = 10`))
+	assert.NoError(t, s.Add("p", p))
+	_, err = s.Run()
+	assert.Error(t, err)
+}
+
 func TestScript_Remove(t *testing.T) {
 	s := script.New([]byte(`a := b`))
 	err := s.Add("b", 5)
@@ -91,6 +124,66 @@ a := enum.all([1,2,3], func(_, v) {
 	assert.Error(t, err)
 }
 
+func TestScript_SetModuleCache(t *testing.T) {
+	cache := compiler.NewModuleCache()
+	modules := stdlib.GetModuleMap("enum", "text")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			s := script.New([]byte(`
+enum := import("enum")
+a := 0
+for v in enum.map([1, 2, 3], func(_, v) { return v * n }) { a += v }`))
+			assert.NoError(t, s.Add("n", i))
+			s.SetImports(modules)
+			s.SetModuleCache(cache)
+			c, err := s.Run()
+			assert.NoError(t, err)
+			compiledGet(t, c, "a", int64(6*i))
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, cache.Len())
+
+	// the module is compiled again if its source has changed
+	modules.AddSourceModule("enum", []byte(`export { map: func(x, f) { return "changed" } }`))
+	s := script.New([]byte(`a := import("enum").map([1], 0)`))
+	s.SetImports(modules)
+	s.SetModuleCache(cache)
+	c, err := s.Run()
+	assert.NoError(t, err)
+	compiledGet(t, c, "a", "changed")
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestScript_SetMaxCompileErrors(t *testing.T) {
+	s := script.New([]byte(`a = 1; b = 2; c = 3`))
+	_, err := s.Compile()
+	assert.Equal(t, 3, len(err.(compiler.ErrorList)))
+
+	s.SetMaxCompileErrors(2)
+	_, err = s.Compile()
+	assert.Equal(t, "Compile Error: unresolved reference 'a'\n\tat (main):1:1 (and 1 more errors)", err.Error())
+}
+
+func TestScript_Warnings(t *testing.T) {
+	s := script.New([]byte(`f := func() { a := 1; return 1 == "1" }`))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(c.Warnings()))
+	assert.Equal(t, "'a' declared and not used", c.Warnings()[0].Message())
+	assert.Equal(t, "comparison of int and string is always false", c.Warnings()[1].Message())
+	assert.Equal(t, 2, len(c.Clone().Warnings()))
+
+	s.SetWarningsAsErrors(true)
+	_, err = s.Compile()
+	assert.Equal(t, "Compile Error: 'a' declared and not used\n\tat (main):1:15 (and 1 more errors)", err.Error())
+}
+
 func TestScript_SetMaxConstObjects(t *testing.T) {
 	// one constant '5'
 	s := script.New([]byte(`a := 5`))
@@ -0,0 +1,125 @@
+package script_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+	"github.com/d5/tengo/script"
+	"github.com/d5/tengo/stdlib"
+)
+
+const snapshotSrc = `
+text := import("text")
+upper := text.to_upper
+
+counter := func() {
+	n := 0
+	return func() { n += 1; return n }
+}()
+
+log := []
+steps := {a: 1, b: 2}
+run := func(items) {
+	for i, item in items {
+		res := await(item)
+		log = append(log, upper(res), counter(), len(log))
+	}
+	return len(log)
+}
+total := run(["x", "y", "z"])
+count := counter()
+`
+
+func compileSnapshotScript(t *testing.T, src string) *script.Compiled {
+	s := script.New([]byte(src))
+	s.SetImports(stdlib.GetModuleMap("text"))
+	assert.NoError(t, s.Add("await", &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			return args[0], runtime.ErrSuspend
+		},
+	}))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+
+	return c
+}
+
+func TestCompiled_Snapshot(t *testing.T) {
+	c := compileSnapshotScript(t, snapshotSrc)
+
+	done, err := c.RunUntilYield(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, done)
+	done, err = c.Resume(context.Background(), "first")
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, "y", c.Yielded().String())
+
+	// restore the suspended execution in another compiled script
+	var buf bytes.Buffer
+	assert.NoError(t, c.Snapshot(&buf))
+
+	restored := compileSnapshotScript(t, snapshotSrc)
+	assert.NoError(t, restored.Restore(bytes.NewReader(buf.Bytes())))
+	assert.True(t, restored.Suspended())
+	assert.Equal(t, "y", restored.Yielded().String())
+
+	for _, value := range []string{"second", "third"} {
+		_, err = restored.Resume(context.Background(), value)
+		assert.NoError(t, err)
+	}
+	assert.False(t, restored.Suspended())
+
+	assert.Equal(t, 9, restored.Get("total").Int())
+	assert.Equal(t, 4, restored.Get("count").Int())
+	log := restored.Get("log").Array()
+	assert.Equal(t, 9, len(log))
+	assert.Equal(t, "FIRST", log[0])
+	assert.Equal(t, "SECOND", log[3])
+	assert.Equal(t, int64(2), log[4])
+	assert.Equal(t, "THIRD", log[6])
+	assert.Equal(t, int64(3), log[7])
+
+	// the original execution is not affected
+	assert.True(t, c.Suspended())
+	assert.Equal(t, 3, len(c.Get("log").Array()))
+
+	// globals only
+	buf.Reset()
+	assert.NoError(t, restored.Snapshot(&buf))
+	globals := compileSnapshotScript(t, snapshotSrc)
+	assert.NoError(t, globals.Restore(&buf))
+	assert.False(t, globals.Suspended())
+	assert.Equal(t, 9, len(globals.Get("log").Array()))
+	assert.Equal(t, int64(2), globals.Get("steps").Map()["b"])
+}
+
+func TestCompiled_Restore_Errors(t *testing.T) {
+	c := compileSnapshotScript(t, snapshotSrc)
+	_, err := c.RunUntilYield(context.Background())
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, c.Snapshot(&buf))
+
+	// different bytecode
+	other := compileSnapshotScript(t, snapshotSrc+"\nextra := 1")
+	assert.Equal(t, runtime.ErrSnapshotMismatch, other.Restore(bytes.NewReader(buf.Bytes())))
+	assert.False(t, other.Suspended())
+
+	// the objects that can't be encoded
+	s := script.New([]byte(`a := 1`))
+	assert.NoError(t, s.Add("host", &objects.Array{Value: []objects.Object{
+		&objects.UserFunction{Value: func(args ...objects.Object) (objects.Object, error) { return nil, nil }},
+	}}))
+	c, err = s.Compile()
+	assert.NoError(t, err)
+	assert.Error(t, c.Snapshot(&buf))
+
+	// the same compiled script can't restore an invalid snapshot
+	assert.Error(t, c.Restore(bytes.NewReader([]byte("invalid"))))
+}
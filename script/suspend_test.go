@@ -0,0 +1,60 @@
+package script_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+	"github.com/d5/tengo/script"
+)
+
+func TestCompiled_RunUntilYield(t *testing.T) {
+	s := script.New([]byte(`
+approved := await("approval")
+status := approved ? "approved" : "rejected"
+`))
+	assert.NoError(t, s.Add("await", &objects.UserFunction{
+		Value: func(args ...objects.Object) (objects.Object, error) {
+			return args[0], runtime.ErrSuspend
+		},
+	}))
+	c, err := s.Compile()
+	assert.NoError(t, err)
+
+	// many scripts scheduled on one goroutine
+	runs := make([]*script.Compiled, 10)
+	for i := range runs {
+		runs[i] = c.Clone()
+		done, err := runs[i].RunUntilYield(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, done)
+		assert.True(t, runs[i].Suspended())
+		assert.Equal(t, "approval", runs[i].Yielded().String())
+	}
+	for i, run := range runs {
+		done, err := run.Resume(context.Background(), i%2 == 0)
+		assert.NoError(t, err)
+		assert.True(t, done)
+		assert.False(t, run.Suspended())
+		assert.True(t, run.Yielded().IsUndefined())
+	}
+	for i, run := range runs {
+		expected := "rejected"
+		if i%2 == 0 {
+			expected = "approved"
+		}
+		assert.Equal(t, expected, run.Get("status").String())
+	}
+
+	_, err = c.Resume(context.Background(), true)
+	assert.Equal(t, runtime.ErrNotSuspended, err)
+
+	// Run discards the suspended execution
+	done, err := c.RunUntilYield(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Error(t, c.Run())
+	assert.False(t, c.Suspended())
+}
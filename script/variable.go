@@ -110,6 +110,12 @@ func (v *Variable) Map() map[string]interface{} {
 	return nil
 }
 
+// Decode stores the variable value into the Go value pointed to by out,
+// e.g. a Map into a struct. See objects.Decode for the conversion rules.
+func (v *Variable) Decode(out interface{}) error {
+	return objects.Decode(v.value, out)
+}
+
 // String returns string value of the variable value.
 // It returns 0 if the value is not convertible to string.
 func (v *Variable) String() string {
@@ -80,3 +80,34 @@ func TestVariable(t *testing.T) {
 		assert.Equal(t, tc.IsUndefined, v.IsUndefined(), "Name: %s", tc.Name)
 	}
 }
+
+func TestVariable_Decode(t *testing.T) {
+	type server struct {
+		Host string `tengo:"host"`
+		Port int    `tengo:"port"`
+	}
+	type config struct {
+		Name    string   `tengo:"name"`
+		Servers []server `tengo:"servers"`
+	}
+
+	c, err := script.New([]byte(`
+config := {
+	name: "prod",
+	servers: [{host: "a", port: 80}, {host: "b", port: 8080}]
+}
+bad := {servers: [{}, {}, {port: "80"}]}
+`)).Run()
+	assert.NoError(t, err)
+
+	var cfg config
+	assert.NoError(t, c.Get("config").Decode(&cfg))
+	assert.Equal(t, "prod", cfg.Name)
+	assert.Equal(t, 2, len(cfg.Servers))
+	assert.Equal(t, "b", cfg.Servers[1].Host)
+	assert.Equal(t, 8080, cfg.Servers[1].Port)
+
+	err = c.Get("bad").Decode(&cfg)
+	assert.Error(t, err)
+	assert.Equal(t, "servers[2].port: expected int, found string", err.Error())
+}
@@ -1,3 +1,5 @@
+// Code generated by tengofunc; DO NOT EDIT.
+
 package stdlib
 
 import (
@@ -107,13 +109,18 @@ func FuncARB(fn func() bool) objects.CallableFunc {
 
 // FuncARE transform a function of 'func() error' signature
 // into CallableFunc type.
+// User function will return 'true' if underlying native function returns nil.
 func FuncARE(fn func() error) objects.CallableFunc {
 	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 0 {
 			return nil, objects.ErrWrongNumArguments
 		}
 
-		return wrapError(fn()), nil
+		if err := fn(); err != nil {
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
+		}
+
+		return objects.TrueValue, nil
 	}
 }
 
@@ -125,13 +132,13 @@ func FuncARS(fn func() string) objects.CallableFunc {
 			return nil, objects.ErrWrongNumArguments
 		}
 
-		s := fn()
+		res := fn()
 
-		if len(s) > tengo.MaxStringLen {
+		if len(res) > tengo.MaxStringLen {
 			return nil, objects.ErrStringLimit
 		}
 
-		return &objects.String{Value: s}, nil
+		return &objects.String{Value: res}, nil
 	}
 }
 
@@ -145,7 +152,7 @@ func FuncARSE(fn func() (string, error)) objects.CallableFunc {
 
 		res, err := fn()
 		if err != nil {
-			return wrapError(err), nil
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
 		}
 
 		if len(res) > tengo.MaxStringLen {
@@ -166,7 +173,7 @@ func FuncARYE(fn func() ([]byte, error)) objects.CallableFunc {
 
 		res, err := fn()
 		if err != nil {
-			return wrapError(err), nil
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
 		}
 
 		if len(res) > tengo.MaxBytesLen {
@@ -220,12 +227,12 @@ func FuncARIsE(fn func() ([]int, error)) objects.CallableFunc {
 
 		res, err := fn()
 		if err != nil {
-			return wrapError(err), nil
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
 		}
 
 		arr := &objects.Array{}
-		for _, v := range res {
-			arr.Value = append(arr.Value, &objects.Int{Value: int64(v)})
+		for _, elem := range res {
+			arr.Value = append(arr.Value, &objects.Int{Value: int64(elem)})
 		}
 
 		return arr, nil
@@ -249,11 +256,9 @@ func FuncAIRIs(fn func(int) []int) objects.CallableFunc {
 			}
 		}
 
-		res := fn(i1)
-
 		arr := &objects.Array{}
-		for _, v := range res {
-			arr.Value = append(arr.Value, &objects.Int{Value: int64(v)})
+		for _, elem := range fn(i1) {
+			arr.Value = append(arr.Value, &objects.Int{Value: int64(elem)})
 		}
 
 		return arr, nil
@@ -495,10 +500,10 @@ func FuncAFRB(fn func(float64) bool) objects.CallableFunc {
 	}
 }
 
-// FuncASRS transform a function of 'func(string) string' signature into CallableFunc type.
-// User function will return 'true' if underlying native function returns nil.
+// FuncASRS transform a function of 'func(string) string' signature
+// into CallableFunc type.
 func FuncASRS(fn func(string) string) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 1 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -512,19 +517,20 @@ func FuncASRS(fn func(string) string) objects.CallableFunc {
 			}
 		}
 
-		s := fn(s1)
+		res := fn(s1)
 
-		if len(s) > tengo.MaxStringLen {
+		if len(res) > tengo.MaxStringLen {
 			return nil, objects.ErrStringLimit
 		}
 
-		return &objects.String{Value: s}, nil
+		return &objects.String{Value: res}, nil
 	}
 }
 
-// FuncASRSs transform a function of 'func(string) []string' signature into CallableFunc type.
+// FuncASRSs transform a function of 'func(string) []string' signature
+// into CallableFunc type.
 func FuncASRSs(fn func(string) []string) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 1 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -538,10 +544,8 @@ func FuncASRSs(fn func(string) []string) objects.CallableFunc {
 			}
 		}
 
-		res := fn(s1)
-
 		arr := &objects.Array{}
-		for _, elem := range res {
+		for _, elem := range fn(s1) {
 			if len(elem) > tengo.MaxStringLen {
 				return nil, objects.ErrStringLimit
 			}
@@ -553,10 +557,10 @@ func FuncASRSs(fn func(string) []string) objects.CallableFunc {
 	}
 }
 
-// FuncASRSE transform a function of 'func(string) (string, error)' signature into CallableFunc type.
-// User function will return 'true' if underlying native function returns nil.
+// FuncASRSE transform a function of 'func(string) (string, error)' signature
+// into CallableFunc type.
 func FuncASRSE(fn func(string) (string, error)) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 1 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -572,7 +576,7 @@ func FuncASRSE(fn func(string) (string, error)) objects.CallableFunc {
 
 		res, err := fn(s1)
 		if err != nil {
-			return wrapError(err), nil
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
 		}
 
 		if len(res) > tengo.MaxStringLen {
@@ -583,10 +587,11 @@ func FuncASRSE(fn func(string) (string, error)) objects.CallableFunc {
 	}
 }
 
-// FuncASRE transform a function of 'func(string) error' signature into CallableFunc type.
+// FuncASRE transform a function of 'func(string) error' signature
+// into CallableFunc type.
 // User function will return 'true' if underlying native function returns nil.
 func FuncASRE(fn func(string) error) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 1 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -600,14 +605,19 @@ func FuncASRE(fn func(string) error) objects.CallableFunc {
 			}
 		}
 
-		return wrapError(fn(s1)), nil
+		if err := fn(s1); err != nil {
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
+		}
+
+		return objects.TrueValue, nil
 	}
 }
 
-// FuncASSRE transform a function of 'func(string, string) error' signature into CallableFunc type.
+// FuncASSRE transform a function of 'func(string, string) error' signature
+// into CallableFunc type.
 // User function will return 'true' if underlying native function returns nil.
 func FuncASSRE(fn func(string, string) error) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 2 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -630,13 +640,18 @@ func FuncASSRE(fn func(string, string) error) objects.CallableFunc {
 			}
 		}
 
-		return wrapError(fn(s1, s2)), nil
+		if err := fn(s1, s2); err != nil {
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
+		}
+
+		return objects.TrueValue, nil
 	}
 }
 
-// FuncASSRSs transform a function of 'func(string, string) []string' signature into CallableFunc type.
+// FuncASSRSs transform a function of 'func(string, string) []string' signature
+// into CallableFunc type.
 func FuncASSRSs(fn func(string, string) []string) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 2 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -653,28 +668,29 @@ func FuncASSRSs(fn func(string, string) []string) objects.CallableFunc {
 		s2, ok := objects.ToString(args[1])
 		if !ok {
 			return nil, objects.ErrInvalidArgumentType{
-				Name:     "first",
+				Name:     "second",
 				Expected: "string(compatible)",
 				Found:    args[1].TypeName(),
 			}
 		}
 
 		arr := &objects.Array{}
-		for _, res := range fn(s1, s2) {
-			if len(res) > tengo.MaxStringLen {
+		for _, elem := range fn(s1, s2) {
+			if len(elem) > tengo.MaxStringLen {
 				return nil, objects.ErrStringLimit
 			}
 
-			arr.Value = append(arr.Value, &objects.String{Value: res})
+			arr.Value = append(arr.Value, &objects.String{Value: elem})
 		}
 
 		return arr, nil
 	}
 }
 
-// FuncASSIRSs transform a function of 'func(string, string, int) []string' signature into CallableFunc type.
+// FuncASSIRSs transform a function of 'func(string, string, int) []string' signature
+// into CallableFunc type.
 func FuncASSIRSs(fn func(string, string, int) []string) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 3 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -707,21 +723,22 @@ func FuncASSIRSs(fn func(string, string, int) []string) objects.CallableFunc {
 		}
 
 		arr := &objects.Array{}
-		for _, res := range fn(s1, s2, i3) {
-			if len(res) > tengo.MaxStringLen {
+		for _, elem := range fn(s1, s2, i3) {
+			if len(elem) > tengo.MaxStringLen {
 				return nil, objects.ErrStringLimit
 			}
 
-			arr.Value = append(arr.Value, &objects.String{Value: res})
+			arr.Value = append(arr.Value, &objects.String{Value: elem})
 		}
 
 		return arr, nil
 	}
 }
 
-// FuncASSRI transform a function of 'func(string, string) int' signature into CallableFunc type.
+// FuncASSRI transform a function of 'func(string, string) int' signature
+// into CallableFunc type.
 func FuncASSRI(fn func(string, string) int) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 2 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -740,7 +757,7 @@ func FuncASSRI(fn func(string, string) int) objects.CallableFunc {
 			return nil, objects.ErrInvalidArgumentType{
 				Name:     "second",
 				Expected: "string(compatible)",
-				Found:    args[0].TypeName(),
+				Found:    args[1].TypeName(),
 			}
 		}
 
@@ -748,9 +765,10 @@ func FuncASSRI(fn func(string, string) int) objects.CallableFunc {
 	}
 }
 
-// FuncASSRS transform a function of 'func(string, string) string' signature into CallableFunc type.
+// FuncASSRS transform a function of 'func(string, string) string' signature
+// into CallableFunc type.
 func FuncASSRS(fn func(string, string) string) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 2 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -773,19 +791,20 @@ func FuncASSRS(fn func(string, string) string) objects.CallableFunc {
 			}
 		}
 
-		s := fn(s1, s2)
+		res := fn(s1, s2)
 
-		if len(s) > tengo.MaxStringLen {
+		if len(res) > tengo.MaxStringLen {
 			return nil, objects.ErrStringLimit
 		}
 
-		return &objects.String{Value: s}, nil
+		return &objects.String{Value: res}, nil
 	}
 }
 
-// FuncASSRB transform a function of 'func(string, string) bool' signature into CallableFunc type.
+// FuncASSRB transform a function of 'func(string, string) bool' signature
+// into CallableFunc type.
 func FuncASSRB(fn func(string, string) bool) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 2 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -816,9 +835,10 @@ func FuncASSRB(fn func(string, string) bool) objects.CallableFunc {
 	}
 }
 
-// FuncASsSRS transform a function of 'func([]string, string) string' signature into CallableFunc type.
+// FuncASsSRS transform a function of 'func([]string, string) string' signature
+// into CallableFunc type.
 func FuncASsSRS(fn func([]string, string) string) objects.CallableFunc {
-	return func(args ...objects.Object) (objects.Object, error) {
+	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 2 {
 			return nil, objects.ErrWrongNumArguments
 		}
@@ -866,17 +886,19 @@ func FuncASsSRS(fn func([]string, string) string) objects.CallableFunc {
 			}
 		}
 
-		s := fn(ss1, s2)
-		if len(s) > tengo.MaxStringLen {
+		res := fn(ss1, s2)
+
+		if len(res) > tengo.MaxStringLen {
 			return nil, objects.ErrStringLimit
 		}
 
-		return &objects.String{Value: s}, nil
+		return &objects.String{Value: res}, nil
 	}
 }
 
 // FuncASI64RE transform a function of 'func(string, int64) error' signature
 // into CallableFunc type.
+// User function will return 'true' if underlying native function returns nil.
 func FuncASI64RE(fn func(string, int64) error) objects.CallableFunc {
 	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 2 {
@@ -901,12 +923,17 @@ func FuncASI64RE(fn func(string, int64) error) objects.CallableFunc {
 			}
 		}
 
-		return wrapError(fn(s1, i2)), nil
+		if err := fn(s1, i2); err != nil {
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
+		}
+
+		return objects.TrueValue, nil
 	}
 }
 
 // FuncAIIRE transform a function of 'func(int, int) error' signature
 // into CallableFunc type.
+// User function will return 'true' if underlying native function returns nil.
 func FuncAIIRE(fn func(int, int) error) objects.CallableFunc {
 	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 2 {
@@ -931,7 +958,11 @@ func FuncAIIRE(fn func(int, int) error) objects.CallableFunc {
 			}
 		}
 
-		return wrapError(fn(i1, i2)), nil
+		if err := fn(i1, i2); err != nil {
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
+		}
+
+		return objects.TrueValue, nil
 	}
 }
 
@@ -961,18 +992,19 @@ func FuncASIRS(fn func(string, int) string) objects.CallableFunc {
 			}
 		}
 
-		s := fn(s1, i2)
+		res := fn(s1, i2)
 
-		if len(s) > tengo.MaxStringLen {
+		if len(res) > tengo.MaxStringLen {
 			return nil, objects.ErrStringLimit
 		}
 
-		return &objects.String{Value: s}, nil
+		return &objects.String{Value: res}, nil
 	}
 }
 
 // FuncASIIRE transform a function of 'func(string, int, int) error' signature
 // into CallableFunc type.
+// User function will return 'true' if underlying native function returns nil.
 func FuncASIIRE(fn func(string, int, int) error) objects.CallableFunc {
 	return func(args ...objects.Object) (ret objects.Object, err error) {
 		if len(args) != 3 {
@@ -1006,7 +1038,11 @@ func FuncASIIRE(fn func(string, int, int) error) objects.CallableFunc {
 			}
 		}
 
-		return wrapError(fn(s1, i2, i3)), nil
+		if err := fn(s1, i2, i3); err != nil {
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
+		}
+
+		return objects.TrueValue, nil
 	}
 }
 
@@ -1029,7 +1065,7 @@ func FuncAYRIE(fn func([]byte) (int, error)) objects.CallableFunc {
 
 		res, err := fn(y1)
 		if err != nil {
-			return wrapError(err), nil
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
 		}
 
 		return &objects.Int{Value: int64(res)}, nil
@@ -1055,7 +1091,7 @@ func FuncASRIE(fn func(string) (int, error)) objects.CallableFunc {
 
 		res, err := fn(s1)
 		if err != nil {
-			return wrapError(err), nil
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
 		}
 
 		return &objects.Int{Value: int64(res)}, nil
@@ -1081,16 +1117,16 @@ func FuncAIRSsE(fn func(int) ([]string, error)) objects.CallableFunc {
 
 		res, err := fn(i1)
 		if err != nil {
-			return wrapError(err), nil
+			return &objects.Error{Value: &objects.String{Value: err.Error()}}, nil
 		}
 
 		arr := &objects.Array{}
-		for _, r := range res {
-			if len(r) > tengo.MaxStringLen {
+		for _, elem := range res {
+			if len(elem) > tengo.MaxStringLen {
 				return nil, objects.ErrStringLimit
 			}
 
-			arr.Value = append(arr.Value, &objects.String{Value: r})
+			arr.Value = append(arr.Value, &objects.String{Value: elem})
 		}
 
 		return arr, nil
@@ -1114,12 +1150,12 @@ func FuncAIRS(fn func(int) string) objects.CallableFunc {
 			}
 		}
 
-		s := fn(i1)
+		res := fn(i1)
 
-		if len(s) > tengo.MaxStringLen {
+		if len(res) > tengo.MaxStringLen {
 			return nil, objects.ErrStringLimit
 		}
 
-		return &objects.String{Value: s}, nil
+		return &objects.String{Value: res}, nil
 	}
 }
@@ -346,6 +346,8 @@ func TestFuncASSRSs(t *testing.T) {
 	assert.Equal(t, array(&objects.String{Value: "foo"}, &objects.String{Value: "bar"}), ret)
 	_, err = funcCall(uf)
 	assert.Equal(t, objects.ErrWrongNumArguments, err)
+	_, err = funcCall(uf, &objects.String{Value: "foo"}, objects.UndefinedValue)
+	assert.Equal(t, "invalid type for argument 'second': expected string(compatible), found undefined", err.Error())
 }
 
 func TestFuncASSIRSs(t *testing.T) {
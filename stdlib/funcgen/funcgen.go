@@ -0,0 +1,434 @@
+package funcgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Signature represents the signature of a Go function for which an adapter
+// is generated. Params and Results are the Go types of the parameters and
+// the results.
+type Signature struct {
+	Params  []string
+	Results []string
+}
+
+// paramType describes how an argument is converted to a Go parameter type.
+type paramType struct {
+	code     string // the code in the adapter name
+	prefix   string // the prefix of the variable name
+	conv     string // the conversion function in the objects package
+	expected string // the expected type in the error
+	elem     *paramType
+}
+
+var paramTypes = map[string]*paramType{
+	"int":     {code: "I", prefix: "i", conv: "ToInt", expected: "int(compatible)"},
+	"int64":   {code: "I64", prefix: "i", conv: "ToInt64", expected: "int(compatible)"},
+	"float64": {code: "F", prefix: "f", conv: "ToFloat64", expected: "float(compatible)"},
+	"string":  {code: "S", prefix: "s", conv: "ToString", expected: "string(compatible)"},
+	"bool":    {code: "B", prefix: "b", conv: "ToBool", expected: "bool(compatible)"},
+	"[]byte":  {code: "Y", prefix: "y", conv: "ToByteSlice", expected: "bytes(compatible)"},
+}
+
+func init() {
+	paramTypes["[]int"] = &paramType{code: "Is", prefix: "is", expected: "array", elem: paramTypes["int"]}
+	paramTypes["[]string"] = &paramType{code: "Ss", prefix: "ss", expected: "array", elem: paramTypes["string"]}
+}
+
+// resultCodes are the codes of the supported result types in the adapter
+// names.
+var resultCodes = map[string]string{
+	"int":      "I",
+	"int64":    "I64",
+	"float64":  "F",
+	"string":   "S",
+	"bool":     "B",
+	"[]byte":   "Y",
+	"[]int":    "Is",
+	"[]string": "Ss",
+	"error":    "E",
+}
+
+var ordinals = []string{"first", "second", "third", "fourth", "fifth", "sixth", "seventh", "eighth", "ninth", "tenth"}
+
+// ParseSignature parses the function type, e.g.
+// "func(string, int) (string, error)".
+func ParseSignature(s string) (*Signature, error) {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature '%s': %s", s, err.Error())
+	}
+
+	funcType, ok := expr.(*ast.FuncType)
+	if !ok {
+		return nil, fmt.Errorf("invalid signature '%s': not a function type", s)
+	}
+
+	sig := signatureOf(funcType)
+	if err := sig.validate(); err != nil {
+		return nil, fmt.Errorf("invalid signature '%s': %s", s, err.Error())
+	}
+
+	return sig, nil
+}
+
+// PackageSignatures returns the signatures of the exported functions of the
+// package in the directory, skipping the test files. The functions whose
+// signatures are not supported are returned in skipped with the reasons.
+func PackageSignatures(dir string) (sigs []*Signature, skipped []string, err error) {
+	fileSet := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fileSet, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []*ast.File
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return fileSet.File(files[i].Pos()).Name() < fileSet.File(files[j].Pos()).Name()
+	})
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !fn.Name.IsExported() {
+				continue
+			}
+
+			sig := signatureOf(fn.Type)
+			if err := sig.validate(); err != nil {
+				name := filepath.Base(fileSet.File(fn.Pos()).Name()) + ": " + fn.Name.Name
+				skipped = append(skipped, name+": "+err.Error())
+				continue
+			}
+
+			sigs = append(sigs, sig)
+		}
+	}
+
+	return
+}
+
+// Name returns the name of the adapter function, e.g. "FuncASIRS" for
+// 'func(string, int) string'.
+func (s *Signature) Name() string {
+	var sb strings.Builder
+	sb.WriteString("FuncA")
+	for _, p := range s.Params {
+		if t, ok := paramTypes[p]; ok {
+			sb.WriteString(t.code)
+		}
+	}
+	sb.WriteString("R")
+	for _, r := range s.Results {
+		sb.WriteString(resultCodes[r])
+	}
+
+	return sb.String()
+}
+
+// String returns the Go function type of the signature.
+func (s *Signature) String() string {
+	res := "func(" + strings.Join(s.Params, ", ") + ")"
+	switch len(s.Results) {
+	case 0:
+		return res
+	case 1:
+		return res + " " + s.Results[0]
+	default:
+		return res + " (" + strings.Join(s.Results, ", ") + ")"
+	}
+}
+
+func (s *Signature) validate() error {
+	for _, p := range s.Params {
+		if _, ok := paramTypes[p]; !ok {
+			return fmt.Errorf("unsupported parameter type '%s'", p)
+		}
+	}
+
+	for _, r := range s.Results {
+		if _, ok := resultCodes[r]; !ok {
+			return fmt.Errorf("unsupported result type '%s'", r)
+		}
+	}
+
+	switch len(s.Results) {
+	case 0, 1:
+	case 2:
+		if s.Results[0] == "error" || s.Results[1] != "error" {
+			return errors.New("the second result must be an error")
+		}
+	default:
+		return errors.New("too many results")
+	}
+
+	return nil
+}
+
+func signatureOf(funcType *ast.FuncType) *Signature {
+	sig := &Signature{
+		Params:  fieldTypes(funcType.Params),
+		Results: fieldTypes(funcType.Results),
+	}
+
+	return sig
+}
+
+func fieldTypes(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+
+	var res []string
+	for _, field := range fields.List {
+		typ := types.ExprString(field.Type)
+
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			res = append(res, typ)
+		}
+	}
+
+	return res
+}
+
+// Generate generates the Go source file of the package that defines the
+// adapter functions for the signatures. Each adapter transforms a function
+// of the signature into objects.CallableFunc: it checks the number of the
+// arguments, converts the arguments to the parameter types, returning
+// objects.ErrInvalidArgumentType naming the argument that cannot be
+// converted, and converts the results into objects. The duplicate
+// signatures are generated once.
+func Generate(pkg string, sigs []*Signature) ([]byte, error) {
+	g := &generator{}
+
+	generated := make(map[string]bool)
+	for _, sig := range sigs {
+		if err := sig.validate(); err != nil {
+			return nil, fmt.Errorf("invalid signature '%s': %s", sig.String(), err.Error())
+		}
+
+		name := sig.Name()
+		if generated[name] {
+			continue
+		}
+		generated[name] = true
+
+		g.adapter(sig)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by tengofunc; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	out.WriteString("import (\n")
+	if g.usesFmt {
+		out.WriteString("\t\"fmt\"\n\n")
+	}
+	if g.usesTengo {
+		out.WriteString("\t\"github.com/d5/tengo\"\n")
+	}
+	out.WriteString("\t\"github.com/d5/tengo/objects\"\n")
+	out.WriteString(")\n")
+	out.Write(g.body.Bytes())
+
+	return format.Source(out.Bytes())
+}
+
+type generator struct {
+	body      bytes.Buffer
+	usesFmt   bool
+	usesTengo bool
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+func (g *generator) adapter(sig *Signature) {
+	name := sig.Name()
+
+	g.printf("\n// %s transform a function of '%s' signature\n", name, sig.String())
+	g.printf("// into CallableFunc type.\n")
+	if len(sig.Results) == 1 && sig.Results[0] == "error" {
+		g.printf("// User function will return 'true' if underlying native function returns nil.\n")
+	}
+	g.printf("func %s(fn %s) objects.CallableFunc {\n", name, sig.String())
+	g.printf("return func(args ...objects.Object) (ret objects.Object, err error) {\n")
+	g.printf("if len(args) != %d {\n", len(sig.Params))
+	g.printf("return nil, objects.ErrWrongNumArguments\n")
+	g.printf("}\n\n")
+
+	var vars []string
+	for idx, p := range sig.Params {
+		t := paramTypes[p]
+		v := fmt.Sprintf("%s%d", t.prefix, idx+1)
+		vars = append(vars, v)
+
+		if t.elem != nil {
+			g.sliceParam(t, p, v, idx)
+		} else {
+			g.param(t, v, idx)
+		}
+	}
+
+	call := fmt.Sprintf("fn(%s)", strings.Join(vars, ", "))
+
+	switch len(sig.Results) {
+	case 0:
+		g.printf("%s\n\n", call)
+		g.printf("return objects.UndefinedValue, nil\n")
+	case 1:
+		if sig.Results[0] == "error" {
+			g.printf("if err := %s; err != nil {\n", call)
+			g.errorResult("err")
+			g.printf("}\n\n")
+			g.printf("return objects.TrueValue, nil\n")
+		} else {
+			g.result(sig.Results[0], call)
+		}
+	case 2:
+		g.printf("res, err := %s\n", call)
+		g.printf("if err != nil {\n")
+		g.errorResult("err")
+		g.printf("}\n\n")
+		g.result(sig.Results[0], "res")
+	}
+
+	g.printf("}\n")
+	g.printf("}\n")
+}
+
+func (g *generator) param(t *paramType, v string, idx int) {
+	g.printf("%s, ok := objects.%s(args[%d])\n", v, t.conv, idx)
+	g.printf("if !ok {\n")
+	g.invalidArgument(fmt.Sprintf("%q", ordinal(idx)), t.expected, fmt.Sprintf("args[%d]", idx))
+	g.printf("}\n\n")
+}
+
+func (g *generator) sliceParam(t *paramType, goType, v string, idx int) {
+	g.usesFmt = true
+
+	elemVar := "a" + t.elem.prefix
+	arg := fmt.Sprintf("arg%d", idx)
+
+	g.printf("var %s %s\n", v, goType)
+	g.printf("switch %s := args[%d].(type) {\n", arg, idx)
+	for _, arrayType := range []string{"Array", "ImmutableArray"} {
+		g.printf("case *objects.%s:\n", arrayType)
+		g.printf("for idx, a := range %s.Value {\n", arg)
+		g.printf("%s, ok := objects.%s(a)\n", elemVar, t.elem.conv)
+		g.printf("if !ok {\n")
+		g.invalidArgument(fmt.Sprintf("fmt.Sprintf(\"%s[%%d]\", idx)", ordinal(idx)), t.elem.expected, "a")
+		g.printf("}\n")
+		g.printf("%s = append(%s, %s)\n", v, v, elemVar)
+		g.printf("}\n")
+	}
+	g.printf("default:\n")
+	g.invalidArgument(fmt.Sprintf("%q", ordinal(idx)), t.expected, fmt.Sprintf("args[%d]", idx))
+	g.printf("}\n\n")
+}
+
+func (g *generator) invalidArgument(name, expected, found string) {
+	g.printf("return nil, objects.ErrInvalidArgumentType{\n")
+	g.printf("Name: %s,\n", name)
+	g.printf("Expected: %q,\n", expected)
+	g.printf("Found: %s.TypeName(),\n", found)
+	g.printf("}\n")
+}
+
+func (g *generator) errorResult(v string) {
+	g.printf("return &objects.Error{Value: &objects.String{Value: %s.Error()}}, nil\n", v)
+}
+
+// result writes the conversion of the result value. v is either the
+// variable of the result or the function call.
+func (g *generator) result(goType, v string) {
+	switch goType {
+	case "int":
+		g.printf("return &objects.Int{Value: int64(%s)}, nil\n", v)
+	case "int64":
+		g.printf("return &objects.Int{Value: %s}, nil\n", v)
+	case "float64":
+		g.printf("return &objects.Float{Value: %s}, nil\n", v)
+	case "bool":
+		g.printf("if %s {\n", v)
+		g.printf("return objects.TrueValue, nil\n")
+		g.printf("}\n\n")
+		g.printf("return objects.FalseValue, nil\n")
+	case "string":
+		g.usesTengo = true
+		v = g.resultVar(v)
+		g.printf("if len(%s) > tengo.MaxStringLen {\n", v)
+		g.printf("return nil, objects.ErrStringLimit\n")
+		g.printf("}\n\n")
+		g.printf("return &objects.String{Value: %s}, nil\n", v)
+	case "[]byte":
+		g.usesTengo = true
+		v = g.resultVar(v)
+		g.printf("if len(%s) > tengo.MaxBytesLen {\n", v)
+		g.printf("return nil, objects.ErrBytesLimit\n")
+		g.printf("}\n\n")
+		g.printf("return &objects.Bytes{Value: %s}, nil\n", v)
+	case "[]int":
+		g.printf("arr := &objects.Array{}\n")
+		g.printf("for _, elem := range %s {\n", v)
+		g.printf("arr.Value = append(arr.Value, &objects.Int{Value: int64(elem)})\n")
+		g.printf("}\n\n")
+		g.printf("return arr, nil\n")
+	case "[]string":
+		g.usesTengo = true
+		g.printf("arr := &objects.Array{}\n")
+		g.printf("for _, elem := range %s {\n", v)
+		g.printf("if len(elem) > tengo.MaxStringLen {\n")
+		g.printf("return nil, objects.ErrStringLimit\n")
+		g.printf("}\n\n")
+		g.printf("arr.Value = append(arr.Value, &objects.String{Value: elem})\n")
+		g.printf("}\n\n")
+		g.printf("return arr, nil\n")
+	}
+}
+
+// resultVar assigns the function call to a variable if v is not a variable
+// already, and returns the variable.
+func (g *generator) resultVar(v string) string {
+	if v == "res" {
+		return v
+	}
+
+	g.printf("res := %s\n\n", v)
+
+	return "res"
+}
+
+func ordinal(idx int) string {
+	if idx < len(ordinals) {
+		return ordinals[idx]
+	}
+
+	return fmt.Sprintf("#%d", idx+1)
+}
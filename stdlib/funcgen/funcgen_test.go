@@ -0,0 +1,163 @@
+package funcgen_test
+
+import (
+	"bufio"
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/stdlib/funcgen"
+)
+
+func TestParseSignature(t *testing.T) {
+	expectSignature(t, "func()", "FuncAR", "func()")
+	expectSignature(t, "func() error", "FuncARE", "func() error")
+	expectSignature(t, "func(string, int) (string, error)", "FuncASIRSE", "func(string, int) (string, error)")
+	expectSignature(t, "func(a, b string, c int64) []string", "FuncASSI64RSs", "func(string, string, int64) []string")
+	expectSignature(t, "func([]string, []byte, float64, bool) (r []int)", "FuncASsYFBRIs", "func([]string, []byte, float64, bool) []int")
+
+	expectSignatureError(t, "func(", "invalid signature 'func(':")
+	expectSignatureError(t, "int", "invalid signature 'int': not a function type")
+	expectSignatureError(t, "func(uint8)", "unsupported parameter type 'uint8'")
+	expectSignatureError(t, "func(...string)", "unsupported parameter type '...string'")
+	expectSignatureError(t, "func() map[string]int", "unsupported result type 'map[string]int'")
+	expectSignatureError(t, "func() (error, string)", "the second result must be an error")
+	expectSignatureError(t, "func() (int, int, error)", "too many results")
+}
+
+func TestGenerate(t *testing.T) {
+	sigs := parseSignatures(t,
+		"func(string, int) (string, error)",
+		"func([]string, string) bool",
+		"func(int64)",
+		"func(string, int) (string, error)")
+
+	out, err := funcgen.Generate("mypkg", sigs)
+	assert.NoError(t, err)
+
+	src := string(out)
+	assert.True(t, strings.HasPrefix(src, "// Code generated by tengofunc; DO NOT EDIT.\n\npackage mypkg\n"))
+	assert.Equal(t, 1, strings.Count(src, "func FuncASIRSE(fn func(string, int) (string, error)) objects.CallableFunc {"))
+	assert.True(t, strings.Contains(src, "func FuncASsSRB(fn func([]string, string) bool) objects.CallableFunc {"))
+	assert.True(t, strings.Contains(src, "func FuncAI64R(fn func(int64)) objects.CallableFunc {"))
+	assert.True(t, strings.Contains(src, `Name:     fmt.Sprintf("first[%d]", idx),`))
+	assert.True(t, strings.Contains(src, `Name:     "second",`))
+
+	_, err = parser.ParseFile(token.NewFileSet(), "", out, 0)
+	assert.NoError(t, err)
+
+	// no fmt and tengo imports if they are not used
+	out, err = funcgen.Generate("mypkg", parseSignatures(t, "func(int) bool"))
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(string(out), `"fmt"`))
+	assert.False(t, strings.Contains(string(out), `"github.com/d5/tengo"`))
+
+	_, err = funcgen.Generate("mypkg", []*funcgen.Signature{{Params: []string{"chan int"}}})
+	assert.Error(t, err)
+}
+
+func TestPackageSignatures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "funcgen")
+	assert.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	writeFile(t, filepath.Join(dir, "b.go"), `package lib
+
+func Join(a []string, sep string) string { return "" }
+
+func Open(name string) (*File, error) { return nil, nil }
+`)
+	writeFile(t, filepath.Join(dir, "a.go"), `package lib
+
+type File struct{}
+
+func Add(a, b int) int { return a + b }
+
+func (f *File) Close() error { return nil }
+
+func helper(s string) string { return s }
+`)
+	writeFile(t, filepath.Join(dir, "a_test.go"), `package lib
+
+func TestAdd(s string) {}
+`)
+
+	sigs, skipped, err := funcgen.PackageSignatures(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(sigs))
+	assert.Equal(t, "FuncAIIRI", sigs[0].Name())
+	assert.Equal(t, "FuncASsSRS", sigs[1].Name())
+	assert.Equal(t, 1, len(skipped))
+	assert.Equal(t, "b.go: Open: unsupported result type '*File'", skipped[0])
+
+	_, _, err = funcgen.PackageSignatures(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestGenerate_Stdlib(t *testing.T) {
+	// func_typedefs.go must be up to date with func_typedefs.txt
+	f, err := os.Open("../func_typedefs.txt")
+	assert.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var sigs []*funcgen.Signature
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sig, err := funcgen.ParseSignature(line)
+		assert.NoError(t, err)
+		sigs = append(sigs, sig)
+	}
+	assert.NoError(t, scanner.Err())
+
+	out, err := funcgen.Generate("stdlib", sigs)
+	assert.NoError(t, err)
+
+	expected, err := ioutil.ReadFile("../func_typedefs.go")
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(expected, out), "func_typedefs.go is out of date: run 'go generate'")
+}
+
+func expectSignature(t *testing.T, input, name, str string) {
+	sig, err := funcgen.ParseSignature(input)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, name, sig.Name())
+	assert.Equal(t, str, sig.String())
+}
+
+func expectSignatureError(t *testing.T, input, errPrefix string) {
+	_, err := funcgen.ParseSignature(input)
+	if !assert.Error(t, err) {
+		return
+	}
+
+	assert.True(t, strings.Contains(err.Error(), errPrefix), err.Error())
+}
+
+func parseSignatures(t *testing.T, inputs ...string) []*funcgen.Signature {
+	var sigs []*funcgen.Signature
+	for _, input := range inputs {
+		sig, err := funcgen.ParseSignature(input)
+		assert.NoError(t, err)
+		sigs = append(sigs, sig)
+	}
+
+	return sigs
+}
+
+func writeFile(t *testing.T, file, content string) {
+	assert.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+}
@@ -1,13 +1,16 @@
 package stdlib_test
 
 import (
+	"context"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/d5/tengo"
 	"github.com/d5/tengo/assert"
 	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/stdlib"
 )
 
 func TestReadFile(t *testing.T) {
@@ -117,3 +120,43 @@ func TestOSExpandEnv(t *testing.T) {
 	_ = os.Setenv("TENGO", "123456")
 	module(t, "os").call("expand_env", "${TENGO} ${TENGO}").expectError()
 }
+
+func TestSetOSArgs(t *testing.T) {
+	modules := stdlib.GetModuleMap("os", "fmt")
+	stdlib.SetOSArgs(modules, []string{"script.tengo", "a"})
+
+	args, err := modules.GetBuiltinModule("os").Attrs["args"].(*objects.UserFunction).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, &objects.Array{Value: []objects.Object{
+		&objects.String{Value: "script.tengo"},
+		&objects.String{Value: "a"},
+	}}, args)
+
+	// the os module of the other module maps is not changed
+	args, err = stdlib.GetModuleMap("os").GetBuiltinModule("os").Attrs["args"].(*objects.UserFunction).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, len(os.Args), len(args.(*objects.Array).Value))
+
+	// no os module
+	modules = stdlib.GetModuleMap("fmt")
+	stdlib.SetOSArgs(modules, []string{"script.tengo"})
+	assert.Nil(t, modules.Get("os"))
+}
+
+func TestExecContext(t *testing.T) {
+	exec := stdlib.GetModuleMap("os").GetBuiltinModule("os").Attrs["exec"].(*objects.UserFunction)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	cmd, err := exec.CallContext(ctx, &objects.String{Value: "sleep"}, &objects.String{Value: "60"})
+	assert.NoError(t, err)
+
+	// the command is killed when the context is done
+	start := time.Now()
+	ret, err := cmd.(*objects.ImmutableMap).Value["run"].(*objects.UserFunction).Call()
+	assert.NoError(t, err)
+	_, isError := ret.(*objects.Error)
+	assert.True(t, isError)
+	assert.True(t, time.Since(start) < 10*time.Second)
+}
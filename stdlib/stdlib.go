@@ -1,6 +1,7 @@
 package stdlib
 
 //go:generate go run gensrcmods.go
+//go:generate go run github.com/d5/tengo/cmd/tengofunc -o func_typedefs.go -f func_typedefs.txt
 
 import "github.com/d5/tengo/objects"
 
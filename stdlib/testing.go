@@ -0,0 +1,248 @@
+package stdlib
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/runtime"
+)
+
+var testingModule = map[string]objects.Object{
+	"equal":      &objects.UserFunction{Name: "equal", Value: testingEqual},          // equal(expected, actual, msg...)
+	"not_equal":  &objects.UserFunction{Name: "not_equal", Value: testingNotEqual},   // not_equal(unexpected, actual, msg...)
+	"deep_equal": &objects.UserFunction{Name: "deep_equal", Value: testingDeepEqual}, // deep_equal(expected, actual, msg...)
+	"is_true":    &objects.UserFunction{Name: "is_true", Value: testingIsTrue},       // is_true(value, msg...)
+	"is_false":   &objects.UserFunction{Name: "is_false", Value: testingIsFalse},     // is_false(value, msg...)
+	"is_error":   &objects.UserFunction{Name: "is_error", Value: testingIsError},     // is_error(value, msg...)
+	"no_error":   &objects.UserFunction{Name: "no_error", Value: testingNoError},     // no_error(value, msg...)
+	"fail":       &objects.UserFunction{Name: "fail", Value: testingFail},            // fail(msg...)
+	"panics":     &runtime.VMFunction{Name: "panics", Value: testingPanics},          // panics(fn, args...)
+}
+
+// testingEqual asserts that expected and actual are equal
+// in the same way as the '==' operator.
+func testingEqual(args ...objects.Object) (ret objects.Object, err error) {
+	if len(args) < 2 {
+		return nil, objects.ErrWrongNumArguments
+	}
+
+	if !args[0].Equals(args[1]) {
+		return nil, assertionError(args[2:], "expected %s, found %s", formatTestValue(args[0]), formatTestValue(args[1]))
+	}
+
+	return objects.TrueValue, nil
+}
+
+// testingNotEqual asserts that unexpected and actual are not equal
+// in the same way as the '!=' operator.
+func testingNotEqual(args ...objects.Object) (ret objects.Object, err error) {
+	if len(args) < 2 {
+		return nil, objects.ErrWrongNumArguments
+	}
+
+	if args[0].Equals(args[1]) {
+		return nil, assertionError(args[2:], "expected not %s", formatTestValue(args[0]))
+	}
+
+	return objects.TrueValue, nil
+}
+
+// testingDeepEqual asserts that expected and actual have the same types
+// and the same values, comparing the elements of arrays, maps and errors recursively.
+func testingDeepEqual(args ...objects.Object) (ret objects.Object, err error) {
+	if len(args) < 2 {
+		return nil, objects.ErrWrongNumArguments
+	}
+
+	if path, ok := deepEqual(args[0], args[1], ""); !ok {
+		if path != "" {
+			return nil, assertionError(args[2:], "expected %s, found %s (at %s)", formatTestValue(args[0]), formatTestValue(args[1]), path)
+		}
+
+		return nil, assertionError(args[2:], "expected %s, found %s", formatTestValue(args[0]), formatTestValue(args[1]))
+	}
+
+	return objects.TrueValue, nil
+}
+
+func testingIsTrue(args ...objects.Object) (ret objects.Object, err error) {
+	if len(args) < 1 {
+		return nil, objects.ErrWrongNumArguments
+	}
+
+	if args[0].IsFalsy() {
+		return nil, assertionError(args[1:], "expected truthy value, found %s", formatTestValue(args[0]))
+	}
+
+	return objects.TrueValue, nil
+}
+
+func testingIsFalse(args ...objects.Object) (ret objects.Object, err error) {
+	if len(args) < 1 {
+		return nil, objects.ErrWrongNumArguments
+	}
+
+	if !args[0].IsFalsy() {
+		return nil, assertionError(args[1:], "expected falsy value, found %s", formatTestValue(args[0]))
+	}
+
+	return objects.TrueValue, nil
+}
+
+func testingIsError(args ...objects.Object) (ret objects.Object, err error) {
+	if len(args) < 1 {
+		return nil, objects.ErrWrongNumArguments
+	}
+
+	if _, ok := args[0].(*objects.Error); !ok {
+		return nil, assertionError(args[1:], "expected error, found %s", formatTestValue(args[0]))
+	}
+
+	return objects.TrueValue, nil
+}
+
+func testingNoError(args ...objects.Object) (ret objects.Object, err error) {
+	if len(args) < 1 {
+		return nil, objects.ErrWrongNumArguments
+	}
+
+	if _, ok := args[0].(*objects.Error); ok {
+		return nil, assertionError(args[1:], "unexpected %s", args[0].String())
+	}
+
+	return objects.TrueValue, nil
+}
+
+func testingFail(args ...objects.Object) (ret objects.Object, err error) {
+	return nil, assertionError(args, "failed")
+}
+
+// testingPanics asserts that calling fn with the given arguments
+// results in a run-time error.
+func testingPanics(v *runtime.VM, args ...objects.Object) (ret objects.Object, err error) {
+	if len(args) < 1 {
+		return nil, objects.ErrWrongNumArguments
+	}
+
+	switch args[0].(type) {
+	case *objects.CompiledFunction, *objects.Closure, objects.Callable:
+	default:
+		return nil, objects.ErrInvalidArgumentType{
+			Name:     "first",
+			Expected: "callable",
+			Found:    args[0].TypeName(),
+		}
+	}
+
+	if _, err := v.Call(args[0], args[1:]...); err == nil {
+		return nil, fmt.Errorf("assertion failed: expected run-time error")
+	}
+
+	return objects.TrueValue, nil
+}
+
+// assertionError creates an assertion failure error. The VM reports the
+// error with the source position of the failed assertion.
+func assertionError(msgArgs []objects.Object, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+
+	if len(msgArgs) > 0 {
+		var custom string
+		if format, ok := msgArgs[0].(*objects.String); ok && len(msgArgs) > 1 {
+			s, err := objects.Format(format.Value, msgArgs[1:]...)
+			if err != nil {
+				return err
+			}
+			custom = s
+		} else {
+			custom, _ = objects.ToString(msgArgs[0])
+		}
+
+		if custom != "" {
+			msg = custom + ": " + msg
+		}
+	}
+
+	return fmt.Errorf("assertion failed: %s", msg)
+}
+
+func formatTestValue(o objects.Object) string {
+	switch o := o.(type) {
+	case *objects.String:
+		return strconv.Quote(o.Value)
+	case *objects.Char:
+		return strconv.QuoteRune(o.Value)
+	case *objects.Undefined:
+		return "undefined"
+	}
+
+	return fmt.Sprintf("%s(%s)", o.TypeName(), o.String())
+}
+
+// deepEqual compares two objects recursively. If they are not equal,
+// it returns false and the path to the first difference found.
+func deepEqual(a, b objects.Object, path string) (string, bool) {
+	if a.TypeName() != b.TypeName() {
+		return path, false
+	}
+
+	switch a := a.(type) {
+	case *objects.Array:
+		return deepEqualSlice(a.Value, b.(*objects.Array).Value, path)
+	case *objects.ImmutableArray:
+		return deepEqualSlice(a.Value, b.(*objects.ImmutableArray).Value, path)
+	case *objects.Map:
+		return deepEqualMap(a.Value, b.(*objects.Map).Value, path)
+	case *objects.ImmutableMap:
+		return deepEqualMap(a.Value, b.(*objects.ImmutableMap).Value, path)
+	case *objects.Error:
+		bv := b.(*objects.Error).Value
+		if a.Value == nil || bv == nil {
+			return path, a.Value == bv
+		}
+
+		return deepEqual(a.Value, bv, path+".value")
+	case *objects.Undefined:
+		return path, true
+	}
+
+	if a == b {
+		return path, true
+	}
+
+	return path, a.Equals(b)
+}
+
+func deepEqualSlice(a, b []objects.Object, path string) (string, bool) {
+	if len(a) != len(b) {
+		return path, false
+	}
+
+	for i := range a {
+		if p, ok := deepEqual(a[i], b[i], fmt.Sprintf("%s[%d]", path, i)); !ok {
+			return p, false
+		}
+	}
+
+	return path, true
+}
+
+func deepEqualMap(a, b map[string]objects.Object, path string) (string, bool) {
+	if len(a) != len(b) {
+		return path, false
+	}
+
+	for k, av := range a {
+		bv, ok := b[k]
+		if !ok {
+			return fmt.Sprintf("%s[%q]", path, k), false
+		}
+
+		if p, ok := deepEqual(av, bv, fmt.Sprintf("%s[%q]", path, k)); !ok {
+			return p, false
+		}
+	}
+
+	return path, true
+}
@@ -0,0 +1,70 @@
+package stdlib_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/d5/tengo/assert"
+	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/script"
+	"github.com/d5/tengo/stdlib"
+)
+
+func TestTesting(t *testing.T) {
+	module(t, "testing").call("equal", 1, 1).expect(true)
+	module(t, "testing").call("equal", "foo", "foo").expect(true)
+	module(t, "testing").call("equal", ARR{1, 2}, IARR{1, 2}).expect(true)
+	module(t, "testing").call("equal", 1, 2).expectError()
+	module(t, "testing").call("equal", 1).expectError()
+	module(t, "testing").call("not_equal", 1, 2).expect(true)
+	module(t, "testing").call("not_equal", 1, 1).expectError()
+
+	module(t, "testing").call("deep_equal", ARR{1, MAP{"a": "b"}}, ARR{1, MAP{"a": "b"}}).expect(true)
+	module(t, "testing").call("deep_equal", ARR{1, 2}, IARR{1, 2}).expectError()
+	module(t, "testing").call("deep_equal", MAP{"a": ARR{1, 2}}, MAP{"a": ARR{1, 3}}).expectError()
+	module(t, "testing").call("deep_equal", &objects.Error{Value: &objects.String{Value: "e"}}, &objects.Error{Value: &objects.String{Value: "e"}}).expect(true)
+
+	module(t, "testing").call("is_true", 1).expect(true)
+	module(t, "testing").call("is_true", 0).expectError()
+	module(t, "testing").call("is_false", "").expect(true)
+	module(t, "testing").call("is_false", "foo").expectError()
+	module(t, "testing").call("is_error", &objects.Error{Value: objects.UndefinedValue}).expect(true)
+	module(t, "testing").call("is_error", 1).expectError()
+	module(t, "testing").call("no_error", 1).expect(true)
+	module(t, "testing").call("no_error", &objects.Error{Value: objects.UndefinedValue}).expectError()
+	module(t, "testing").call("fail").expectError()
+
+	expect(t, `
+testing := import("testing")
+out := testing.panics(func(x) { return x + "foo" }, 1)
+`, true)
+
+	expectTestingError(t, `
+testing := import("testing")
+testing.panics(func(x) { return x + 1 }, 1)
+`, "Runtime Error: assertion failed: expected run-time error\n\tat (main):3:1")
+
+	expectTestingError(t, `
+testing := import("testing")
+testing.equal(1, 2, "values")
+`, "Runtime Error: assertion failed: values: expected int(1), found int(2)\n\tat (main):3:1")
+
+	expectTestingError(t, `
+testing := import("testing")
+testing.equal("a", "b", "%s and %d", "values", 3)
+`, `Runtime Error: assertion failed: values and 3: expected "a", found "b"`)
+
+	expectTestingError(t, `
+testing := import("testing")
+testing.deep_equal({a: [1, 2]}, {a: [1, 3]})
+`, `expected map({a: [1, 2]}), found map({a: [1, 3]}) (at ["a"][1])`)
+}
+
+func expectTestingError(t *testing.T, input string, expected string) {
+	s := script.New([]byte(input))
+	s.SetImports(stdlib.GetModuleMap("testing"))
+	_, err := s.Run()
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), expected), "expected error string: %s, got: %s", expected, err.Error())
+	}
+}
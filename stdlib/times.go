@@ -1,6 +1,7 @@
 package stdlib
 
 import (
+	"context"
 	"time"
 
 	"github.com/d5/tengo"
@@ -41,44 +42,50 @@ var timesModule = map[string]objects.Object{
 	"october":              &objects.Int{Value: int64(time.October)},
 	"november":             &objects.Int{Value: int64(time.November)},
 	"december":             &objects.Int{Value: int64(time.December)},
-	"sleep":                &objects.UserFunction{Name: "sleep", Value: timesSleep},                              // sleep(int)
-	"parse_duration":       &objects.UserFunction{Name: "parse_duration", Value: timesParseDuration},             // parse_duration(str) => int
-	"since":                &objects.UserFunction{Name: "since", Value: timesSince},                              // since(time) => int
-	"until":                &objects.UserFunction{Name: "until", Value: timesUntil},                              // until(time) => int
-	"duration_hours":       &objects.UserFunction{Name: "duration_hours", Value: timesDurationHours},             // duration_hours(int) => float
-	"duration_minutes":     &objects.UserFunction{Name: "duration_minutes", Value: timesDurationMinutes},         // duration_minutes(int) => float
-	"duration_nanoseconds": &objects.UserFunction{Name: "duration_nanoseconds", Value: timesDurationNanoseconds}, // duration_nanoseconds(int) => int
-	"duration_seconds":     &objects.UserFunction{Name: "duration_seconds", Value: timesDurationSeconds},         // duration_seconds(int) => float
-	"duration_string":      &objects.UserFunction{Name: "duration_string", Value: timesDurationString},           // duration_string(int) => string
-	"month_string":         &objects.UserFunction{Name: "month_string", Value: timesMonthString},                 // month_string(int) => string
-	"date":                 &objects.UserFunction{Name: "date", Value: timesDate},                                // date(year, month, day, hour, min, sec, nsec) => time
-	"now":                  &objects.UserFunction{Name: "now", Value: timesNow},                                  // now() => time
-	"parse":                &objects.UserFunction{Name: "parse", Value: timesParse},                              // parse(format, str) => time
-	"unix":                 &objects.UserFunction{Name: "unix", Value: timesUnix},                                // unix(sec, nsec) => time
-	"add":                  &objects.UserFunction{Name: "add", Value: timesAdd},                                  // add(time, int) => time
-	"add_date":             &objects.UserFunction{Name: "add_date", Value: timesAddDate},                         // add_date(time, years, months, days) => time
-	"sub":                  &objects.UserFunction{Name: "sub", Value: timesSub},                                  // sub(t time, u time) => int
-	"after":                &objects.UserFunction{Name: "after", Value: timesAfter},                              // after(t time, u time) => bool
-	"before":               &objects.UserFunction{Name: "before", Value: timesBefore},                            // before(t time, u time) => bool
-	"time_year":            &objects.UserFunction{Name: "time_year", Value: timesTimeYear},                       // time_year(time) => int
-	"time_month":           &objects.UserFunction{Name: "time_month", Value: timesTimeMonth},                     // time_month(time) => int
-	"time_day":             &objects.UserFunction{Name: "time_day", Value: timesTimeDay},                         // time_day(time) => int
-	"time_weekday":         &objects.UserFunction{Name: "time_weekday", Value: timesTimeWeekday},                 // time_weekday(time) => int
-	"time_hour":            &objects.UserFunction{Name: "time_hour", Value: timesTimeHour},                       // time_hour(time) => int
-	"time_minute":          &objects.UserFunction{Name: "time_minute", Value: timesTimeMinute},                   // time_minute(time) => int
-	"time_second":          &objects.UserFunction{Name: "time_second", Value: timesTimeSecond},                   // time_second(time) => int
-	"time_nanosecond":      &objects.UserFunction{Name: "time_nanosecond", Value: timesTimeNanosecond},           // time_nanosecond(time) => int
-	"time_unix":            &objects.UserFunction{Name: "time_unix", Value: timesTimeUnix},                       // time_unix(time) => int
-	"time_unix_nano":       &objects.UserFunction{Name: "time_unix_nano", Value: timesTimeUnixNano},              // time_unix_nano(time) => int
-	"time_format":          &objects.UserFunction{Name: "time_format", Value: timesTimeFormat},                   // time_format(time, format) => string
-	"time_location":        &objects.UserFunction{Name: "time_location", Value: timesTimeLocation},               // time_location(time) => string
-	"time_string":          &objects.UserFunction{Name: "time_string", Value: timesTimeString},                   // time_string(time) => string
-	"is_zero":              &objects.UserFunction{Name: "is_zero", Value: timesIsZero},                           // is_zero(time) => bool
-	"to_local":             &objects.UserFunction{Name: "to_local", Value: timesToLocal},                         // to_local(time) => time
-	"to_utc":               &objects.UserFunction{Name: "to_utc", Value: timesToUTC},                             // to_utc(time) => time
+	"sleep":                &objects.UserFunction{Name: "sleep", Value: timesSleep, ContextValue: timesSleepContext}, // sleep(int)
+	"parse_duration":       &objects.UserFunction{Name: "parse_duration", Value: timesParseDuration},                 // parse_duration(str) => int
+	"since":                &objects.UserFunction{Name: "since", Value: timesSince},                                  // since(time) => int
+	"until":                &objects.UserFunction{Name: "until", Value: timesUntil},                                  // until(time) => int
+	"duration_hours":       &objects.UserFunction{Name: "duration_hours", Value: timesDurationHours},                 // duration_hours(int) => float
+	"duration_minutes":     &objects.UserFunction{Name: "duration_minutes", Value: timesDurationMinutes},             // duration_minutes(int) => float
+	"duration_nanoseconds": &objects.UserFunction{Name: "duration_nanoseconds", Value: timesDurationNanoseconds},     // duration_nanoseconds(int) => int
+	"duration_seconds":     &objects.UserFunction{Name: "duration_seconds", Value: timesDurationSeconds},             // duration_seconds(int) => float
+	"duration_string":      &objects.UserFunction{Name: "duration_string", Value: timesDurationString},               // duration_string(int) => string
+	"month_string":         &objects.UserFunction{Name: "month_string", Value: timesMonthString},                     // month_string(int) => string
+	"date":                 &objects.UserFunction{Name: "date", Value: timesDate},                                    // date(year, month, day, hour, min, sec, nsec) => time
+	"now":                  &objects.UserFunction{Name: "now", Value: timesNow},                                      // now() => time
+	"parse":                &objects.UserFunction{Name: "parse", Value: timesParse},                                  // parse(format, str) => time
+	"unix":                 &objects.UserFunction{Name: "unix", Value: timesUnix},                                    // unix(sec, nsec) => time
+	"add":                  &objects.UserFunction{Name: "add", Value: timesAdd},                                      // add(time, int) => time
+	"add_date":             &objects.UserFunction{Name: "add_date", Value: timesAddDate},                             // add_date(time, years, months, days) => time
+	"sub":                  &objects.UserFunction{Name: "sub", Value: timesSub},                                      // sub(t time, u time) => int
+	"after":                &objects.UserFunction{Name: "after", Value: timesAfter},                                  // after(t time, u time) => bool
+	"before":               &objects.UserFunction{Name: "before", Value: timesBefore},                                // before(t time, u time) => bool
+	"time_year":            &objects.UserFunction{Name: "time_year", Value: timesTimeYear},                           // time_year(time) => int
+	"time_month":           &objects.UserFunction{Name: "time_month", Value: timesTimeMonth},                         // time_month(time) => int
+	"time_day":             &objects.UserFunction{Name: "time_day", Value: timesTimeDay},                             // time_day(time) => int
+	"time_weekday":         &objects.UserFunction{Name: "time_weekday", Value: timesTimeWeekday},                     // time_weekday(time) => int
+	"time_hour":            &objects.UserFunction{Name: "time_hour", Value: timesTimeHour},                           // time_hour(time) => int
+	"time_minute":          &objects.UserFunction{Name: "time_minute", Value: timesTimeMinute},                       // time_minute(time) => int
+	"time_second":          &objects.UserFunction{Name: "time_second", Value: timesTimeSecond},                       // time_second(time) => int
+	"time_nanosecond":      &objects.UserFunction{Name: "time_nanosecond", Value: timesTimeNanosecond},               // time_nanosecond(time) => int
+	"time_unix":            &objects.UserFunction{Name: "time_unix", Value: timesTimeUnix},                           // time_unix(time) => int
+	"time_unix_nano":       &objects.UserFunction{Name: "time_unix_nano", Value: timesTimeUnixNano},                  // time_unix_nano(time) => int
+	"time_format":          &objects.UserFunction{Name: "time_format", Value: timesTimeFormat},                       // time_format(time, format) => string
+	"time_location":        &objects.UserFunction{Name: "time_location", Value: timesTimeLocation},                   // time_location(time) => string
+	"time_string":          &objects.UserFunction{Name: "time_string", Value: timesTimeString},                       // time_string(time) => string
+	"is_zero":              &objects.UserFunction{Name: "is_zero", Value: timesIsZero},                               // is_zero(time) => bool
+	"to_local":             &objects.UserFunction{Name: "to_local", Value: timesToLocal},                             // to_local(time) => time
+	"to_utc":               &objects.UserFunction{Name: "to_utc", Value: timesToUTC},                                 // to_utc(time) => time
 }
 
 func timesSleep(args ...objects.Object) (ret objects.Object, err error) {
+	return timesSleepContext(context.Background(), args...)
+}
+
+// timesSleepContext returns the context error if the context is done before
+// the duration elapses.
+func timesSleepContext(ctx context.Context, args ...objects.Object) (ret objects.Object, err error) {
 	if len(args) != 1 {
 		err = objects.ErrWrongNumArguments
 		return
@@ -94,7 +101,16 @@ func timesSleep(args ...objects.Object) (ret objects.Object, err error) {
 		return
 	}
 
-	time.Sleep(time.Duration(i1))
+	timer := time.NewTimer(time.Duration(i1))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+		return
+	}
+
 	ret = objects.UndefinedValue
 
 	return
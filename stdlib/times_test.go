@@ -1,11 +1,13 @@
 package stdlib_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/d5/tengo/assert"
 	"github.com/d5/tengo/objects"
+	"github.com/d5/tengo/stdlib"
 )
 
 func TestTimes(t *testing.T) {
@@ -61,3 +63,19 @@ func TestTimes(t *testing.T) {
 	module(t, "times").call("time_location", time1).expect(time1.Location().String())
 	module(t, "times").call("time_string", time1).expect(time1.String())
 }
+
+func TestTimesSleepContext(t *testing.T) {
+	sleep := stdlib.GetModuleMap("times").GetBuiltinModule("times").Attrs["sleep"].(*objects.UserFunction)
+
+	ret, err := sleep.CallContext(context.Background(), &objects.Int{Value: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, objects.UndefinedValue, ret)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = sleep.CallContext(ctx, &objects.Int{Value: int64(time.Minute)})
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 10*time.Second)
+}